@@ -3,18 +3,27 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
 
 	cal "github.com/bupd/timeotter/pkg/calendar"
 	"github.com/bupd/timeotter/pkg/config"
+	"github.com/bupd/timeotter/pkg/cron"
+	"github.com/bupd/timeotter/pkg/fire"
+	"github.com/bupd/timeotter/pkg/history"
 	"github.com/bupd/timeotter/pkg/oauth"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/calendar/v3"
 	"google.golang.org/api/option"
+	"google.golang.org/api/tasks/v1"
 )
 
 var (
@@ -27,10 +36,67 @@ var (
 	triggerBeforeMinutes int
 	cronMarker           string
 	showDeleted          bool
+	attendanceStatuses   []string
+	skipFreeEvents       bool
+	rules                []config.Rule
+	ruleDefault          string
+	allDayPolicy         string
+	allDayTime           string
+	allDayEveningTime    string
+	triggerPoints        []config.TriggerPoint
+	triggerMode          string
+	reminderMethods      []string
+	reminderFallback     []int
+	hours                config.Hours
+	calendarHours        []config.CalendarHours
+	detectOutOfOffice    bool
+	holidayCalendars     []string
+	awayAction           string
+	awayCmd              string
+	coalesce             bool
+	backToBackGapMinutes int
+	joinOpener           string
+	source               string
+	cacheFile            string
+	maxCacheAgeHours     int
+	stateFile            string
+	tasksEnabled         bool
+	taskLists            []string
+	taskCmd              string
+	taskBeforeMinutes    int
+	taskDueTime          string
+	freeSlotMinutes      int
+	freeSlotCmd          string
+	freeBusyCalendars    []string
+	travel               []config.Travel
+	routes               []config.Route
+	routeMode            string
+	directives           bool
+	namedCommands        map[string]string
+	execDefaults         config.Exec
+	commandExec          []config.CommandExec
+	logMaxKB             int
+	logBackups           int
+	historyFile          string
+	historyRetentionDays int
+	actions              []config.Action
 )
 
+const usage = `Usage:
+  timeotter                     sync upcoming events into the crontab
+  timeotter plan                show the triggers a sync would schedule
+  timeotter explain <event-id>  show which rules apply to an event
+  timeotter join [--next]       open the current (or next) meeting's video link
+  timeotter fire <key>          run the command of a scheduled trigger (used by cron)
+  timeotter snooze <event|key> <duration>
+                                fire a trigger again after a delay such as 5m
+  timeotter ack <event|key>     cancel the remaining triggers of an event
+  timeotter history [--since <when>] [--failed] [--json]
+                                list the commands fire has run`
+
 func main() {
 	conf := config.GetConfig()
+	checkCommands(&conf)
 
 	calendarID = conf.CalendarID
 	cmdToExec = conf.CmdToExec
@@ -41,25 +107,293 @@ func main() {
 	triggerBeforeMinutes = conf.TriggerBeforeMinutes
 	cronMarker = conf.CronMarker
 	showDeleted = conf.ShowDeleted
+	attendanceStatuses = conf.AttendanceStatuses
+	skipFreeEvents = conf.SkipFreeEvents
+	rules = conf.Rules
+	ruleDefault = conf.RuleDefault
+	allDayPolicy = conf.AllDayPolicy
+	allDayTime = conf.AllDayTime
+	allDayEveningTime = conf.AllDayEveningTime
+	triggerPoints = conf.Triggers
+	triggerMode = conf.TriggerMode
+	reminderMethods = conf.ReminderMethods
+	reminderFallback = conf.ReminderFallbackMinutes
+	hours = conf.Hours
+	calendarHours = conf.CalendarHours
+	detectOutOfOffice = conf.DetectOutOfOffice
+	holidayCalendars = conf.HolidayCalendars
+	awayAction = conf.AwayAction
+	awayCmd = conf.AwayCmd
+	coalesce = conf.Coalesce
+	backToBackGapMinutes = conf.BackToBackGapMinutes
+	joinOpener = conf.JoinOpener
+	source = conf.Source
+	cacheFile = conf.CacheFile
+	maxCacheAgeHours = conf.MaxCacheAgeHours
+	stateFile = conf.StateFile
+	tasksEnabled = conf.Tasks
+	taskLists = conf.TaskLists
+	taskCmd = conf.TaskCmd
+	taskBeforeMinutes = conf.TaskBeforeMinutes
+	taskDueTime = conf.TaskDueTime
+	freeSlotMinutes = conf.FreeSlotMinutes
+	freeSlotCmd = conf.FreeSlotCmd
+	freeBusyCalendars = conf.FreeBusyCalendars
+	travel = conf.Travel
+	routes = conf.Routes
+	routeMode = conf.RouteMode
+	directives = conf.Directives
+	namedCommands = conf.NamedCommands
+	execDefaults = conf.Exec
+	commandExec = conf.CommandExec
+	logMaxKB = conf.LogMaxKB
+	logBackups = conf.LogBackups
+	historyFile = conf.HistoryFile
+	historyRetentionDays = conf.HistoryRetentionDays
+	actions = conf.Actions
 
-	ctx := context.Background()
+	args := os.Args[1:]
+	if len(args) == 0 {
+		runSync()
+		return
+	}
+
+	switch args[0] {
+	case "plan":
+		runPlan()
+	case "explain":
+		if len(args) != 2 {
+			log.Fatalf("explain expects exactly one event ID\n%s", usage)
+		}
+		runExplain(args[1])
+	case "join":
+		next := false
+		for _, arg := range args[1:] {
+			if arg != "--next" {
+				log.Fatalf("unknown join flag %q\n%s", arg, usage)
+			}
+			next = true
+		}
+		runJoin(next)
+	case "fire":
+		if len(args) != 2 {
+			log.Fatalf("fire expects exactly one trigger key\n%s", usage)
+		}
+		runFire(args[1])
+	case "snooze":
+		if len(args) != 3 {
+			log.Fatalf("snooze expects an event ID or trigger key and a duration\n%s", usage)
+		}
+		runSnooze(args[1], args[2])
+	case "ack":
+		if len(args) != 2 {
+			log.Fatalf("ack expects exactly one event ID or trigger key\n%s", usage)
+		}
+		runAck(args[1])
+	case "history":
+		runHistory(args[1:])
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+	default:
+		log.Fatalf("unknown command %q\n%s", args[0], usage)
+	}
+}
+
+// checkCommands renders every configured command against a sample event so
+// that templates referring to unknown fields fail at startup rather than
+// when a trigger fires.
+func checkCommands(conf *config.Config) {
+	for _, command := range conf.Commands() {
+		if err := fire.Check(command.Cmd); err != nil {
+			log.Fatalf("Invalid %s: %v", command.Setting, err)
+		}
+	}
+	for _, text := range conf.Texts() {
+		if err := fire.CheckText(text.Cmd); err != nil {
+			log.Fatalf("Invalid %s: %v", text.Setting, err)
+		}
+	}
+}
+
+// googleClient builds an HTTP client authorized for the Google APIs in use.
+func googleClient() *http.Client {
 	b, err := os.ReadFile(filepath.Clean(credentialsFile))
 	if err != nil {
 		log.Fatalf("Unable to read client secret file: %v", err)
 	}
 
 	// If modifying these scopes, delete your previously saved token.json.
-	config, err := google.ConfigFromJSON(b, calendar.CalendarReadonlyScope)
+	scopes := []string{calendar.CalendarReadonlyScope}
+	if tasksEnabled {
+		scopes = append(scopes, tasks.TasksReadonlyScope)
+	}
+	config, err := google.ConfigFromJSON(b, scopes...)
 	if err != nil {
 		log.Fatalf("Unable to parse client secret file to config: %v", err)
 	}
-	client := oauth.GetClient(config, tokenFile)
+	return oauth.GetClient(config, tokenFile)
+}
 
-	srv, err := calendar.NewService(ctx, option.WithHTTPClient(client))
+// newCalendarService builds an authenticated Google Calendar client.
+func newCalendarService(ctx context.Context) *calendar.Service {
+	srv, err := calendar.NewService(ctx, option.WithHTTPClient(googleClient()))
 	if err != nil {
 		log.Fatalf("Unable to retrieve Calendar client: %v", err)
 	}
+	return srv
+}
+
+// newTaskSource returns the Google Tasks source.
+func newTaskSource(ctx context.Context) *cal.TaskSource {
+	srv, err := tasks.NewService(ctx, option.WithHTTPClient(googleClient()))
+	if err != nil {
+		log.Fatalf("Unable to retrieve Tasks client: %v", err)
+	}
+	return &cal.TaskSource{Service: srv, TaskLists: taskLists, DueTime: taskDueTime, Location: time.Local}
+}
+
+// newSource returns the configured calendar source.
+func newSource(ctx context.Context) cal.Source {
+	switch source {
+	case config.SourceGoogle:
+		return &cal.GoogleSource{
+			Service:     newCalendarService(ctx),
+			CalendarID:  calendarID,
+			ShowDeleted: showDeleted,
+		}
+	default:
+		log.Fatalf("Unknown calendar source %q", source)
+		return nil
+	}
+}
+
+// eventOptions assembles the event parser options from the loaded config.
+func eventOptions() cal.Options {
+	ruleSet, err := cal.NewRuleSet(rules, ruleDefault)
+	if err != nil {
+		log.Fatalf("Unable to compile rules: %v", err)
+	}
+	travelTimes, err := cal.NewTravelTimes(travel)
+	if err != nil {
+		log.Fatalf("Unable to compile travel times: %v", err)
+	}
+	routeSet, err := cal.NewRouteSet(routes, routeMode)
+	if err != nil {
+		log.Fatalf("Unable to compile routes: %v", err)
+	}
+	// Events acknowledged with `timeotter ack` stay quiet until the ack
+	// expires.
+	var acked map[string]time.Time
+	if state, err := cal.LoadTriggerState(stateFile); err == nil {
+		acked = state.ActiveAcks(time.Now())
+	}
 
+	return cal.Options{
+		CmdToExec:            cmdToExec,
+		BackupFile:           backupFile,
+		CronMarker:           cronMarker,
+		TriggerBeforeMinutes: triggerBeforeMinutes,
+		Attendance: cal.AttendanceFilter{
+			Statuses: attendanceStatuses,
+			SkipFree: skipFreeEvents,
+		},
+		Rules: ruleSet,
+		AllDay: cal.AllDayOptions{
+			Policy:    allDayPolicy,
+			At:        allDayTime,
+			EveningAt: allDayEveningTime,
+		},
+		TriggerPoints: triggerPoints,
+		Reminders: cal.ReminderOptions{
+			Enabled:         triggerMode == config.TriggerModeReminders,
+			Methods:         reminderMethods,
+			FallbackMinutes: reminderFallback,
+		},
+		CalendarID: calendarID,
+		Hours: cal.HoursPolicy{
+			Default:     hours,
+			PerCalendar: calendarHours,
+		},
+		Away: cal.AwayOptions{
+			DetectOutOfOffice: detectOutOfOffice,
+			Action:            awayAction,
+			Cmd:               awayCmd,
+		},
+		Tasks: cal.TaskOptions{
+			Cmd:           taskCmd,
+			BeforeMinutes: taskBeforeMinutes,
+		},
+		FreeSlots: cal.FreeSlotOptions{
+			MinGap: time.Duration(freeSlotMinutes) * time.Minute,
+			Cmd:    freeSlotCmd,
+		},
+		Routes: routeSet,
+		Directives: cal.DirectiveOptions{
+			Enabled:  directives,
+			Commands: namedCommands,
+		},
+		Travel:        travelTimes,
+		Coalesce:      coalesce,
+		BackToBackGap: time.Duration(backToBackGapMinutes) * time.Minute,
+		Acked:         acked,
+	}
+}
+
+// planOptions extends eventOptions with the holiday spans that overlap the
+// fetched events.
+func planOptions(ctx context.Context, src cal.Source, events []cal.Event, cachedAt time.Time) cal.Options {
+	opts := eventOptions()
+	opts.StateFile = stateFile
+	if bin, err := os.Executable(); err == nil {
+		opts.FireCmd = bin
+	} else {
+		log.Printf("Unable to locate the timeotter executable: %v", err)
+	}
+	opts.Away.Spans = holidaySpans(ctx, src, events)
+	opts.FreeSlots.Busy = freeBusy(ctx, src, events)
+	opts.CachedAt = cachedAt
+	return opts
+}
+
+// lookahead returns the end of the last fetched event, or a week ahead
+// when that is sooner.
+func lookahead(now time.Time, events []cal.Event) time.Time {
+	horizon := now.AddDate(0, 0, 7)
+	for _, item := range events {
+		if item.End.After(horizon) {
+			horizon = item.End
+		}
+	}
+	return horizon
+}
+
+// holidaySpans fetches the configured holiday calendars up to the
+// lookahead horizon.
+func holidaySpans(ctx context.Context, src cal.Source, events []cal.Event) []cal.AwaySpan {
+	if len(holidayCalendars) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	horizon := lookahead(now, events)
+
+	var spans []cal.AwaySpan
+	for _, id := range holidayCalendars {
+		holidays, err := src.Events(ctx, cal.Query{CalendarID: id, From: now, To: horizon})
+		if err != nil {
+			log.Printf("Unable to retrieve holiday calendar %s: %v", id, err)
+			continue
+		}
+		spans = append(spans, cal.AwaySpans(holidays, "holiday")...)
+	}
+	return spans
+}
+
+// fetchEvents lists the upcoming events of the configured calendar, with
+// recurring series expanded up to the lookahead horizon. When the calendar
+// is unreachable it falls back to the offline cache and returns the time
+// the cached events were fetched.
+func fetchEvents(ctx context.Context, src cal.Source) ([]cal.Event, time.Time) {
 	// calList := srv.CalendarList.List()
 	// kumar := srv.CalendarList.List().Fields()
 	// Marshal the struct to a JSON string
@@ -78,15 +412,360 @@ func main() {
 
 	// Print the marshaled output
 	// fmt.Println(string(jsonDatas))
-	t := time.Now().Format(time.RFC3339)
-	events, err := srv.Events.List(calendarID).ShowDeleted(showDeleted).
-		SingleEvents(true).TimeMin(t).MaxResults(maxRes).OrderBy("startTime").Do()
+	cache := cal.EventCache{Path: cacheFile, MaxAge: time.Duration(maxCacheAgeHours) * time.Hour}
+	now := time.Now()
+	events, cachedAt, err := cache.Fetch(ctx, src, cal.Query{From: now, MaxResults: maxRes}, now)
 	if err != nil {
 		log.Fatalf("Unable to retrieve next ten of the user's events: %v", err)
 	}
-	if len(events.Items) == 0 {
+	if !cachedAt.IsZero() {
+		log.Printf("Calendar unreachable, using events cached at %s", cachedAt.Format(time.RFC3339))
+	}
+	events, err = cal.ExpandRecurring(events, now, lookahead(now, events))
+	if err != nil {
+		log.Printf("Unable to expand recurring events: %v", err)
+	}
+	return events, cachedAt
+}
+
+// freeBusy queries the busy periods of FreeBusyCalendars up to the
+// lookahead horizon, when free-slot triggers are enabled and the source
+// supports it.
+func freeBusy(ctx context.Context, src cal.Source, events []cal.Event) []cal.Period {
+	if freeSlotMinutes == 0 || len(freeBusyCalendars) == 0 {
+		return nil
+	}
+	busySrc, ok := src.(cal.BusySource)
+	if !ok {
+		log.Printf("Source %s does not support FreeBusyCalendars", source)
+		return nil
+	}
+
+	now := time.Now()
+	busy, err := busySrc.Busy(ctx, freeBusyCalendars, now, lookahead(now, events))
+	if err != nil {
+		log.Printf("Unable to retrieve free/busy information: %v", err)
+		return nil
+	}
+	return busy
+}
+
+// fetchTasks lists the open tasks due within the next week when Tasks is
+// enabled. Failures are only logged so calendar triggers still get
+// scheduled.
+func fetchTasks(ctx context.Context) []cal.Event {
+	if !tasksEnabled {
+		return nil
+	}
+	now := time.Now()
+	items, err := newTaskSource(ctx).Events(ctx, cal.Query{From: now, To: now.AddDate(0, 0, 7)})
+	if err != nil {
+		log.Printf("Unable to retrieve tasks: %v (if Tasks was enabled after %s was created, delete it to grant access)", err, tokenFile)
+		return nil
+	}
+	return items
+}
+
+// runSync fetches upcoming events and replaces the managed cron entries.
+func runSync() {
+	fmt.Printf("Token File: %s\n", tokenFile)
+	fmt.Printf("Calendar ID: %s\n", calendarID)
+	fmt.Printf("Max Events: %d\n", maxRes)
+	fmt.Printf("Command to Execute: %s\n", cmdToExec)
+
+	ctx := context.Background()
+	src := newSource(ctx)
+	events, cachedAt := fetchEvents(ctx, src)
+	events = append(events, fetchTasks(ctx)...)
+	pruneHistory()
+	if len(events) == 0 {
 		fmt.Println("No upcoming events found.")
 	} else {
-		cal.EventParser(events, cmdToExec, backupFile, cronMarker, triggerBeforeMinutes)
+		cal.EventParser(events, planOptions(ctx, src, events, cachedAt))
+	}
+}
+
+// runPlan prints the triggers a sync would schedule without touching the
+// crontab.
+func runPlan() {
+	ctx := context.Background()
+	src := newSource(ctx)
+	events, cachedAt := fetchEvents(ctx, src)
+	events = append(events, fetchTasks(ctx)...)
+	cal.BuildPlan(events, planOptions(ctx, src, events, cachedAt), time.Now()).Write(os.Stdout)
+}
+
+// runExplain prints how the attendance filter and rules treat one event.
+func runExplain(eventID string) {
+	ctx := context.Background()
+	src := newSource(ctx)
+
+	item, err := src.Event(ctx, eventID)
+	if err != nil && tasksEnabled {
+		item, err = newTaskSource(ctx).Event(ctx, eventID)
+	}
+	if err != nil {
+		log.Fatalf("Unable to retrieve event %s: %v", eventID, err)
+	}
+	cal.ExplainEvent(os.Stdout, item, eventOptions())
+}
+
+// runJoin opens the video link of the meeting in progress, or of the next
+// one, with the configured opener.
+func runJoin(next bool) {
+	ctx := context.Background()
+	src := newSource(ctx)
+	// Events are listed by end time, so a meeting in progress is included.
+	events, _ := fetchEvents(ctx, src)
+
+	item, url, err := cal.SelectMeeting(events, eventOptions(), time.Now(), next)
+	if err != nil {
+		log.Fatalf("Unable to find a meeting to join: %v", err)
+	}
+	fmt.Printf("Joining %q: %s\n", item.Summary, url)
+
+	opener := strings.Fields(joinOpener)
+	cmd := exec.Command(opener[0], append(opener[1:], url)...)
+	if err := cmd.Start(); err != nil {
+		log.Fatalf("Unable to run %s: %v", opener[0], err)
+	}
+	_ = cmd.Process.Release()
+}
+
+// runFire runs the command of the trigger recorded under key by the last
+// sync or by snooze, passing the details of its events. Unknown keys still
+// run CmdToExec so the alarm is not lost; triggers whose events were all
+// acknowledged do not run.
+func runFire(key string) {
+	now := time.Now()
+	trigger := cal.StoredTrigger{Name: "unknown", Summary: "unknown trigger", Cmd: cmdToExec}
+	state, err := cal.LoadTriggerState(stateFile)
+	if err != nil {
+		log.Printf("Unable to read trigger state, running CmdToExec: %v", err)
+	} else if stored, ok := state.Lookup(key); ok {
+		pending := false
+		if trigger, pending = state.Unacked(stored, now); !pending {
+			entry := fire.HistoryEntry(fire.NewPayload(key, trigger, now), "", fire.Result{Started: now}, nil)
+			entry.Status = "acknowledged"
+			appendHistory(entry)
+			return
+		}
+	} else {
+		log.Printf("Trigger %s is not in %s, running CmdToExec", key, stateFile)
+	}
+
+	payload := fire.NewPayload(key, trigger, now)
+	if err := execute(payload, trigger.Cmd); err != nil {
+		log.Fatalf("Trigger %s (%s) failed: %v", key, trigger.Summary, err)
+	}
+}
+
+// execute runs cmd, or the action it names, for the payload and records
+// the run in the command log and history.
+func execute(payload fire.Payload, cmd string) error {
+	settings := config.ExecFor(execDefaults, commandExec, cmd)
+	// Without a log file the output goes to cron, which mails it.
+	var stdout, stderr io.Writer = os.Stdout, os.Stderr
+	if settings.LogFile != "-" && settings.LogFile != "" {
+		stdout, stderr = nil, nil
+	}
+	logRun := func(rendered string, result fire.Result, runErr error) {
+		if stdout == nil {
+			commandLog := fire.Log{Path: settings.LogFile, MaxBytes: int64(logMaxKB) << 10, Backups: logBackups}
+			if err := commandLog.Append(payload, rendered, result, runErr); err != nil {
+				log.Printf("Unable to write %s: %v", settings.LogFile, err)
+			}
+		}
+		recordRun(payload, rendered, result, runErr)
+	}
+
+	if name, ok := config.ActionRef(cmd); ok {
+		action, found := actionByName(name)
+		if !found {
+			err := fmt.Errorf("no action named %q", name)
+			recordRun(payload, cmd, fire.Result{Started: time.Now(), ExitCode: -1}, err)
+			return err
+		}
+		result, next, runErr := fire.RunAction(context.Background(), action, payload)
+		if stdout != nil {
+			_, _ = stdout.Write(result.Output)
+		}
+		logRun(cmd, result, runErr)
+		if runErr != nil || next == nil {
+			return runErr
+		}
+		return clicked(payload, *next)
+	}
+
+	rendered, err := fire.Render(cmd, payload)
+	if err != nil {
+		recordRun(payload, cmd, fire.Result{Started: time.Now(), ExitCode: -1}, err)
+		return fmt.Errorf("rendering the command: %w", err)
+	}
+	opts, err := fire.NewExecOptions(settings)
+	if err != nil {
+		return err
+	}
+	result, runErr := fire.Run(context.Background(), rendered, payload, opts, stdout, stderr)
+	logRun(rendered, result, runErr)
+	return runErr
+}
+
+// actionByName returns the configured action called name.
+func actionByName(name string) (config.Action, bool) {
+	for _, action := range actions {
+		if action.Name == name {
+			return action, true
+		}
+	}
+	return config.Action{}, false
+}
+
+// clicked carries out the notification button clicked for the payload.
+func clicked(payload fire.Payload, button config.Button) error {
+	switch {
+	case button.Snooze != "":
+		d, err := time.ParseDuration(button.Snooze)
+		if err != nil {
+			return err
+		}
+		_, err = snooze(payload.Key, d)
+		return err
+	case button.Ack:
+		_, err := ack(payload.Key)
+		return err
+	}
+	return execute(payload, button.Cmd)
+}
+
+// runSnooze snoozes the trigger ref refers to for duration.
+func runSnooze(ref, duration string) {
+	d, err := time.ParseDuration(duration)
+	if err != nil || d < time.Minute {
+		log.Fatalf("Unable to snooze: invalid duration %q (want at least 1m, such as 5m or 1h)", duration)
+	}
+	trigger, err := snooze(ref, d)
+	if err != nil {
+		log.Fatalf("Unable to snooze: %v", err)
+	}
+	fmt.Printf("Snoozed %q until %s\n", trigger.Summary, trigger.At.Format("15:04"))
+}
+
+// snooze adds a one-off trigger that fires the trigger ref refers to, a
+// trigger key or an event ID, again after d.
+func snooze(ref string, d time.Duration) (cal.StoredTrigger, error) {
+	state, err := cal.LoadTriggerState(stateFile)
+	if err != nil {
+		return cal.StoredTrigger{}, err
+	}
+	now := time.Now()
+	_, trigger, ok := state.Resolve(ref, now)
+	if !ok {
+		return cal.StoredTrigger{}, fmt.Errorf("no trigger or event %q in %s", ref, stateFile)
+	}
+	bin, err := os.Executable()
+	if err != nil {
+		return cal.StoredTrigger{}, err
+	}
+	key, snoozed := state.Snooze(trigger, now.Add(d))
+	if err := state.Save(stateFile); err != nil {
+		return cal.StoredTrigger{}, err
+	}
+	if err := cron.AddCrons(cal.CronExpression(snoozed.At), cal.FireKeyCommand(bin, key)); err != nil {
+		return cal.StoredTrigger{}, err
+	}
+	return snoozed, nil
+}
+
+// runAck acknowledges the event ref refers to.
+func runAck(ref string) {
+	trigger, err := ack(ref)
+	if err != nil {
+		log.Fatalf("Unable to acknowledge: %v", err)
+	}
+	fmt.Printf("Acknowledged %q; its remaining triggers will not fire\n", trigger.Summary)
+}
+
+// ack cancels the remaining triggers of an event: ref is an event ID, or a
+// trigger key standing for all the events of that trigger.
+func ack(ref string) (cal.StoredTrigger, error) {
+	state, err := cal.LoadTriggerState(stateFile)
+	if err != nil {
+		return cal.StoredTrigger{}, err
+	}
+	now := time.Now()
+	key, trigger, ok := state.Resolve(ref, now)
+	if !ok {
+		return cal.StoredTrigger{}, fmt.Errorf("no trigger or event %q in %s", ref, stateFile)
+	}
+	ids := []string{ref}
+	if key == ref {
+		ids = ids[:0]
+		for _, item := range trigger.Events {
+			ids = append(ids, item.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return cal.StoredTrigger{}, fmt.Errorf("trigger %s has no events to acknowledge", ref)
+	}
+	state.Ack(ids, now)
+	return trigger, state.Save(stateFile)
+}
+
+// recordRun adds a fire attempt to the history file, when there is one.
+func recordRun(payload fire.Payload, cmd string, result fire.Result, runErr error) {
+	appendHistory(fire.HistoryEntry(payload, cmd, result, runErr))
+}
+
+// appendHistory adds entry to the history file, when there is one.
+func appendHistory(entry history.Entry) {
+	if historyFile == "" {
+		return
+	}
+	if err := history.Append(historyFile, entry); err != nil {
+		log.Printf("Unable to record the run in %s: %v", historyFile, err)
+	}
+}
+
+// runHistory prints the recorded fire attempts.
+func runHistory(args []string) {
+	flags := flag.NewFlagSet("history", flag.ExitOnError)
+	since := flags.String("since", "", "only runs since a duration ago (24h, 7d), a date or an RFC 3339 time")
+	failed := flags.Bool("failed", false, "only runs that did not exit 0")
+	asJSON := flags.Bool("json", false, "print JSON Lines instead of a table")
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+	_ = flags.Parse(args)
+	if flags.NArg() > 0 {
+		log.Fatalf("unexpected history argument %q\n%s", flags.Arg(0), usage)
+	}
+
+	filter := history.Filter{FailedOnly: *failed}
+	if *since != "" {
+		var err error
+		if filter.Since, err = history.ParseSince(*since, time.Now()); err != nil {
+			log.Fatal(err)
+		}
+	}
+	entries, err := history.Load(historyFile)
+	if err != nil {
+		log.Fatalf("Unable to read %s: %v", historyFile, err)
+	}
+	entries = filter.Select(entries)
+	if *asJSON {
+		if err := history.WriteJSON(os.Stdout, entries); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	history.Write(os.Stdout, entries)
+}
+
+// pruneHistory drops runs older than HistoryRetentionDays.
+func pruneHistory() {
+	if historyFile == "" || historyRetentionDays == 0 {
+		return
+	}
+	if err := history.Prune(historyFile, time.Now().AddDate(0, 0, -historyRetentionDays)); err != nil {
+		log.Printf("Unable to prune %s: %v", historyFile, err)
 	}
 }
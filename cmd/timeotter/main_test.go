@@ -1,8 +1,10 @@
 package main
 
 import (
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/bupd/timeotter/pkg/config"
@@ -283,3 +285,33 @@ ShowDeleted = false
 		t.Error("showDeleted should be false")
 	}
 }
+
+// TestE2E_FireQuiet checks that with a command log fire prints nothing, so
+// cron has no output to mail for a trigger.
+func TestE2E_FireQuiet(t *testing.T) {
+	tmpDir := t.TempDir()
+	stateFile = filepath.Join(tmpDir, "triggers.json")
+	cmdToExec = "echo meeting"
+	execDefaults = config.Exec{LogFile: filepath.Join(tmpDir, "commands.log")}
+	historyFile = filepath.Join(tmpDir, "history.jsonl")
+	t.Cleanup(func() { stateFile, cmdToExec, execDefaults, historyFile = "", "", config.Exec{}, "" })
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	runFire("0123456789ab")
+	os.Stdout = stdout
+	_ = w.Close()
+	out, _ := io.ReadAll(r)
+
+	if len(out) != 0 {
+		t.Errorf("fire printed %q", out)
+	}
+	logged, err := os.ReadFile(execDefaults.LogFile)
+	if err != nil || !strings.Contains(string(logged), "meeting") {
+		t.Errorf("command output missing from the log: %q, %v", logged, err)
+	}
+}
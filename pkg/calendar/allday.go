@@ -0,0 +1,84 @@
+package calendar
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/config"
+)
+
+// AllDayOptions controls how all-day events are turned into triggers.
+type AllDayOptions struct {
+	// Policy is one of config.AllDaySkip, config.AllDayAt or
+	// config.AllDayEveningBefore.
+	Policy string
+	// At is the local "HH:MM" time used by the "at" policy.
+	At string
+	// EveningAt is the local "HH:MM" time used by the "evening-before" policy.
+	EveningAt string
+}
+
+// AllDayTriggers returns the trigger times for an all-day event, at wall
+// clock times in the event's time zone, converted to the local zone cron
+// runs in. With the "at" policy a multi-day event fires on every day it
+// spans; "evening-before" fires once, the evening before the first day.
+// Times that are not after now are dropped.
+func AllDayTriggers(item Event, opts AllDayOptions, now time.Time) ([]time.Time, error) {
+	if opts.Policy == config.AllDaySkip || opts.Policy == "" {
+		return nil, nil
+	}
+
+	first := item.Start
+	// The end of an all-day event is exclusive.
+	last := first
+	if item.End.After(first) {
+		last = item.End.AddDate(0, 0, -1)
+	}
+
+	var times []time.Time
+	switch opts.Policy {
+	case config.AllDayAt:
+		hour, minute, err := config.ParseClock(opts.At)
+		if err != nil {
+			return nil, err
+		}
+		for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+			times = append(times, atClock(day, hour, minute))
+		}
+	case config.AllDayEveningBefore:
+		hour, minute, err := config.ParseClock(opts.EveningAt)
+		if err != nil {
+			return nil, err
+		}
+		times = append(times, atClock(first.AddDate(0, 0, -1), hour, minute))
+	default:
+		return nil, fmt.Errorf("unknown all-day policy %q", opts.Policy)
+	}
+
+	upcoming := times[:0]
+	for _, t := range times {
+		if t.After(now) {
+			upcoming = append(upcoming, t.In(time.Local))
+		}
+	}
+	return upcoming, nil
+}
+
+// atClock returns the given wall-clock time on day, honouring DST in day's
+// location.
+func atClock(day time.Time, hour, minute int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, day.Location())
+}
+
+// calendarLocation resolves the calendar's IANA time zone, falling back to
+// the local zone when it is empty or unknown.
+func calendarLocation(name string) *time.Location {
+	if name == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
@@ -0,0 +1,163 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/config"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s not available: %v", name, err)
+	}
+	return loc
+}
+
+// allDayEvent returns an all-day event spanning the dates start to end
+// (exclusive) in loc. An empty end makes it a single day.
+func allDayEvent(t *testing.T, loc *time.Location, start, end string) Event {
+	t.Helper()
+	first, err := time.ParseInLocation("2006-01-02", start, loc)
+	if err != nil {
+		t.Fatalf("parsing %q: %v", start, err)
+	}
+	last := first.AddDate(0, 0, 1)
+	if end != "" {
+		if last, err = time.ParseInLocation("2006-01-02", end, loc); err != nil {
+			t.Fatalf("parsing %q: %v", end, err)
+		}
+	}
+	return Event{Summary: "Holiday", Start: first, End: last, AllDay: true}
+}
+
+func TestAllDayTriggers(t *testing.T) {
+	kolkata := mustLoadLocation(t, "Asia/Kolkata")
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		event Event
+		opts  AllDayOptions
+		want  []string
+	}{
+		{
+			name:  "skip policy",
+			event: allDayEvent(t, kolkata, "2025-03-15", "2025-03-16"),
+			opts:  AllDayOptions{Policy: config.AllDaySkip, At: "08:00", EveningAt: "18:00"},
+			want:  nil,
+		},
+		{
+			name:  "single day at 08:00",
+			event: allDayEvent(t, kolkata, "2025-03-15", "2025-03-16"),
+			opts:  AllDayOptions{Policy: config.AllDayAt, At: "08:00"},
+			want:  []string{"2025-03-15T08:00:00+05:30"},
+		},
+		{
+			name:  "multi-day span fires every day",
+			event: allDayEvent(t, kolkata, "2025-03-15", "2025-03-18"),
+			opts:  AllDayOptions{Policy: config.AllDayAt, At: "09:30"},
+			want: []string{
+				"2025-03-15T09:30:00+05:30",
+				"2025-03-16T09:30:00+05:30",
+				"2025-03-17T09:30:00+05:30",
+			},
+		},
+		{
+			name:  "evening before first day",
+			event: allDayEvent(t, kolkata, "2025-03-15", "2025-03-18"),
+			opts:  AllDayOptions{Policy: config.AllDayEveningBefore, EveningAt: "18:00"},
+			want:  []string{"2025-03-14T18:00:00+05:30"},
+		},
+		{
+			name:  "missing end treated as single day",
+			event: allDayEvent(t, kolkata, "2025-03-15", ""),
+			opts:  AllDayOptions{Policy: config.AllDayAt, At: "08:00"},
+			want:  []string{"2025-03-15T08:00:00+05:30"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AllDayTriggers(tt.event, tt.opts, now)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d triggers %v, want %d", len(got), got, len(tt.want))
+			}
+			for i := range got {
+				if want, _ := time.Parse(time.RFC3339, tt.want[i]); !got[i].Equal(want) {
+					t.Errorf("trigger %d = %s, want %s", i, got[i].Format(time.RFC3339), tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAllDayTriggers_DropsPastDays(t *testing.T) {
+	now := time.Date(2025, 3, 16, 12, 0, 0, 0, time.UTC)
+	got, err := AllDayTriggers(allDayEvent(t, time.UTC, "2025-03-15", "2025-03-18"),
+		AllDayOptions{Policy: config.AllDayAt, At: "08:00"}, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0].Day() != 17 {
+		t.Errorf("expected only the Mar 17 trigger, got %v", got)
+	}
+}
+
+func TestAllDayTriggers_DST(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+
+	// Span crosses the US DST change on 2025-03-09; the wall-clock time
+	// stays at 08:00 while the UTC offset changes.
+	got, err := AllDayTriggers(allDayEvent(t, newYork, "2025-03-08", "2025-03-10"),
+		AllDayOptions{Policy: config.AllDayAt, At: "08:00"}, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"2025-03-08T08:00:00-05:00", "2025-03-09T08:00:00-04:00"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range got {
+		if w, _ := time.Parse(time.RFC3339, want[i]); !got[i].Equal(w) {
+			t.Errorf("trigger %d = %s, want %s", i, got[i].Format(time.RFC3339), want[i])
+		}
+	}
+}
+
+// TestAllDayTriggers_LocalZone checks that triggers of a calendar in
+// another zone are scheduled at the matching local time, as cron reads
+// the crontab in the system's zone.
+func TestAllDayTriggers_LocalZone(t *testing.T) {
+	kolkata := mustLoadLocation(t, "Asia/Kolkata")
+	newYork := mustLoadLocation(t, "America/New_York")
+	local := time.Local
+	time.Local = newYork
+	t.Cleanup(func() { time.Local = local })
+
+	got, err := AllDayTriggers(allDayEvent(t, kolkata, "2025-03-15", ""),
+		AllDayOptions{Policy: config.AllDayAt, At: "08:00"}, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0].Location() != time.Local {
+		t.Fatalf("got %v, want one trigger in the local zone", got)
+	}
+	// 08:00 in Kolkata is 22:30 the evening before in New York.
+	if cron := CronExpression(got[0]); cron != "30 22 14 3 5" {
+		t.Errorf("CronExpression() = %q, want %q", cron, "30 22 14 3 5")
+	}
+}
+
+func TestCronExpression(t *testing.T) {
+	loc := time.FixedZone("IST", 5*3600+1800)
+	got := CronExpression(time.Date(2025, 2, 2, 20, 25, 0, 0, loc))
+	if got != "25 20 2 2 0" {
+		t.Errorf("CronExpression() = %q, want %q", got, "25 20 2 2 0")
+	}
+}
@@ -0,0 +1,56 @@
+package calendar
+
+import "fmt"
+
+// Response statuses reported by the Calendar API for an attendee.
+const (
+	StatusAccepted    = "accepted"
+	StatusTentative   = "tentative"
+	StatusNeedsAction = "needsAction"
+	StatusDeclined    = "declined"
+)
+
+// SelfResponseStatus returns the authenticated user's response to an event.
+// Events without a self attendee (e.g. ones created without guests) are
+// treated as accepted.
+func SelfResponseStatus(item Event) string {
+	for _, attendee := range item.Attendees {
+		if attendee.Self {
+			if attendee.ResponseStatus == "" {
+				return StatusNeedsAction
+			}
+			return attendee.ResponseStatus
+		}
+	}
+	return StatusAccepted
+}
+
+// AttendanceFilter selects events based on the user's RSVP and the
+// event's transparency.
+type AttendanceFilter struct {
+	// Statuses lists the response statuses that produce triggers. An empty
+	// list disables the RSVP check.
+	Statuses []string
+	// SkipFree excludes events marked as "free".
+	SkipFree bool
+}
+
+// Allows reports whether item should produce a trigger. When it does not,
+// the returned reason explains why.
+func (f AttendanceFilter) Allows(item Event) (bool, string) {
+	if f.SkipFree && item.Free {
+		return false, "event is marked as free"
+	}
+
+	if len(f.Statuses) == 0 {
+		return true, ""
+	}
+
+	status := SelfResponseStatus(item)
+	for _, allowed := range f.Statuses {
+		if status == allowed {
+			return true, ""
+		}
+	}
+	return false, fmt.Sprintf("response status is %s", status)
+}
@@ -0,0 +1,103 @@
+package calendar
+
+import "testing"
+
+func eventWithSelfStatus(status string) Event {
+	return Event{
+		Summary: "Sync",
+		Attendees: []Attendee{
+			{Email: "someone@example.com", ResponseStatus: StatusAccepted},
+			{Email: "me@example.com", Self: true, ResponseStatus: status},
+		},
+	}
+}
+
+func TestSelfResponseStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    Event
+		expected string
+	}{
+		{
+			name:     "no attendees treated as accepted",
+			event:    Event{Summary: "Focus"},
+			expected: StatusAccepted,
+		},
+		{
+			name: "no self attendee treated as accepted",
+			event: Event{Attendees: []Attendee{
+				{Email: "other@example.com", ResponseStatus: StatusDeclined},
+			}},
+			expected: StatusAccepted,
+		},
+		{
+			name:     "declined",
+			event:    eventWithSelfStatus(StatusDeclined),
+			expected: StatusDeclined,
+		},
+		{
+			name:     "tentative",
+			event:    eventWithSelfStatus(StatusTentative),
+			expected: StatusTentative,
+		},
+		{
+			name:     "empty status treated as needsAction",
+			event:    eventWithSelfStatus(""),
+			expected: StatusNeedsAction,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SelfResponseStatus(tt.event); got != tt.expected {
+				t.Errorf("SelfResponseStatus() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAttendanceFilter_Allows(t *testing.T) {
+	defaultFilter := AttendanceFilter{Statuses: []string{StatusAccepted, StatusTentative}}
+
+	freeEvent := eventWithSelfStatus(StatusAccepted)
+	freeEvent.Free = true
+
+	tests := []struct {
+		name   string
+		filter AttendanceFilter
+		event  Event
+		want   bool
+	}{
+		{"accepted allowed", defaultFilter, eventWithSelfStatus(StatusAccepted), true},
+		{"tentative allowed", defaultFilter, eventWithSelfStatus(StatusTentative), true},
+		{"declined skipped", defaultFilter, eventWithSelfStatus(StatusDeclined), false},
+		{"needsAction skipped", defaultFilter, eventWithSelfStatus(StatusNeedsAction), false},
+		{
+			"needsAction allowed when configured",
+			AttendanceFilter{Statuses: []string{StatusNeedsAction}},
+			eventWithSelfStatus(StatusNeedsAction),
+			true,
+		},
+		{"own event without guests allowed", defaultFilter, Event{Summary: "Focus"}, true},
+		{"free event allowed by default", defaultFilter, freeEvent, true},
+		{
+			"free event skipped when SkipFree",
+			AttendanceFilter{Statuses: []string{StatusAccepted}, SkipFree: true},
+			freeEvent,
+			false,
+		},
+		{"empty status list disables the check", AttendanceFilter{}, eventWithSelfStatus(StatusDeclined), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := tt.filter.Allows(tt.event)
+			if got != tt.want {
+				t.Errorf("Allows() = %v (%s), want %v", got, reason, tt.want)
+			}
+			if !got && reason == "" {
+				t.Error("expected a reason when event is skipped")
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package calendar
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/config"
+)
+
+// AwaySpan is a period during which the user is away, e.g. an
+// out-of-office block or a public holiday.
+type AwaySpan struct {
+	Start  time.Time
+	End    time.Time
+	Reason string
+}
+
+// Contains reports whether t falls inside the span.
+func (s AwaySpan) Contains(t time.Time) bool {
+	return !t.Before(s.Start) && t.Before(s.End)
+}
+
+// AwayOptions controls suppressing triggers while the user is away.
+type AwayOptions struct {
+	// DetectOutOfOffice treats outOfOffice events of the synced calendar
+	// as away spans.
+	DetectOutOfOffice bool
+	// Spans holds additional away spans, e.g. from holiday calendars.
+	Spans []AwaySpan
+	// Action is config.AwaySuppress or config.AwayCommand.
+	Action string
+	// Cmd replaces the trigger command when Action is config.AwayCommand.
+	Cmd string
+}
+
+// AwaySpans converts events into away spans, labelling each with label and
+// the event summary.
+func AwaySpans(events []Event, label string) []AwaySpan {
+	var spans []AwaySpan
+	for _, item := range events {
+		spans = append(spans, AwaySpan{Start: item.Start, End: item.End, Reason: fmt.Sprintf("%s: %s", label, item.Summary)})
+	}
+	return spans
+}
+
+// isOutOfOffice reports whether item is an out-of-office block.
+func isOutOfOffice(item Event) bool {
+	return item.Type == "outOfOffice"
+}
+
+// outOfOfficeSpans collects the out-of-office blocks among events.
+func outOfOfficeSpans(events []Event) []AwaySpan {
+	var ooo []Event
+	for _, item := range events {
+		if isOutOfOffice(item) {
+			ooo = append(ooo, item)
+		}
+	}
+	return AwaySpans(ooo, "out of office")
+}
+
+// awayAt returns the away span covering t, if any.
+func awayAt(spans []AwaySpan, t time.Time) (AwaySpan, bool) {
+	for _, span := range spans {
+		if span.Contains(t) {
+			return span, true
+		}
+	}
+	return AwaySpan{}, false
+}
+
+// awaySpans returns every away span that applies to events.
+func (opts Options) awaySpans(events []Event) []AwaySpan {
+	spans := append([]AwaySpan(nil), opts.Away.Spans...)
+	if opts.Away.DetectOutOfOffice {
+		spans = append(spans, outOfOfficeSpans(events)...)
+	}
+	return spans
+}
+
+// redirectsAway reports whether away triggers run Cmd instead of being
+// suppressed.
+func (a AwayOptions) redirectsAway() bool {
+	return a.Action == config.AwayCommand && a.Cmd != ""
+}
@@ -0,0 +1,96 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/config"
+)
+
+func TestAwaySpans(t *testing.T) {
+	holidays := []Event{allDayEvent(t, time.UTC, "2025-03-14", "2025-03-15")}
+	holidays[0].Summary = "Holi"
+
+	spans := AwaySpans(holidays, "holiday")
+	if len(spans) != 1 || spans[0].Reason != "holiday: Holi" {
+		t.Fatalf("unexpected spans %+v", spans)
+	}
+	if !spans[0].Contains(time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC)) {
+		t.Error("span should contain a time on the holiday")
+	}
+	if spans[0].Contains(time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Error("span end should be exclusive")
+	}
+}
+
+func TestBuildPlan_OutOfOffice(t *testing.T) {
+	ooo := timedEvent(t, "ooo", "Vacation", "2025-03-17T00:00:00+00:00", "2025-03-22T00:00:00+00:00")
+	ooo.Type = "outOfOffice"
+	standup := timedEvent(t, "s1", "Standup", "2025-03-18T09:30:00+00:00", "2025-03-18T09:45:00+00:00")
+	afterwards := timedEvent(t, "s2", "Standup", "2025-03-24T09:30:00+00:00", "2025-03-24T09:45:00+00:00")
+	events := []Event{ooo, standup, afterwards}
+
+	t.Run("suppress", func(t *testing.T) {
+		plan := BuildPlan(events, Options{
+			CmdToExec: "join",
+			Away:      AwayOptions{DetectOutOfOffice: true, Action: config.AwaySuppress},
+		}, planNow())
+
+		if len(plan.Triggers) != 1 || plan.Triggers[0].EventID != "s2" {
+			t.Errorf("expected only the standup after vacation, got %+v", plan.Triggers)
+		}
+		reasons := ""
+		for _, s := range plan.Skipped {
+			reasons += s.Reason + ";"
+		}
+		if !strings.Contains(reasons, "out-of-office block") || !strings.Contains(reasons, "during out of office: Vacation") {
+			t.Errorf("unexpected skip reasons %q", reasons)
+		}
+	})
+
+	t.Run("command", func(t *testing.T) {
+		plan := BuildPlan(events, Options{
+			CmdToExec: "join",
+			Away:      AwayOptions{DetectOutOfOffice: true, Action: config.AwayCommand, Cmd: "decline.sh"},
+		}, planNow())
+
+		if len(plan.Triggers) != 2 {
+			t.Fatalf("expected 2 triggers, got %+v", plan.Triggers)
+		}
+		if plan.Triggers[0].Cmd != "decline.sh" || !strings.Contains(plan.Triggers[0].Note, "away command") {
+			t.Errorf("expected away command, got %+v", plan.Triggers[0])
+		}
+		if plan.Triggers[1].Cmd != "join" {
+			t.Errorf("expected normal command after vacation, got %+v", plan.Triggers[1])
+		}
+	})
+
+	t.Run("detection disabled", func(t *testing.T) {
+		plan := BuildPlan(events, Options{CmdToExec: "join"}, planNow())
+		if len(plan.Triggers) != 3 {
+			t.Errorf("expected every event to trigger, got %+v", plan.Triggers)
+		}
+	})
+}
+
+func TestBuildPlan_HolidaySpans(t *testing.T) {
+	events := []Event{
+		timedEvent(t, "s1", "Standup", "2025-03-14T09:30:00+00:00", "2025-03-14T09:45:00+00:00"),
+	}
+	plan := BuildPlan(events, Options{
+		CmdToExec: "join",
+		Away: AwayOptions{
+			Action: config.AwaySuppress,
+			Spans: []AwaySpan{{
+				Start:  time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC),
+				End:    time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC),
+				Reason: "holiday: Holi",
+			}},
+		},
+	}, planNow())
+
+	if len(plan.Triggers) != 0 || len(plan.Skipped) != 1 || !strings.Contains(plan.Skipped[0].Reason, "holiday: Holi") {
+		t.Errorf("expected standup on holiday to be suppressed, got %+v", plan)
+	}
+}
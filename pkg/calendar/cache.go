@@ -0,0 +1,94 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// EventCache keeps the last successful fetch on disk so triggers can still
+// be generated while the calendar is unreachable.
+type EventCache struct {
+	Path string
+	// MaxAge is the oldest cache that may be used. Zero disables the
+	// fallback.
+	MaxAge time.Duration
+}
+
+// cachedEvents is the on-disk form of the cache.
+type cachedEvents struct {
+	FetchedAt time.Time `json:"fetchedAt"`
+	Events    []Event   `json:"events"`
+}
+
+// Fetch lists events from src and saves them to the cache. When src fails
+// and a fresh enough cache exists, the cached events that have not ended
+// by now are returned instead, together with the time they were fetched.
+// The returned time is zero for a live fetch.
+func (c EventCache) Fetch(ctx context.Context, src Source, q Query, now time.Time) ([]Event, time.Time, error) {
+	events, err := src.Events(ctx, q)
+	if err == nil {
+		if c.Path != "" {
+			if saveErr := c.Save(events, now); saveErr != nil {
+				log.Printf("Unable to save event cache: %v", saveErr)
+			}
+		}
+		return events, time.Time{}, nil
+	}
+
+	cached, fetchedAt, cacheErr := c.Load(now)
+	if cacheErr != nil {
+		return nil, time.Time{}, fmt.Errorf("%w (no usable cache: %v)", err, cacheErr)
+	}
+	return cached, fetchedAt, nil
+}
+
+// Save writes events to the cache file, replacing it atomically.
+func (c EventCache) Save(events []Event, now time.Time) error {
+	data, err := json.Marshal(cachedEvents{FetchedAt: now, Events: events})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0750); err != nil {
+		return err
+	}
+
+	tmp := c.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.Path)
+}
+
+// Load reads the cache, dropping events that have ended by now; recurring
+// series are kept for ExpandRecurring to pick their instances. It fails
+// when the fallback is disabled or the cache is older than MaxAge.
+func (c EventCache) Load(now time.Time) ([]Event, time.Time, error) {
+	if c.MaxAge <= 0 || c.Path == "" {
+		return nil, time.Time{}, fmt.Errorf("offline cache is disabled")
+	}
+
+	data, err := os.ReadFile(filepath.Clean(c.Path))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	var cached cachedEvents
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, time.Time{}, fmt.Errorf("parsing %s: %w", c.Path, err)
+	}
+	if age := now.Sub(cached.FetchedAt); age > c.MaxAge {
+		return nil, time.Time{}, fmt.Errorf("cache is %s old, older than %s", age.Round(time.Minute), c.MaxAge)
+	}
+
+	var upcoming []Event
+	for _, item := range cached.Events {
+		if item.End.After(now) || len(item.Recurrence) > 0 {
+			upcoming = append(upcoming, item)
+		}
+	}
+	return upcoming, cached.FetchedAt, nil
+}
@@ -0,0 +1,113 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSource returns a fixed set of events, or err when it is set.
+type fakeSource struct {
+	events []Event
+	err    error
+}
+
+func (s fakeSource) Events(context.Context, Query) ([]Event, error) {
+	return s.events, s.err
+}
+
+func (s fakeSource) Event(_ context.Context, id string) (Event, error) {
+	for _, item := range s.events {
+		if item.ID == id {
+			return item, nil
+		}
+	}
+	return Event{}, errors.New("not found")
+}
+
+func TestEventCache_Fetch(t *testing.T) {
+	cache := EventCache{Path: filepath.Join(t.TempDir(), "cache", "events.json"), MaxAge: 24 * time.Hour}
+	fetchedAt := time.Date(2025, 3, 17, 8, 0, 0, 0, time.UTC)
+
+	morning := timedEvent(t, "a", "Standup", "2025-03-17T09:30:00+05:30", "2025-03-17T09:45:00+05:30")
+	morning.Links = []Link{{Kind: LinkVideo, URL: "https://meet.google.com/abc"}}
+	afternoon := timedEvent(t, "b", "Review", "2025-03-17T15:00:00+05:30", "2025-03-17T16:00:00+05:30")
+	weekly := timedEvent(t, "c", "Weekly", "2025-03-10T09:00:00Z", "2025-03-10T09:30:00Z")
+	weekly.Recurrence = []string{"RRULE:FREQ=WEEKLY"}
+	live := fakeSource{events: []Event{morning, afternoon, weekly}}
+
+	events, cachedAt, err := cache.Fetch(context.Background(), live, Query{}, fetchedAt)
+	if err != nil || len(events) != 3 || !cachedAt.IsZero() {
+		t.Fatalf("live fetch = %d events, %v, %v", len(events), cachedAt, err)
+	}
+
+	offline := fakeSource{err: errors.New("dial tcp: no route to host")}
+	later := fetchedAt.Add(time.Hour)
+	events, cachedAt, err = cache.Fetch(context.Background(), offline, Query{}, later)
+	if err != nil {
+		t.Fatalf("expected cached events, got %v", err)
+	}
+	if !cachedAt.Equal(fetchedAt) {
+		t.Errorf("cachedAt = %s, want %s", cachedAt, fetchedAt)
+	}
+	// The series stays although its first occurrence has ended.
+	if len(events) != 2 || events[0].ID != "b" || events[1].ID != "c" {
+		t.Fatalf("expected the afternoon event and the series, got %+v", events)
+	}
+	if !events[0].Start.Equal(afternoon.Start) || events[0].Start.Format(time.RFC3339) != "2025-03-17T15:00:00+05:30" {
+		t.Errorf("start not preserved: %s", events[0].Start)
+	}
+	if CronExpression(events[0].Start) != CronExpression(afternoon.Start) {
+		t.Errorf("cron expression changed after caching: %s", CronExpression(events[0].Start))
+	}
+}
+
+func TestEventCache_Refuses(t *testing.T) {
+	dir := t.TempDir()
+	fetchedAt := time.Date(2025, 3, 17, 8, 0, 0, 0, time.UTC)
+	offline := fakeSource{err: errors.New("unreachable")}
+
+	cache := EventCache{Path: filepath.Join(dir, "events.json"), MaxAge: 24 * time.Hour}
+	if _, _, err := cache.Fetch(context.Background(), offline, Query{}, fetchedAt); err == nil ||
+		!strings.Contains(err.Error(), "unreachable") {
+		t.Errorf("expected error without cache, got %v", err)
+	}
+
+	if err := cache.Save([]Event{timedEvent(t, "a", "Sync", "2025-03-20T10:00:00Z", "2025-03-20T11:00:00Z")}, fetchedAt); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, _, err := cache.Load(fetchedAt.Add(25 * time.Hour)); err == nil || !strings.Contains(err.Error(), "older than") {
+		t.Errorf("expected cache to be too old, got %v", err)
+	}
+
+	disabled := EventCache{Path: cache.Path}
+	if _, _, err := disabled.Load(fetchedAt); err == nil {
+		t.Error("expected zero MaxAge to disable the fallback")
+	}
+
+	if err := os.WriteFile(cache.Path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := cache.Load(fetchedAt); err == nil {
+		t.Error("expected error for a corrupt cache")
+	}
+}
+
+func TestBuildPlan_Stale(t *testing.T) {
+	cachedAt := time.Date(2025, 2, 28, 18, 0, 0, 0, time.UTC)
+	plan := BuildPlan([]Event{timedEvent(t, "a", "Sync", "2025-03-15T10:00:00Z", "2025-03-15T11:00:00Z")},
+		Options{CmdToExec: "notify", CachedAt: cachedAt}, planNow())
+	if !plan.Stale() || len(plan.Triggers) != 1 {
+		t.Fatalf("expected a stale plan with one trigger, got %+v", plan)
+	}
+
+	var out strings.Builder
+	plan.Write(&out)
+	if !strings.HasPrefix(out.String(), "Warning: calendar unreachable, using events cached at Fri 2025-02-28 18:00") {
+		t.Errorf("missing stale warning:\n%s", out.String())
+	}
+}
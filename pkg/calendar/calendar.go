@@ -1,45 +1,137 @@
-// Package calendar provides utilities for parsing Google Calendar events.
+// Package calendar turns calendar events into scheduled triggers.
 package calendar
 
 import (
 	"fmt"
 	"log"
+	"maps"
+	"slices"
 	"time"
 
+	"github.com/bupd/timeotter/pkg/config"
 	"github.com/bupd/timeotter/pkg/cron"
-	"google.golang.org/api/calendar/v3"
 )
 
+// Options controls how EventParser turns events into cron jobs.
+type Options struct {
+	CmdToExec            string
+	BackupFile           string
+	CronMarker           string
+	TriggerBeforeMinutes int
+	Attendance           AttendanceFilter
+	Rules                *RuleSet
+	AllDay               AllDayOptions
+	TriggerPoints        []config.TriggerPoint
+	Reminders            ReminderOptions
+	Tasks                TaskOptions
+	FreeSlots            FreeSlotOptions
+	// Routes picks the command and lead time of each event.
+	Routes *RouteSet
+	// Directives lets single events override their triggers.
+	Directives DirectiveOptions
+	// Travel adds "leave" triggers for events at known locations.
+	Travel *TravelTimes
+	// CalendarID selects per-calendar overrides such as working hours for
+	// events that do not record their own calendar, and for free slots.
+	CalendarID string
+	Hours      HoursPolicy
+	Away       AwayOptions
+	// Coalesce merges same-minute triggers that run the same command.
+	Coalesce bool
+	// BackToBackGap suppresses triggers that fire before an event while
+	// another event, ending at most this long before it starts, is running.
+	BackToBackGap time.Duration
+	// CachedAt is set when the events were loaded from the offline cache
+	// and records when they were fetched.
+	CachedAt time.Time
+	// FireCmd is the path of the timeotter executable. Crontab entries run
+	// `timeotter fire <key>`, which renders the command of the trigger
+	// recorded in StateFile; EventParser requires it.
+	FireCmd   string
+	StateFile string
+	// Acked holds the event occurrences acknowledged with `timeotter ack`,
+	// which get no triggers until the ack expires.
+	Acked map[string]time.Time
+}
+
 // EventParser parses calendar events and creates cron jobs for each event.
-func EventParser(events *calendar.Events, cmdToExec string, backupFile string, cronMarker string, triggerBeforeMinutes int) {
-	err := cron.ClearCronJobs(backupFile, cronMarker)
+func EventParser(events []Event, opts Options) {
+	// Commands are templates that only fire can render, so they cannot go
+	// into the crontab as they are.
+	if opts.FireCmd == "" {
+		log.Fatalf("scheduling triggers needs the path of the timeotter executable")
+	}
+	err := cron.ClearCronJobs(opts.BackupFile, opts.CronMarker)
 	if err != nil {
 		log.Fatalf("clearing cron jobs failed: %v", err)
 	}
 
-	for _, item := range events.Items {
-		date := item.Start.DateTime
-		if date == "" {
-			date = item.Start.Date
+	now := time.Now()
+	plan := BuildPlan(events, opts, now)
+	if plan.Stale() {
+		fmt.Println(plan.staleWarning())
+	}
+	for _, skipped := range plan.Skipped {
+		fmt.Printf("Skipping %q: %s\n", skipped.Summary, skipped.Reason)
+	}
+	state := NewTriggerState(plan, events, now)
+	if previous, err := LoadTriggerState(opts.StateFile); err == nil {
+		state.Carry(previous, now)
+	}
+	if err := state.Save(opts.StateFile); err != nil {
+		log.Fatalf("saving trigger state failed: %v", err)
+	}
+	for _, trigger := range plan.Triggers {
+		// fmt.Printf("cron string: %s:- ", CronExpression(trigger.At))
+		// fmt.Printf("%v (%v)\n", trigger.Summary, trigger.At)
+		err := cron.AddCrons(CronExpression(trigger.At), trigger.FireCommand(opts.FireCmd))
+		if err != nil {
+			log.Fatalf("unable to add crons: %v", err)
 		}
-		// fmt.Printf("cron string: %s:- ", ConvertTimeToCron(date))
-		// fmt.Printf("%v (%v)\n", item.Summary, date)
-		cronStr := ConvertTimeToCron(date, triggerBeforeMinutes)
-		err := cron.AddCrons(cronStr, cmdToExec)
+	}
+	for _, key := range slices.Sorted(maps.Keys(state.Snoozed)) {
+		err := cron.AddCrons(CronExpression(state.Snoozed[key].At), FireKeyCommand(opts.FireCmd, key))
 		if err != nil {
 			log.Fatalf("unable to add crons: %v", err)
 		}
 	}
 }
 
+// shouldTrigger applies the attendance filter and the rule set to item.
+// When the event is skipped, the returned reason explains why.
+func shouldTrigger(item Event, opts Options) (bool, string) {
+	if ok, reason := opts.Attendance.Allows(item); !ok {
+		return false, reason
+	}
+	decision := opts.Rules.Evaluate(item)
+	if !decision.Include {
+		if decision.Rule == "" {
+			return false, "excluded by default rule action"
+		}
+		return false, fmt.Sprintf("excluded by rule %q", decision.Rule)
+	}
+	return true, ""
+}
+
 // ConvertTimeToCron takes a time in string format and returns the cron expression.
 // triggerBeforeMinutes specifies how many minutes before the event to trigger.
 func ConvertTimeToCron(timeStr string, triggerBeforeMinutes int) string {
-	// Parse the input time string
+	// Parse errors leave t at the zero time, as before.
+	t, _ := ParseEventTime(timeStr)
+
+	// Subtract triggerBeforeMinutes from the given time
+	t = t.Add(-time.Duration(triggerBeforeMinutes) * time.Minute)
+
+	return CronExpression(t)
+}
+
+// ParseEventTime parses an event's dateTime, keeping its UTC offset.
+func ParseEventTime(timeStr string) (time.Time, error) {
 	// Try multiple layouts to parse the time string
 	layouts := []string{
 		"2006-01-02T15:04:05-07:00", // e.g., 2025-02-02T20:29:00+05:30
 		"2006/01/02T15:04:05-07:00", // e.g., 2025/02/02T20:29:00+05:30 (slashes in date)
+		time.RFC3339,                // e.g., 2025-02-02T14:59:00Z
 	}
 
 	var t time.Time
@@ -48,13 +140,15 @@ func ConvertTimeToCron(timeStr string, triggerBeforeMinutes int) string {
 	for _, layout := range layouts {
 		t, err = time.Parse(layout, timeStr)
 		if err == nil {
-			break
+			return t, nil
 		}
 	}
+	return t, fmt.Errorf("unrecognized event time %q", timeStr)
+}
 
-	// Subtract triggerBeforeMinutes from the given time
-	t = t.Add(-time.Duration(triggerBeforeMinutes) * time.Minute)
-
+// CronExpression returns the cron expression that fires at t, in t's own
+// location.
+func CronExpression(t time.Time) string {
 	// Extract time components
 	_ = t.Second()
 	mins := t.Minute()
@@ -0,0 +1,144 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// busySpan is the time range of an event that produces triggers.
+type busySpan struct {
+	id      string
+	summary string
+	start   time.Time
+	end     time.Time
+}
+
+// busySpans collects the spans of the timed events among items.
+func busySpans(items []Event) []busySpan {
+	var spans []busySpan
+	for _, item := range items {
+		if item.AllDay {
+			continue
+		}
+		spans = append(spans, busySpan{id: item.ID, summary: item.Summary, start: item.Start, end: item.End})
+	}
+	return spans
+}
+
+// stillBusy reports whether a trigger at, firing before item starts, would
+// interrupt another event that is running at that moment and ends no
+// earlier than gap before item's start. A zero gap disables the check.
+func stillBusy(busy []busySpan, item Event, at time.Time, gap time.Duration) (busySpan, bool) {
+	start := item.Start
+	if gap <= 0 || !at.Before(start) {
+		return busySpan{}, false
+	}
+
+	for _, other := range busy {
+		if other.id == item.ID {
+			continue
+		}
+		running := !other.start.After(at) && other.end.After(at)
+		if running && !other.end.Before(start.Add(-gap)) {
+			return other, true
+		}
+	}
+	return busySpan{}, false
+}
+
+// Coalesce merges triggers that fire in the same minute with the same
+// command into one execution covering all of their events. triggers must
+// be sorted by time.
+func Coalesce(triggers []Trigger) []Trigger {
+	type coalesceKey struct {
+		at  time.Time
+		cmd string
+	}
+	var merged []Trigger
+	index := make(map[coalesceKey]int)
+
+	for _, t := range triggers {
+		// Events from calendars in other zones carry other offsets for the
+		// same instant.
+		key := coalesceKey{t.At.UTC().Truncate(time.Minute), t.Cmd}
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(merged)
+			merged = append(merged, t)
+			continue
+		}
+
+		m := &merged[i]
+		m.Events = append(m.Events, t.Events...)
+		if m.JoinURL == "" {
+			m.JoinURL = t.JoinURL
+		}
+		if !containsName(m.Name, t.Name) {
+			m.Name += "+" + t.Name
+		}
+		m.Note = fmt.Sprintf("coalesced %d events", len(m.Events))
+	}
+	return merged
+}
+
+func containsName(names, name string) bool {
+	for _, n := range strings.Split(names, "+") {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// EventIDs returns the IDs of every event behind the trigger.
+func (t Trigger) EventIDs() []string {
+	ids := make([]string, 0, len(t.Events))
+	for _, ref := range t.Events {
+		if ref.ID != "" {
+			ids = append(ids, ref.ID)
+		}
+	}
+	return ids
+}
+
+// CronCommand returns the crontab command for the trigger. The IDs of the
+// events behind it are passed in TIMEOTTER_EVENT_IDS, comma separated, the
+// meeting link, if any, in TIMEOTTER_JOIN_URL and the length of a free gap
+// in TIMEOTTER_GAP_MINUTES.
+func (t Trigger) CronCommand() string {
+	var env []string
+	if ids := t.EventIDs(); len(ids) > 0 {
+		env = append(env, "TIMEOTTER_EVENT_IDS="+strings.Join(ids, ","))
+	}
+	if t.JoinURL != "" {
+		env = append(env, "TIMEOTTER_JOIN_URL="+cronQuote(t.JoinURL))
+	}
+	if t.GapMinutes > 0 {
+		env = append(env, fmt.Sprintf("TIMEOTTER_GAP_MINUTES=%d", t.GapMinutes))
+	}
+	if len(env) == 0 {
+		return t.Cmd
+	}
+	return strings.Join(env, " ") + " " + t.Cmd
+}
+
+// cronQuote single-quotes s for the shell and escapes the percent signs
+// that cron would otherwise turn into newlines.
+func cronQuote(s string) string {
+	s = strings.ReplaceAll(s, "'", `'\''`)
+	s = strings.ReplaceAll(s, "%", `\%`)
+	return "'" + s + "'"
+}
+
+// summaries joins the summaries of every event behind the trigger.
+func (t Trigger) summaries() string {
+	if len(t.Events) == 0 {
+		return t.Summary
+	}
+	names := make([]string, 0, len(t.Events))
+	for _, ref := range t.Events {
+		names = append(names, ref.Summary)
+	}
+	return strings.Join(names, ", ")
+}
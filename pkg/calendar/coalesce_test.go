@@ -0,0 +1,113 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/config"
+)
+
+func TestCoalesce(t *testing.T) {
+	at := time.Date(2025, 3, 17, 10, 0, 0, 0, time.UTC)
+	triggers := []Trigger{
+		{EventID: "a", Summary: "Sync", Events: []EventRef{{"a", "Sync"}}, Name: "start", At: at, Cmd: "join"},
+		{EventID: "b", Summary: "Review", Events: []EventRef{{"b", "Review"}}, Name: "start", At: at.Add(20 * time.Second), Cmd: "join"},
+		{EventID: "c", Summary: "Interview", Events: []EventRef{{"c", "Interview"}}, Name: "prep", At: at.In(time.FixedZone("CET", 3600)), Cmd: "join"},
+		{EventID: "d", Summary: "Lamp", Events: []EventRef{{"d", "Lamp"}}, Name: "start", At: at, Cmd: "lamp on"},
+		{EventID: "e", Summary: "Later", Events: []EventRef{{"e", "Later"}}, Name: "start", At: at.Add(time.Minute), Cmd: "join"},
+	}
+
+	got := Coalesce(triggers)
+	if len(got) != 3 {
+		t.Fatalf("expected 3 triggers, got %d: %+v", len(got), got)
+	}
+
+	merged := got[0]
+	if strings.Join(merged.EventIDs(), ",") != "a,b,c" {
+		t.Errorf("merged event IDs = %v", merged.EventIDs())
+	}
+	if merged.Name != "start+prep" || merged.Note != "coalesced 3 events" {
+		t.Errorf("merged trigger = %+v", merged)
+	}
+	if got[1].Cmd != "lamp on" || len(got[1].Events) != 1 {
+		t.Errorf("different command should not merge: %+v", got[1])
+	}
+	if got[2].EventID != "e" {
+		t.Errorf("different minute should not merge: %+v", got[2])
+	}
+}
+
+func TestTrigger_CronCommand(t *testing.T) {
+	trigger := Trigger{Cmd: "notify-send hi", Events: []EventRef{{"abc", "A"}, {"def_20250317T100000Z", "B"}}}
+	if got := trigger.CronCommand(); got != "TIMEOTTER_EVENT_IDS=abc,def_20250317T100000Z notify-send hi" {
+		t.Errorf("CronCommand() = %q", got)
+	}
+	if got := (Trigger{Cmd: "notify-send hi"}).CronCommand(); got != "notify-send hi" {
+		t.Errorf("CronCommand() without events = %q", got)
+	}
+}
+
+func TestBuildPlan_CoalescesOverlappingEvents(t *testing.T) {
+	events := []Event{
+		timedEvent(t, "a", "Sync", "2025-03-17T10:00:00+00:00", "2025-03-17T10:30:00+00:00"),
+		timedEvent(t, "b", "Review", "2025-03-17T10:00:00+00:00", "2025-03-17T11:00:00+00:00"),
+		timedEvent(t, "c", "Town hall", "2025-03-17T10:00:00+00:00", "2025-03-17T11:00:00+00:00"),
+	}
+	opts := Options{CmdToExec: "join", Coalesce: true}
+
+	plan := BuildPlan(events, opts, planNow())
+	if len(plan.Triggers) != 1 {
+		t.Fatalf("expected one coalesced trigger, got %+v", plan.Triggers)
+	}
+	if got := plan.Triggers[0].summaries(); got != "Sync, Review, Town hall" {
+		t.Errorf("summaries = %q", got)
+	}
+
+	opts.Coalesce = false
+	if plan := BuildPlan(events, opts, planNow()); len(plan.Triggers) != 3 {
+		t.Errorf("expected 3 triggers without coalescing, got %d", len(plan.Triggers))
+	}
+}
+
+func TestBuildPlan_BackToBack(t *testing.T) {
+	events := []Event{
+		timedEvent(t, "a", "Planning", "2025-03-17T09:00:00+00:00", "2025-03-17T10:00:00+00:00"),
+		timedEvent(t, "b", "Retro", "2025-03-17T10:00:00+00:00", "2025-03-17T11:00:00+00:00"),
+		timedEvent(t, "c", "Lunch talk", "2025-03-17T11:30:00+00:00", "2025-03-17T12:00:00+00:00"),
+	}
+	opts := Options{
+		CmdToExec: "notify",
+		TriggerPoints: []config.TriggerPoint{
+			{Name: "prep", Anchor: config.AnchorStart, OffsetMinutes: -10},
+			{Name: "join", Anchor: config.AnchorStart},
+		},
+		BackToBackGap: 5 * time.Minute,
+	}
+
+	plan := BuildPlan(events, opts, planNow())
+
+	var got []string
+	for _, trigger := range plan.Triggers {
+		got = append(got, trigger.Summary+"/"+trigger.Name)
+	}
+	want := "Planning/prep,Planning/join,Retro/join,Lunch talk/prep,Lunch talk/join"
+	if strings.Join(got, ",") != want {
+		t.Errorf("triggers = %s, want %s", strings.Join(got, ","), want)
+	}
+
+	found := false
+	for _, s := range plan.Skipped {
+		if s.Summary == "Retro" && s.Reason == `prep: still in "Planning"` {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected Retro prep to be skipped, got %+v", plan.Skipped)
+	}
+
+	opts.BackToBackGap = 0
+	if plan := BuildPlan(events, opts, planNow()); len(plan.Triggers) != 6 {
+		t.Errorf("expected all 6 triggers with the check disabled, got %d", len(plan.Triggers))
+	}
+}
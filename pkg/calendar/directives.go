@@ -0,0 +1,183 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bupd/timeotter/pkg/config"
+)
+
+// directivePrefix starts a directive line in an event description, and
+// with a dot, the private extended property keys of an event.
+const directivePrefix = "timeotter"
+
+// DirectiveOptions controls per-event overrides written into events.
+type DirectiveOptions struct {
+	Enabled bool
+	// Commands maps the names a directive may select with cmd= to the
+	// commands they run. Directives can never run anything else.
+	Commands map[string]string
+}
+
+// Directive is a per-event override, read from "timeotter:" lines in the
+// description (e.g. "timeotter: before=15 cmd=standup") and from private
+// extended properties named "timeotter.before", "timeotter.cmd" and
+// "timeotter.off". Extended properties win over the description.
+type Directive struct {
+	// BeforeMinutes replaces the event's trigger points with a single one
+	// this long before the start.
+	BeforeMinutes *int
+	// Cmd names one of the configured commands.
+	Cmd string
+	// Off disables every trigger of the event.
+	Off bool
+}
+
+// IsZero reports whether the directive overrides nothing.
+func (d Directive) IsZero() bool {
+	return d.BeforeMinutes == nil && d.Cmd == "" && !d.Off
+}
+
+func (d Directive) String() string {
+	var parts []string
+	if d.BeforeMinutes != nil {
+		parts = append(parts, fmt.Sprintf("before=%d", *d.BeforeMinutes))
+	}
+	if d.Cmd != "" {
+		parts = append(parts, "cmd="+d.Cmd)
+	}
+	if d.Off {
+		parts = append(parts, "off")
+	}
+	return strings.Join(parts, " ")
+}
+
+var (
+	htmlBreak = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</li>`)
+	htmlTag   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// ParseDirective reads the directive of item. Malformed entries are
+// reported in the error and left out of the returned directive.
+func ParseDirective(item Event) (Directive, error) {
+	var d Directive
+	var errs []error
+
+	// Calendar clients store descriptions as HTML.
+	text := html.UnescapeString(htmlTag.ReplaceAllString(htmlBreak.ReplaceAllString(item.Description, "\n"), ""))
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) <= len(directivePrefix) || !strings.EqualFold(line[:len(directivePrefix)+1], directivePrefix+":") {
+			continue
+		}
+		for _, field := range strings.Fields(line[len(directivePrefix)+1:]) {
+			key, value, _ := strings.Cut(field, "=")
+			if err := d.set(key, value); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(item.Properties))
+	for key := range item.Properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name, ok := strings.CutPrefix(key, directivePrefix+".")
+		if !ok {
+			continue
+		}
+		if err := d.set(name, item.Properties[key]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return d, errors.Join(errs...)
+}
+
+func (d *Directive) set(key, value string) error {
+	switch strings.ToLower(key) {
+	case "before":
+		minutes, err := strconv.Atoi(value)
+		if err != nil || minutes < 0 {
+			return fmt.Errorf("before=%s is not a number of minutes", value)
+		}
+		d.BeforeMinutes = &minutes
+	case "cmd":
+		if value == "" {
+			return fmt.Errorf("cmd needs a command name")
+		}
+		d.Cmd = strings.ToLower(value)
+	case "off":
+		if value == "" {
+			d.Off = true
+			return nil
+		}
+		off, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("off=%s is not true or false", value)
+		}
+		d.Off = off
+	default:
+		return fmt.Errorf("unknown directive %q", key)
+	}
+	return nil
+}
+
+// directive returns the directive of item, with cmd= resolved against the
+// configured commands, and a note describing anything that was ignored.
+func (opts Options) directive(item Event) (d Directive, cmd string, note string) {
+	if !opts.Directives.Enabled {
+		return Directive{}, "", ""
+	}
+	d, err := ParseDirective(item)
+	var problems []string
+	if err != nil {
+		problems = append(problems, strings.ReplaceAll(err.Error(), "\n", "; "))
+	}
+	if d.Cmd != "" {
+		var ok bool
+		if cmd, ok = opts.Directives.Commands[d.Cmd]; !ok {
+			problems = append(problems, fmt.Sprintf("cmd=%s is not a configured command", d.Cmd))
+		}
+	}
+	if len(problems) > 0 {
+		note = "ignored directive: " + strings.Join(problems, "; ")
+	}
+	return d, cmd, note
+}
+
+// applyDirective overrides the lead trigger points of item with its
+// directive: BeforeMinutes replaces them with a single point and cmd, when
+// set, replaces their command.
+func applyDirective(item Event, points []config.TriggerPoint, d Directive, cmd string) []config.TriggerPoint {
+	if d.BeforeMinutes != nil {
+		if cmd == "" && len(points) > 0 {
+			cmd = points[0].Cmd
+		}
+		name := "start"
+		if item.Type == EventTypeTask {
+			name = taskTriggerName
+		}
+		return []config.TriggerPoint{{
+			Name:          name,
+			Anchor:        config.AnchorStart,
+			OffsetMinutes: -*d.BeforeMinutes,
+			Cmd:           cmd,
+		}}
+	}
+	if cmd == "" {
+		return points
+	}
+	overridden := make([]config.TriggerPoint, len(points))
+	for i, point := range points {
+		point.Cmd = cmd
+		overridden[i] = point
+	}
+	return overridden
+}
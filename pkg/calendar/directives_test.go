@@ -0,0 +1,114 @@
+package calendar
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseDirective(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		properties  map[string]string
+		want        string
+		wantErr     string
+	}{
+		{name: "none", description: "Agenda: review the roadmap"},
+		{name: "plain text", description: "Agenda\ntimeotter: before=15 cmd=Standup\nNotes", want: "before=15 cmd=standup"},
+		{name: "html", description: "<p>Agenda</p><p>TimeOtter:&nbsp;off</p>", want: "off"},
+		{name: "html breaks", description: "Agenda<br>timeotter: before=0<br/>bye", want: "before=0"},
+		{name: "off false", description: "timeotter: off=false"},
+		{name: "not at line start", description: "see timeotter: off"},
+		{
+			name:        "properties win",
+			description: "timeotter: before=15 cmd=standup",
+			properties:  map[string]string{"timeotter.before": "3", "timeotter.off": "true", "other": "x"},
+			want:        "before=3 cmd=standup off",
+		},
+		{name: "bad minutes", description: "timeotter: before=soon cmd=standup", want: "cmd=standup", wantErr: "before=soon is not a number of minutes"},
+		{name: "negative minutes", description: "timeotter: before=-5", wantErr: "before=-5"},
+		{name: "unknown key", description: "timeotter: run=rm", wantErr: `unknown directive "run"`},
+		{name: "empty cmd", description: "timeotter: cmd=", wantErr: "cmd needs a command name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ParseDirective(Event{Description: tt.description, Properties: tt.properties})
+			if got := d.String(); got != tt.want {
+				t.Errorf("got directive %q, want %q", got, tt.want)
+			}
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("got error %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func directiveOptions() Options {
+	return Options{
+		CmdToExec:            "notify",
+		TriggerBeforeMinutes: 5,
+		Directives: DirectiveOptions{
+			Enabled:  true,
+			Commands: map[string]string{"standup": "start-timer", "loud": "mpv alarm.mp3"},
+		},
+	}
+}
+
+func TestBuildPlan_Directives(t *testing.T) {
+	lead := timedEvent(t, "a", "Lead time", "2025-03-15T10:00:00Z", "2025-03-15T11:00:00Z")
+	lead.Description = "timeotter: before=15"
+	named := timedEvent(t, "b", "Standup", "2025-03-15T12:00:00Z", "2025-03-15T12:15:00Z")
+	named.Properties = map[string]string{"timeotter.cmd": "standup"}
+	off := timedEvent(t, "c", "Lunch", "2025-03-15T13:00:00Z", "2025-03-15T14:00:00Z")
+	off.Description = "timeotter: off"
+	injected := timedEvent(t, "d", "Sync", "2025-03-15T15:00:00Z", "2025-03-15T16:00:00Z")
+	injected.Description = "timeotter: cmd=rm before=1"
+	task := Event{ID: "t", Summary: "Report", Type: EventTypeTask, Description: "timeotter: before=60 cmd=loud",
+		Start: mustParseEventTime(t, "2025-03-15T18:00:00Z"), End: mustParseEventTime(t, "2025-03-15T18:00:00Z")}
+
+	plan := BuildPlan([]Event{lead, named, off, injected, task}, directiveOptions(), planNow())
+
+	var got []string
+	for _, trigger := range plan.Triggers {
+		got = append(got, strings.TrimSpace(trigger.At.Format("15:04")+" "+trigger.Name+" "+trigger.Cmd+" "+trigger.Note))
+	}
+	want := []string{
+		"09:45 start notify",
+		"11:55 start start-timer",
+		"14:59 start notify ignored directive: cmd=rm is not a configured command",
+		"17:00 due mpv alarm.mp3",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if len(plan.Skipped) != 1 || plan.Skipped[0].EventID != "c" || !strings.Contains(plan.Skipped[0].Reason, "turned off") {
+		t.Errorf("unexpected skipped events %+v", plan.Skipped)
+	}
+}
+
+func TestBuildPlan_DirectivesDisabled(t *testing.T) {
+	item := timedEvent(t, "a", "Lunch", "2025-03-15T13:00:00Z", "2025-03-15T14:00:00Z")
+	item.Description = "timeotter: off"
+	opts := directiveOptions()
+	opts.Directives.Enabled = false
+
+	plan := BuildPlan([]Event{item}, opts, planNow())
+	if len(plan.Triggers) != 1 {
+		t.Errorf("expected the directive to be ignored, got %+v", plan)
+	}
+}
+
+func TestExplainEvent_Directive(t *testing.T) {
+	item := timedEvent(t, "a", "Standup", "2025-03-15T12:00:00Z", "2025-03-15T12:15:00Z")
+	item.Description = "timeotter: cmd=standup before=2 volume=11"
+	var buf bytes.Buffer
+	ExplainEvent(&buf, item, directiveOptions())
+	want := `Directive:  before=2 cmd=standup ignored directive: unknown directive "volume"`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("unexpected explanation:\n%s", buf.String())
+	}
+}
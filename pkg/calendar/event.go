@@ -0,0 +1,113 @@
+package calendar
+
+import (
+	"context"
+	"time"
+)
+
+// Event is a calendar event in a provider-neutral form. Every Source
+// converts its own representation into it, so planning never depends on a
+// provider's API types.
+type Event struct {
+	ID string `json:"id"`
+	// SeriesID identifies the recurring series the event is an instance of.
+	SeriesID string `json:"seriesId,omitempty"`
+	// Start and End keep the event's own UTC offset. All-day events start
+	// and end at midnight in their time zone and End is exclusive.
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	AllDay bool      `json:"allDay,omitempty"`
+	// TimeZone is the IANA zone the event was scheduled in. Recurring
+	// series are expanded in it so occurrences keep their wall-clock time
+	// across DST changes.
+	TimeZone string `json:"timeZone,omitempty"`
+	// Calendar identifies the calendar, or task list, the event was read
+	// from.
+	Calendar string `json:"calendar,omitempty"`
+
+	// Recurrence holds the RRULE, RDATE and EXDATE lines of a series that
+	// the source did not expand itself; see ExpandRecurring.
+	Recurrence []string `json:"recurrence,omitempty"`
+	// RecurrenceID is the original start of an instance that overrides one
+	// occurrence of the series identified by SeriesID.
+	RecurrenceID time.Time `json:"recurrenceId,omitzero"`
+
+	Summary     string `json:"summary"`
+	Description string `json:"description,omitempty"`
+	Location    string `json:"location,omitempty"`
+	// Status is "confirmed", "tentative" or "cancelled".
+	Status string `json:"status,omitempty"`
+	// Type is the provider's event type, e.g. "outOfOffice". Empty means an
+	// ordinary event.
+	Type    string `json:"type,omitempty"`
+	ColorID string `json:"colorId,omitempty"`
+	// Free marks events that do not block time.
+	Free bool `json:"free,omitempty"`
+	// Properties are the provider's private key-value pairs of the event,
+	// e.g. Google Calendar's extendedProperties.private.
+	Properties map[string]string `json:"properties,omitempty"`
+
+	Organizer Person     `json:"organizer,omitzero"`
+	Attendees []Attendee `json:"attendees,omitempty"`
+	// Links lists the conference entry points of the event, in the
+	// provider's order of preference.
+	Links []Link `json:"links,omitempty"`
+	// Reminders are the event's effective reminders, with any calendar
+	// defaults already applied.
+	Reminders []Reminder `json:"reminders,omitempty"`
+}
+
+// Person identifies an organizer by email and display name.
+type Person struct {
+	Email string `json:"email,omitempty"`
+	Name  string `json:"name,omitempty"`
+}
+
+// Attendee is a guest of an event. Self marks the authenticated user.
+type Attendee struct {
+	Email          string `json:"email"`
+	Name           string `json:"name,omitempty"`
+	Self           bool   `json:"self,omitempty"`
+	ResponseStatus string `json:"responseStatus,omitempty"`
+}
+
+// Link kinds, following the conference entry point types of the Calendar API.
+const (
+	LinkVideo = "video"
+	LinkPhone = "phone"
+	LinkMore  = "more"
+)
+
+// Link is a way of joining an event, e.g. a video meeting URL.
+type Link struct {
+	Kind string `json:"kind"`
+	URL  string `json:"url"`
+}
+
+// Reminder is a notification configured on an event.
+type Reminder struct {
+	Method  string `json:"method"`
+	Minutes int    `json:"minutes"`
+}
+
+// Query selects the events a Source returns.
+type Query struct {
+	// CalendarID overrides the source's own calendar, e.g. to read a
+	// holiday calendar.
+	CalendarID string
+	// Events ending after From are returned. A zero To leaves the range
+	// open-ended.
+	From time.Time
+	To   time.Time
+	// MaxResults caps the number of events; zero uses the provider default.
+	MaxResults int64
+}
+
+// Source supplies events from a calendar provider.
+type Source interface {
+	// Events lists the events matching q, with recurring events expanded
+	// into instances and ordered by start time.
+	Events(ctx context.Context, q Query) ([]Event, error)
+	// Event fetches a single event by ID.
+	Event(ctx context.Context, id string) (Event, error)
+}
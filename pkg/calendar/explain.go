@@ -0,0 +1,69 @@
+package calendar
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ExplainEvent writes a human readable account of how opts treat item: the
+// attendance check, every rule that was consulted, the final decision and
+// the routes and directive that pick its command.
+func ExplainEvent(w io.Writer, item Event, opts Options) {
+	start := item.Start.Format(time.RFC3339)
+	if item.AllDay {
+		start = item.Start.Format("2006-01-02")
+	}
+	fmt.Fprintf(w, "Event:      %s (%s)\n", item.Summary, item.ID)
+	fmt.Fprintf(w, "Start:      %s\n", start)
+	fmt.Fprintf(w, "Event type: %s\n", eventType(item))
+
+	if until, ok := opts.Acked[item.ID]; ok {
+		fmt.Fprintf(w, "Acked:      until %s\n", until.Format(time.RFC3339))
+		fmt.Fprintln(w, "Decision:   no trigger")
+		return
+	}
+
+	if ok, reason := opts.Attendance.Allows(item); !ok {
+		fmt.Fprintf(w, "Attendance: skipped, %s\n", reason)
+		fmt.Fprintln(w, "Decision:   no trigger")
+		return
+	}
+	fmt.Fprintf(w, "Attendance: ok (%s)\n", SelfResponseStatus(item))
+
+	decision := opts.Rules.Evaluate(item)
+	if len(decision.Trace) > 0 {
+		fmt.Fprintln(w, "Rules:")
+	}
+	for i, trace := range decision.Trace {
+		action := "include"
+		if !trace.Include {
+			action = "exclude"
+		}
+		if trace.Matched {
+			fmt.Fprintf(w, "  %d. %s (%s): matched\n", i+1, trace.Name, action)
+		} else {
+			fmt.Fprintf(w, "  %d. %s (%s): %s\n", i+1, trace.Name, action, trace.Mismatch)
+		}
+	}
+
+	verdict := "trigger"
+	if !decision.Include {
+		verdict = "no trigger"
+	}
+	if decision.Rule == "" {
+		fmt.Fprintf(w, "Decision:   %s (no rule matched, default action)\n", verdict)
+	} else {
+		fmt.Fprintf(w, "Decision:   %s (rule %q)\n", verdict, decision.Rule)
+	}
+	if !decision.Include {
+		return
+	}
+	if names := opts.Routes.Match(item); len(names) > 0 {
+		fmt.Fprintf(w, "Routes:     %s\n", strings.Join(names, ", "))
+	}
+	if directive, _, note := opts.directive(item); !directive.IsZero() || note != "" {
+		fmt.Fprintf(w, "Directive:  %s\n", strings.TrimSpace(directive.String()+" "+note))
+	}
+}
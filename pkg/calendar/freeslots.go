@@ -0,0 +1,175 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/config"
+)
+
+// freeSlotTriggerName is the trigger point name used for free gaps.
+const freeSlotTriggerName = "free-slot"
+
+// Period is a time range, End exclusive.
+type Period struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Duration returns the length of the period.
+func (p Period) Duration() time.Duration {
+	return p.End.Sub(p.Start)
+}
+
+// BusySource reports when calendars are busy without listing their events,
+// like the Google Calendar FreeBusy API.
+type BusySource interface {
+	Busy(ctx context.Context, calendarIDs []string, from, to time.Time) ([]Period, error)
+}
+
+// FreeSlotOptions controls triggers that fire when a free gap opens
+// during working hours.
+type FreeSlotOptions struct {
+	// MinGap is the shortest gap that produces a trigger; zero disables
+	// free-slot triggers.
+	MinGap time.Duration
+	Cmd    string
+	// Busy holds additional busy periods, e.g. from other calendars.
+	Busy []Period
+}
+
+// FreeGaps returns the free periods of at least minGap between from and to
+// that lie inside the working windows, or anywhere when there are none.
+// Windows are evaluated in from's location.
+func FreeGaps(busy []Period, windows []config.Window, from, to time.Time, minGap time.Duration) []Period {
+	busy = append([]Period(nil), busy...)
+	sort.Slice(busy, func(i, j int) bool { return busy[i].Start.Before(busy[j].Start) })
+
+	var gaps []Period
+	for _, work := range workingPeriods(windows, from, to) {
+		cursor := work.Start
+		for _, b := range busy {
+			if !b.End.After(cursor) || !b.Start.Before(work.End) {
+				continue
+			}
+			if b.Start.After(cursor) {
+				gaps = append(gaps, Period{Start: cursor, End: b.Start})
+			}
+			cursor = b.End
+		}
+		if cursor.Before(work.End) {
+			gaps = append(gaps, Period{Start: cursor, End: work.End})
+		}
+	}
+
+	long := gaps[:0]
+	for _, gap := range gaps {
+		if gap.Duration() >= minGap {
+			long = append(long, gap)
+		}
+	}
+	return long
+}
+
+// workingPeriods turns daily windows into concrete, merged periods between
+// from and to.
+func workingPeriods(windows []config.Window, from, to time.Time) []Period {
+	if len(windows) == 0 {
+		return []Period{{Start: from, End: to}}
+	}
+
+	var periods []Period
+	day := atClock(from, 0, 0).AddDate(0, 0, -1)
+	for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+		for _, w := range windows {
+			if !onDay(w.Days, day.Weekday()) {
+				continue
+			}
+			startH, startM, err := config.ParseClock(w.Start)
+			if err != nil {
+				continue
+			}
+			endH, endM, err := config.ParseClock(w.End)
+			if err != nil {
+				continue
+			}
+			start, end := atClock(day, startH, startM), atClock(day, endH, endM)
+			if !end.After(start) {
+				end = end.AddDate(0, 0, 1)
+			}
+			if start.Before(from) {
+				start = from
+			}
+			if end.After(to) {
+				end = to
+			}
+			if end.After(start) {
+				periods = append(periods, Period{Start: start, End: end})
+			}
+		}
+	}
+
+	sort.Slice(periods, func(i, j int) bool { return periods[i].Start.Before(periods[j].Start) })
+	var merged []Period
+	for _, p := range periods {
+		if n := len(merged); n > 0 && !p.Start.After(merged[n-1].End) {
+			if p.End.After(merged[n-1].End) {
+				merged[n-1].End = p.End
+			}
+			continue
+		}
+		merged = append(merged, p)
+	}
+	return merged
+}
+
+// blocksTime reports whether item makes the user busy.
+func blocksTime(item Event) bool {
+	return !item.AllDay && !item.Free && item.Status != "cancelled" &&
+		item.Type != EventTypeTask && SelfResponseStatus(item) != StatusDeclined
+}
+
+// scheduleFreeSlots adds a trigger at the start of every free gap that
+// opens after now, up to the end of the last known busy period. Gaps are
+// found in now's location, so that working hours and the cron entries
+// use the same clock whatever zone the busy periods came in.
+func (p *Plan) scheduleFreeSlots(events []Event, opts Options, now time.Time) {
+	loc := now.Location()
+	var busy []Period
+	for _, b := range opts.FreeSlots.Busy {
+		busy = append(busy, Period{Start: b.Start.In(loc), End: b.End.In(loc)})
+	}
+	for _, item := range events {
+		if blocksTime(item) {
+			busy = append(busy, Period{Start: item.Start.In(loc), End: item.End.In(loc)})
+		}
+	}
+	horizon := now
+	for _, b := range busy {
+		if b.End.After(horizon) {
+			horizon = b.End
+		}
+	}
+
+	// Gaps are free across every calendar, so they follow the hours of
+	// the main one.
+	windows := opts.Hours.hoursFor(opts.CalendarID).WorkingHours
+	for _, gap := range FreeGaps(busy, windows, now, horizon, opts.FreeSlots.MinGap) {
+		if !gap.Start.After(now) {
+			continue
+		}
+		minutes := int(gap.Duration() / time.Minute)
+		slot := Event{
+			Calendar: opts.CalendarID,
+			Summary:  fmt.Sprintf("Free slot (%d min)", minutes),
+			Start:    gap.Start,
+			End:      gap.End,
+		}
+		if trigger := p.schedule(slot, freeSlotTriggerName, gap.Start, opts.FreeSlots.Cmd, opts); trigger != nil {
+			trigger.GapMinutes = minutes
+			trigger.Events = nil
+		}
+	}
+}
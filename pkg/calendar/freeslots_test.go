@@ -0,0 +1,168 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/config"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+)
+
+func TestFreeGaps(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, 3, day, hour, minute, 0, 0, time.UTC)
+	}
+	busy := []Period{
+		{Start: at(17, 10, 0), End: at(17, 11, 0)},
+		{Start: at(17, 10, 30), End: at(17, 11, 30)},
+		{Start: at(17, 12, 0), End: at(17, 14, 0)},
+		{Start: at(18, 9, 0), End: at(18, 9, 30)},
+	}
+	weekdays := []config.Window{{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "17:00"}}
+
+	tests := []struct {
+		name    string
+		windows []config.Window
+		from    time.Time
+		to      time.Time
+		minGap  time.Duration
+		want    []string
+	}{
+		{
+			name: "working hours", windows: weekdays, from: at(17, 8, 0), to: at(18, 12, 0), minGap: 45 * time.Minute,
+			want: []string{"17 09:00-10:00", "17 14:00-17:00", "18 09:30-12:00"},
+		},
+		{
+			name: "short gaps are dropped", windows: weekdays, from: at(17, 8, 0), to: at(17, 17, 0), minGap: 90 * time.Minute,
+			want: []string{"17 14:00-17:00"},
+		},
+		{
+			name: "no working hours", from: at(17, 11, 0), to: at(18, 9, 0), minGap: 45 * time.Minute,
+			want: []string{"17 14:00-09:00"},
+		},
+		{
+			name: "weekend", windows: weekdays, from: at(15, 0, 0), to: at(16, 23, 0), minGap: time.Minute,
+		},
+		{
+			name: "window past midnight", windows: []config.Window{{Start: "22:00", End: "02:00"}},
+			from: at(17, 0, 0), to: at(18, 12, 0), minGap: time.Hour,
+			want: []string{"17 00:00-02:00", "17 22:00-02:00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, gap := range FreeGaps(busy, tt.windows, tt.from, tt.to, tt.minGap) {
+				got = append(got, gap.Start.Format("02 15:04")+"-"+gap.End.Format("15:04"))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildPlan_FreeSlots(t *testing.T) {
+	now := time.Date(2025, 3, 17, 8, 0, 0, 0, time.UTC)
+	declined := timedEvent(t, "d", "Optional", "2025-03-17T13:00:00Z", "2025-03-17T14:00:00Z")
+	declined.Attendees = []Attendee{{Email: "me@example.com", Self: true, ResponseStatus: StatusDeclined}}
+	events := []Event{
+		timedEvent(t, "a", "Standup", "2025-03-17T09:00:00Z", "2025-03-17T09:15:00Z"),
+		timedEvent(t, "b", "Review", "2025-03-17T10:00:00Z", "2025-03-17T11:00:00Z"),
+		declined,
+		timedEvent(t, "c", "Planning", "2025-03-17T15:00:00Z", "2025-03-17T16:00:00Z"),
+	}
+	opts := Options{
+		CmdToExec: "notify",
+		Hours: HoursPolicy{Default: config.Hours{
+			WorkingHours: []config.Window{{Start: "09:00", End: "17:00"}},
+		}},
+		FreeSlots: FreeSlotOptions{
+			MinGap: 45 * time.Minute,
+			Cmd:    "focus-timer",
+			Busy:   []Period{{Start: time.Date(2025, 3, 17, 11, 30, 0, 0, time.UTC), End: time.Date(2025, 3, 17, 12, 0, 0, 0, time.UTC)}},
+		},
+	}
+
+	plan := BuildPlan(events, opts, now)
+	var got []string
+	for _, trigger := range plan.Triggers {
+		if trigger.Name == "free-slot" {
+			got = append(got, trigger.At.Format("15:04")+" "+trigger.CronCommand())
+		}
+	}
+	want := []string{
+		"09:15 TIMEOTTER_GAP_MINUTES=45 focus-timer",
+		"12:00 TIMEOTTER_GAP_MINUTES=180 focus-timer",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestBuildPlan_FreeSlotsLocalZone(t *testing.T) {
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Skip("Asia/Kolkata not available")
+	}
+	now := time.Date(2025, 3, 17, 9, 0, 0, 0, kolkata)
+	opts := Options{
+		CmdToExec: "notify",
+		Hours: HoursPolicy{Default: config.Hours{
+			WorkingHours: []config.Window{{Start: "09:00", End: "18:00"}},
+		}},
+		FreeSlots: FreeSlotOptions{
+			MinGap: 45 * time.Minute,
+			Cmd:    "focus-timer",
+			// Busy 09:00-10:30 and 11:30-12:00 in Kolkata, as FreeBusy reports them in UTC.
+			Busy: []Period{
+				{Start: time.Date(2025, 3, 17, 3, 30, 0, 0, time.UTC), End: time.Date(2025, 3, 17, 5, 0, 0, 0, time.UTC)},
+				{Start: time.Date(2025, 3, 17, 6, 0, 0, 0, time.UTC), End: time.Date(2025, 3, 17, 6, 30, 0, 0, time.UTC)},
+			},
+		},
+	}
+
+	plan := BuildPlan(nil, opts, now)
+	if len(plan.Triggers) != 1 {
+		t.Fatalf("got triggers %+v, skipped %+v", plan.Triggers, plan.Skipped)
+	}
+	trigger := plan.Triggers[0]
+	if trigger.At.Location() != kolkata || trigger.At.Format("15:04") != "10:30" || CronExpression(trigger.At) != "30 10 17 3 1" {
+		t.Errorf("got a trigger at %s (%s)", trigger.At, CronExpression(trigger.At))
+	}
+}
+
+func TestGoogleSource_Busy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req calendar.FreeBusyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Items) != 2 {
+			t.Errorf("unexpected request %+v (%v)", req, err)
+		}
+		_ = json.NewEncoder(w).Encode(calendar.FreeBusyResponse{Calendars: map[string]calendar.FreeBusyCalendar{
+			"team@example.com":   {Busy: []*calendar.TimePeriod{{Start: "2025-03-17T10:00:00Z", End: "2025-03-17T11:00:00Z"}}},
+			"secret@example.com": {Errors: []*calendar.Error{{Domain: "global", Reason: "notFound"}}},
+		}})
+	}))
+	defer server.Close()
+
+	srv, err := calendar.NewService(context.Background(), option.WithEndpoint(server.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &GoogleSource{Service: srv}
+	from := time.Date(2025, 3, 17, 0, 0, 0, 0, time.UTC)
+	busy, err := src.Busy(context.Background(), []string{"team@example.com", "secret@example.com"}, from, from.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(busy) != 1 || busy[0].Duration() != time.Hour || busy[0].Start.Location() != time.Local {
+		t.Errorf("unexpected busy periods %+v", busy)
+	}
+}
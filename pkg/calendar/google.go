@@ -0,0 +1,265 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// GoogleSource reads events from a Google Calendar.
+type GoogleSource struct {
+	Service    *calendar.Service
+	CalendarID string
+	// ShowDeleted includes cancelled events.
+	ShowDeleted bool
+}
+
+// Events lists single events of the calendar, ordered by start time.
+// Events that cannot be converted are logged and left out.
+func (s *GoogleSource) Events(ctx context.Context, q Query) ([]Event, error) {
+	id := q.CalendarID
+	if id == "" {
+		id = s.CalendarID
+	}
+
+	call := s.Service.Events.List(id).Context(ctx).ShowDeleted(s.ShowDeleted).
+		SingleEvents(true).TimeMin(q.From.Format(time.RFC3339)).OrderBy("startTime")
+	if !q.To.IsZero() {
+		call = call.TimeMax(q.To.Format(time.RFC3339))
+	}
+	if q.MaxResults > 0 {
+		call = call.MaxResults(q.MaxResults)
+	}
+	list, err := call.Do()
+	if err != nil {
+		return nil, err
+	}
+	events := googleEvents(list)
+	for i := range events {
+		events[i].Calendar = id
+	}
+	return events, nil
+}
+
+// Event fetches a single event of the calendar. Unlike a list of events,
+// a single one comes without the calendar's time zone and default
+// reminders, so they are read from the calendar list entry.
+func (s *GoogleSource) Event(ctx context.Context, id string) (Event, error) {
+	item, err := s.Service.Events.Get(s.CalendarID, id).Context(ctx).Do()
+	if err != nil {
+		return Event{}, err
+	}
+	loc := time.Local
+	var defaults []*calendar.EventReminder
+	if entry, err := s.Service.CalendarList.Get(s.CalendarID).Context(ctx).Do(); err == nil {
+		loc = calendarLocation(entry.TimeZone)
+		defaults = entry.DefaultReminders
+	} else {
+		log.Printf("Unable to read the settings of %s, using no default reminders: %v", s.CalendarID, err)
+	}
+	event, err := FromGoogle(item, loc, defaults)
+	if err != nil {
+		return Event{}, err
+	}
+	event.Calendar = s.CalendarID
+	return event, nil
+}
+
+// Busy queries the FreeBusy API for the busy periods of calendarIDs.
+// Calendars the API reports errors for are logged and left out.
+func (s *GoogleSource) Busy(ctx context.Context, calendarIDs []string, from, to time.Time) ([]Period, error) {
+	req := &calendar.FreeBusyRequest{
+		TimeMin: from.Format(time.RFC3339),
+		TimeMax: to.Format(time.RFC3339),
+	}
+	for _, id := range calendarIDs {
+		req.Items = append(req.Items, &calendar.FreeBusyRequestItem{Id: id})
+	}
+	resp, err := s.Service.Freebusy.Query(req).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	var busy []Period
+	for _, id := range calendarIDs {
+		cal, ok := resp.Calendars[id]
+		if !ok {
+			continue
+		}
+		for _, e := range cal.Errors {
+			log.Printf("Unable to read free/busy of %s: %s", id, e.Reason)
+		}
+		for _, period := range cal.Busy {
+			start, err := ParseEventTime(period.Start)
+			if err != nil {
+				return nil, err
+			}
+			end, err := ParseEventTime(period.End)
+			if err != nil {
+				return nil, err
+			}
+			// FreeBusy answers in UTC; cron and working hours use local time.
+			busy = append(busy, Period{Start: start.In(time.Local), End: end.In(time.Local)})
+		}
+	}
+	return busy, nil
+}
+
+// googleEvents converts a page of Google Calendar events, resolving all-day
+// dates in the calendar's time zone.
+func googleEvents(list *calendar.Events) []Event {
+	loc := calendarLocation(list.TimeZone)
+	events := make([]Event, 0, len(list.Items))
+	for _, item := range list.Items {
+		event, err := FromGoogle(item, loc, list.DefaultReminders)
+		if err != nil {
+			log.Printf("Skipping %q: %v", item.Summary, err)
+			continue
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// FromGoogle converts a Google Calendar event. All-day dates are
+// interpreted in the event's own time zone, falling back to loc, and
+// events that use the calendar's default reminders get defaults.
+func FromGoogle(item *calendar.Event, loc *time.Location, defaults []*calendar.EventReminder) (Event, error) {
+	event := Event{
+		ID:          item.Id,
+		SeriesID:    item.RecurringEventId,
+		Summary:     item.Summary,
+		Description: item.Description,
+		Location:    item.Location,
+		Status:      item.Status,
+		Type:        item.EventType,
+		ColorID:     item.ColorId,
+		Free:        item.Transparency == "transparent",
+		Recurrence:  item.Recurrence,
+		Links:       googleLinks(item),
+		Reminders:   googleReminders(item, defaults),
+	}
+	if item.ExtendedProperties != nil {
+		event.Properties = item.ExtendedProperties.Private
+	}
+	if item.Organizer != nil {
+		event.Organizer = Person{Email: item.Organizer.Email, Name: item.Organizer.DisplayName}
+	}
+	for _, attendee := range item.Attendees {
+		if attendee == nil {
+			continue
+		}
+		event.Attendees = append(event.Attendees, Attendee{
+			Email:          attendee.Email,
+			Name:           attendee.DisplayName,
+			Self:           attendee.Self,
+			ResponseStatus: attendee.ResponseStatus,
+		})
+	}
+
+	if item.Start == nil {
+		return Event{}, fmt.Errorf("event has no start time")
+	}
+	event.TimeZone = item.Start.TimeZone
+	if original := item.OriginalStartTime; original != nil {
+		event.RecurrenceID = originalStart(original, loc)
+	}
+	if item.Start.DateTime == "" && item.Start.Date != "" {
+		return allDayFromGoogle(event, item, loc)
+	}
+
+	start, err := ParseEventTime(item.Start.DateTime)
+	if err != nil {
+		return Event{}, err
+	}
+	event.Start, event.End = start, start
+	if item.End != nil && item.End.DateTime != "" {
+		if event.End, err = ParseEventTime(item.End.DateTime); err != nil {
+			return Event{}, err
+		}
+	}
+	return event, nil
+}
+
+// originalStart parses the original start of a recurring event instance,
+// returning the zero time when it is malformed.
+func originalStart(original *calendar.EventDateTime, loc *time.Location) time.Time {
+	if original.DateTime != "" {
+		t, _ := ParseEventTime(original.DateTime)
+		return t
+	}
+	if tz, err := time.LoadLocation(original.TimeZone); err == nil && original.TimeZone != "" {
+		loc = tz
+	}
+	t, _ := time.ParseInLocation("2006-01-02", original.Date, loc)
+	return t
+}
+
+// allDayFromGoogle fills in the span of an all-day event. A missing or
+// empty end date makes it a single day.
+func allDayFromGoogle(event Event, item *calendar.Event, loc *time.Location) (Event, error) {
+	if item.Start.TimeZone != "" {
+		if eventLoc, err := time.LoadLocation(item.Start.TimeZone); err == nil {
+			loc = eventLoc
+		}
+	}
+
+	start, err := time.ParseInLocation("2006-01-02", item.Start.Date, loc)
+	if err != nil {
+		return Event{}, fmt.Errorf("parsing all-day start %q: %w", item.Start.Date, err)
+	}
+	end := start.AddDate(0, 0, 1)
+	if item.End != nil && item.End.Date != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", item.End.Date, loc)
+		if err != nil {
+			return Event{}, fmt.Errorf("parsing all-day end %q: %w", item.End.Date, err)
+		}
+		if parsed.After(start) {
+			end = parsed
+		}
+	}
+
+	event.Start, event.End, event.AllDay = start, end, true
+	return event, nil
+}
+
+// googleLinks collects the conference entry points of item, followed by
+// its Meet link when no entry point already carries it.
+func googleLinks(item *calendar.Event) []Link {
+	var links []Link
+	if item.ConferenceData != nil {
+		for _, entry := range item.ConferenceData.EntryPoints {
+			if entry != nil && entry.Uri != "" {
+				links = append(links, Link{Kind: entry.EntryPointType, URL: entry.Uri})
+			}
+		}
+	}
+	if item.HangoutLink != "" {
+		for _, link := range links {
+			if link.URL == item.HangoutLink {
+				return links
+			}
+		}
+		links = append(links, Link{Kind: LinkVideo, URL: item.HangoutLink})
+	}
+	return links
+}
+
+// googleReminders resolves the reminders that apply to item.
+func googleReminders(item *calendar.Event, defaults []*calendar.EventReminder) []Reminder {
+	source := defaults
+	if item.Reminders != nil && !item.Reminders.UseDefault {
+		source = item.Reminders.Overrides
+	}
+
+	var reminders []Reminder
+	for _, r := range source {
+		if r != nil {
+			reminders = append(reminders, Reminder{Method: r.Method, Minutes: int(r.Minutes)})
+		}
+	}
+	return reminders
+}
@@ -0,0 +1,286 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/testutil"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+)
+
+func TestFromGoogle_Timed(t *testing.T) {
+	item := testutil.MockCalendarEvent("Design review", "2025-03-15T10:00:00+05:30")
+	item.Id = "evt1"
+	item.RecurringEventId = "series1"
+	item.End = &calendar.EventDateTime{DateTime: "2025-03-15T11:00:00+05:30"}
+	item.Status = "confirmed"
+	item.Transparency = "transparent"
+	item.EventType = "focusTime"
+	item.ColorId = "11"
+	item.Organizer = &calendar.EventOrganizer{Email: "boss@example.com", DisplayName: "Boss"}
+	item.Attendees = []*calendar.EventAttendee{
+		{Email: "me@example.com", Self: true, ResponseStatus: StatusTentative},
+		nil,
+	}
+
+	event, err := FromGoogle(item, time.UTC, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if event.ID != "evt1" || event.SeriesID != "series1" || event.Status != "confirmed" ||
+		event.Type != "focusTime" || event.ColorID != "11" || !event.Free || event.AllDay {
+		t.Errorf("unexpected event %+v", event)
+	}
+	if event.Start.Format(time.RFC3339) != "2025-03-15T10:00:00+05:30" || event.End.Sub(event.Start) != time.Hour {
+		t.Errorf("span = %s - %s", event.Start, event.End)
+	}
+	if event.Organizer != (Person{Email: "boss@example.com", Name: "Boss"}) {
+		t.Errorf("organizer = %+v", event.Organizer)
+	}
+	if len(event.Attendees) != 1 || !event.Attendees[0].Self || event.Attendees[0].ResponseStatus != StatusTentative {
+		t.Errorf("attendees = %+v", event.Attendees)
+	}
+}
+
+func TestFromGoogle_AllDay(t *testing.T) {
+	kolkata := mustLoadLocation(t, "Asia/Kolkata")
+	mustLoadLocation(t, "America/New_York")
+
+	tests := []struct {
+		name       string
+		start, end string
+		timeZone   string
+		wantStart  string
+		wantEnd    string
+	}{
+		{"calendar time zone", "2025-03-15", "2025-03-17", "", "2025-03-15T00:00:00+05:30", "2025-03-17T00:00:00+05:30"},
+		{"missing end is a single day", "2025-03-15", "", "", "2025-03-15T00:00:00+05:30", "2025-03-16T00:00:00+05:30"},
+		{"event time zone wins", "2025-03-08", "2025-03-10", "America/New_York", "2025-03-08T00:00:00-05:00", "2025-03-10T00:00:00-04:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := testutil.MockCalendarEventAllDay("Holiday", tt.start)
+			item.Start.TimeZone = tt.timeZone
+			if tt.end != "" {
+				item.End = &calendar.EventDateTime{Date: tt.end}
+			}
+
+			event, err := FromGoogle(item, kolkata, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !event.AllDay {
+				t.Error("date-only event should be all-day")
+			}
+			if got := event.Start.Format(time.RFC3339); got != tt.wantStart {
+				t.Errorf("start = %s, want %s", got, tt.wantStart)
+			}
+			if got := event.End.Format(time.RFC3339); got != tt.wantEnd {
+				t.Errorf("end = %s, want %s", got, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestFromGoogle_InvalidTimes(t *testing.T) {
+	for name, item := range map[string]*calendar.Event{
+		"malformed date":     testutil.MockCalendarEventAllDay("Holiday", "15-03-2025"),
+		"malformed dateTime": testutil.MockCalendarEvent("Sync", "tomorrow"),
+		"no start":           {Summary: "Broken"},
+	} {
+		if _, err := FromGoogle(item, time.UTC, nil); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestFromGoogle_Links(t *testing.T) {
+	item := testutil.MockCalendarEvent("Sync", "2025-03-15T10:00:00+05:30")
+	item.HangoutLink = "https://meet.google.com/aaa-bbbb-ccc"
+	item.ConferenceData = &calendar.ConferenceData{EntryPoints: []*calendar.EntryPoint{
+		{EntryPointType: "phone", Uri: "tel:+1-555-0100"},
+		{EntryPointType: "video", Uri: "https://zoom.us/j/123456789?pwd=abc"},
+	}}
+
+	event, err := FromGoogle(item, time.UTC, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Link{
+		{Kind: LinkPhone, URL: "tel:+1-555-0100"},
+		{Kind: LinkVideo, URL: "https://zoom.us/j/123456789?pwd=abc"},
+		{Kind: LinkVideo, URL: "https://meet.google.com/aaa-bbbb-ccc"},
+	}
+	if len(event.Links) != len(want) {
+		t.Fatalf("links = %+v, want %+v", event.Links, want)
+	}
+	for i := range want {
+		if event.Links[i] != want[i] {
+			t.Errorf("link %d = %+v, want %+v", i, event.Links[i], want[i])
+		}
+	}
+	if got := JoinURL(event); got != "https://zoom.us/j/123456789?pwd=abc" {
+		t.Errorf("JoinURL() = %q, want the conference video entry point", got)
+	}
+
+	// A Meet link that is also an entry point is not listed twice.
+	item.ConferenceData.EntryPoints[1].Uri = item.HangoutLink
+	if event, _ = FromGoogle(item, time.UTC, nil); len(event.Links) != 2 {
+		t.Errorf("expected duplicate Meet link to be dropped, got %+v", event.Links)
+	}
+}
+
+func TestFromGoogle_Reminders(t *testing.T) {
+	defaults := []*calendar.EventReminder{
+		{Method: "popup", Minutes: 10},
+		{Method: "email", Minutes: 60},
+	}
+
+	tests := []struct {
+		name      string
+		reminders *calendar.EventReminders
+		want      []Reminder
+	}{
+		{"no reminders field uses calendar defaults", nil, []Reminder{{"popup", 10}, {"email", 60}}},
+		{"useDefault uses calendar defaults", &calendar.EventReminders{UseDefault: true}, []Reminder{{"popup", 10}, {"email", 60}}},
+		{
+			"overrides replace defaults",
+			&calendar.EventReminders{Overrides: []*calendar.EventReminder{{Method: "popup", Minutes: 2}}},
+			[]Reminder{{"popup", 2}},
+		},
+		{"override with no reminders", &calendar.EventReminders{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := testutil.MockCalendarEvent("Sync", "2025-03-15T10:00:00+05:30")
+			item.Reminders = tt.reminders
+
+			event, err := FromGoogle(item, time.UTC, defaults)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(event.Reminders) != len(tt.want) {
+				t.Fatalf("reminders = %+v, want %+v", event.Reminders, tt.want)
+			}
+			for i := range tt.want {
+				if event.Reminders[i] != tt.want[i] {
+					t.Errorf("reminder %d = %+v, want %+v", i, event.Reminders[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGoogleEvents(t *testing.T) {
+	list := testutil.MockCalendarEvents(
+		testutil.MockCalendarEventAllDay("Holi", "2025-03-14"),
+		testutil.MockCalendarEvent("Broken", "not a time"),
+		testutil.MockCalendarEvent("Sync", "2025-03-15T10:00:00+05:30"),
+	)
+	list.TimeZone = "UTC"
+	list.DefaultReminders = []*calendar.EventReminder{{Method: "popup", Minutes: 10}}
+
+	events := googleEvents(list)
+	if len(events) != 2 || events[0].Summary != "Holi" || events[1].Summary != "Sync" {
+		t.Fatalf("expected the broken event to be dropped, got %+v", events)
+	}
+	if events[0].Start.Location() != time.UTC {
+		t.Errorf("all-day event should use the calendar time zone, got %s", events[0].Start.Location())
+	}
+	if len(events[1].Reminders) != 1 || events[1].Reminders[0].Minutes != 10 {
+		t.Errorf("expected calendar default reminders, got %+v", events[1].Reminders)
+	}
+}
+
+func TestFromGoogle_Recurrence(t *testing.T) {
+	item := testutil.MockCalendarEvent("Standup", "2025-03-17T09:00:00-04:00")
+	item.Start.TimeZone = "America/New_York"
+	item.Recurrence = []string{"RRULE:FREQ=DAILY;COUNT=5"}
+	item.OriginalStartTime = &calendar.EventDateTime{DateTime: "2025-03-18T09:00:00-04:00"}
+
+	event, err := FromGoogle(item, time.UTC, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if event.TimeZone != "America/New_York" || len(event.Recurrence) != 1 {
+		t.Errorf("recurrence not kept: %+v", event)
+	}
+	if event.RecurrenceID.Format(time.RFC3339) != "2025-03-18T09:00:00-04:00" {
+		t.Errorf("RecurrenceID = %s", event.RecurrenceID)
+	}
+}
+
+func TestFromGoogle_Properties(t *testing.T) {
+	item := testutil.MockCalendarEvent("Standup", "2025-03-17T09:00:00-04:00")
+	item.ExtendedProperties = &calendar.EventExtendedProperties{
+		Private: map[string]string{"timeotter.before": "15"},
+		Shared:  map[string]string{"timeotter.off": "true"},
+	}
+
+	event, err := FromGoogle(item, time.UTC, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(event.Properties) != 1 || event.Properties["timeotter.before"] != "15" {
+		t.Errorf("expected only the private properties, got %v", event.Properties)
+	}
+}
+
+func TestGoogleSource_Event(t *testing.T) {
+	listed := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "calendarList"):
+			if !listed {
+				http.Error(w, `{"error": {"code": 404, "message": "Not Found"}}`, http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(calendar.CalendarListEntry{
+				Id:               "work@example.com",
+				TimeZone:         "Asia/Kolkata",
+				DefaultReminders: []*calendar.EventReminder{{Method: "popup", Minutes: 10}},
+			})
+		case strings.HasSuffix(r.URL.Path, "/events/broken"):
+			_ = json.NewEncoder(w).Encode(calendar.Event{Id: "broken", Start: &calendar.EventDateTime{DateTime: "not a time"}})
+		default:
+			item := testutil.MockCalendarEvent("Standup", "2025-03-15T10:00:00+05:30")
+			item.Id = "evt1"
+			item.End = &calendar.EventDateTime{DateTime: "2025-03-15T10:15:00+05:30"}
+			item.Reminders = &calendar.EventReminders{UseDefault: true}
+			_ = json.NewEncoder(w).Encode(item)
+		}
+	}))
+	defer server.Close()
+
+	srv, err := calendar.NewService(context.Background(), option.WithEndpoint(server.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &GoogleSource{Service: srv, CalendarID: "work@example.com"}
+
+	event, err := src.Event(context.Background(), "evt1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if event.Calendar != "work@example.com" || len(event.Reminders) != 1 || event.Reminders[0] != (Reminder{Method: "popup", Minutes: 10}) {
+		t.Errorf("unexpected event %+v", event)
+	}
+
+	if event, err := src.Event(context.Background(), "broken"); err == nil || event.Calendar != "" {
+		t.Errorf("got %+v, %v, want an error and no event", event, err)
+	}
+
+	// Without the calendar's settings the event still loads.
+	listed = false
+	if event, err := src.Event(context.Background(), "evt1"); err != nil || len(event.Reminders) != 0 {
+		t.Errorf("got %+v, %v", event, err)
+	}
+}
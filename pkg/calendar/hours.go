@@ -0,0 +1,120 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/config"
+)
+
+// HoursPolicy decides whether triggers may fire at a given time. Default
+// applies to every calendar without an entry in PerCalendar.
+type HoursPolicy struct {
+	Default     config.Hours
+	PerCalendar []config.CalendarHours
+}
+
+// HoursDecision is the outcome of checking a trigger time against the
+// working and quiet hours.
+type HoursDecision struct {
+	// Allowed is true when the trigger may run its own command.
+	Allowed bool
+	// Drop is true when the trigger must not run at all. When neither
+	// Allowed nor Drop is set, QuietCmd runs instead.
+	Drop     bool
+	QuietCmd string
+	Reason   string
+}
+
+// Check evaluates t, in its own location, against the hours configured for
+// calendarID.
+func (p HoursPolicy) Check(calendarID string, t time.Time) HoursDecision {
+	hours := p.hoursFor(calendarID)
+
+	reason := ""
+	if len(hours.WorkingHours) > 0 && !inAnyWindow(hours.WorkingHours, t) {
+		reason = "outside working hours"
+	} else if inAnyWindow(hours.QuietHours, t) {
+		reason = "inside quiet hours"
+	}
+	if reason == "" {
+		return HoursDecision{Allowed: true}
+	}
+
+	if hours.OutsideAction == config.OutsideQuiet && hours.QuietCmd != "" {
+		return HoursDecision{QuietCmd: hours.QuietCmd, Reason: reason}
+	}
+	return HoursDecision{Drop: true, Reason: reason}
+}
+
+// hoursFor returns the hours configured for calendarID.
+func (p HoursPolicy) hoursFor(calendarID string) config.Hours {
+	for _, override := range p.PerCalendar {
+		if strings.EqualFold(override.Calendar, calendarID) {
+			return override.Hours
+		}
+	}
+	return p.Default
+}
+
+func inAnyWindow(windows []config.Window, t time.Time) bool {
+	for _, w := range windows {
+		if inWindow(w, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// inWindow reports whether t falls inside w. Windows whose end is before
+// their start wrap past midnight and belong to the day they start on;
+// equal start and end cover the whole day.
+func inWindow(w config.Window, t time.Time) bool {
+	startH, startM, err := config.ParseClock(w.Start)
+	if err != nil {
+		return false
+	}
+	endH, endM, err := config.ParseClock(w.End)
+	if err != nil {
+		return false
+	}
+	start := startH*60 + startM
+	end := endH*60 + endM
+	now := t.Hour()*60 + t.Minute()
+
+	switch {
+	case start == end:
+		return onDay(w.Days, t.Weekday())
+	case start < end:
+		return onDay(w.Days, t.Weekday()) && now >= start && now < end
+	default:
+		if now >= start {
+			return onDay(w.Days, t.Weekday())
+		}
+		if now < end {
+			return onDay(w.Days, t.AddDate(0, 0, -1).Weekday())
+		}
+		return false
+	}
+}
+
+func onDay(days []string, weekday time.Weekday) bool {
+	if len(days) == 0 {
+		return true
+	}
+	for _, day := range days {
+		if d, err := config.ParseWeekday(day); err == nil && d == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// describe renders the decision for plan output.
+func (d HoursDecision) describe(at time.Time) string {
+	if d.Drop {
+		return fmt.Sprintf("%s %s, dropped", at.Format("15:04"), d.Reason)
+	}
+	return fmt.Sprintf("%s, quiet command", d.Reason)
+}
@@ -0,0 +1,177 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/config"
+)
+
+func TestInWindow(t *testing.T) {
+	weekdays := []string{"mon", "tue", "wed", "thu", "fri"}
+	// 2025-03-17 is a Monday.
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, 3, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name   string
+		window config.Window
+		t      time.Time
+		want   bool
+	}{
+		{"inside working day", config.Window{Days: weekdays, Start: "09:00", End: "18:00"}, at(17, 10, 0), true},
+		{"start is inclusive", config.Window{Days: weekdays, Start: "09:00", End: "18:00"}, at(17, 9, 0), true},
+		{"end is exclusive", config.Window{Days: weekdays, Start: "09:00", End: "18:00"}, at(17, 18, 0), false},
+		{"weekend excluded", config.Window{Days: weekdays, Start: "09:00", End: "18:00"}, at(16, 10, 0), false},
+		{"no days means every day", config.Window{Start: "09:00", End: "18:00"}, at(16, 10, 0), true},
+		{"wrapping window before midnight", config.Window{Start: "22:00", End: "07:00"}, at(17, 23, 30), true},
+		{"wrapping window after midnight", config.Window{Start: "22:00", End: "07:00"}, at(18, 6, 0), true},
+		{"wrapping window outside", config.Window{Start: "22:00", End: "07:00"}, at(18, 12, 0), false},
+		{
+			"wrapping window belongs to start day",
+			config.Window{Days: []string{"friday"}, Start: "22:00", End: "07:00"},
+			at(22, 2, 0), // Saturday 02:00, window opened Friday night
+			true,
+		},
+		{
+			"wrapping window on wrong start day",
+			config.Window{Days: []string{"friday"}, Start: "22:00", End: "07:00"},
+			at(18, 2, 0), // Tuesday 02:00
+			false,
+		},
+		{"equal start and end is all day", config.Window{Days: []string{"sun"}, Start: "00:00", End: "00:00"}, at(16, 15, 0), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inWindow(tt.window, tt.t); got != tt.want {
+				t.Errorf("inWindow() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHoursPolicy_Check(t *testing.T) {
+	policy := HoursPolicy{
+		Default: config.Hours{
+			WorkingHours:  []config.Window{{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "08:00", End: "19:00"}},
+			QuietHours:    []config.Window{{Start: "12:30", End: "13:30"}},
+			OutsideAction: config.OutsideDrop,
+		},
+		PerCalendar: []config.CalendarHours{{
+			Calendar: "oncall@example.com",
+			Hours: config.Hours{
+				QuietHours:    []config.Window{{Start: "23:00", End: "06:00"}},
+				OutsideAction: config.OutsideQuiet,
+				QuietCmd:      "notify-send -u low meeting",
+			},
+		}},
+	}
+	monday := func(hour, minute int) time.Time {
+		return time.Date(2025, 3, 17, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		calendar string
+		t        time.Time
+		allowed  bool
+		drop     bool
+		reason   string
+	}{
+		{"within working hours", "primary", monday(10, 0), true, false, ""},
+		{"early morning dropped", "primary", monday(6, 0), false, true, "outside working hours"},
+		{"lunch quiet hours dropped", "primary", monday(12, 45), false, true, "inside quiet hours"},
+		{"override has no working hours", "OnCall@example.com", monday(6, 0), true, false, ""},
+		{"override redirects to quiet command", "oncall@example.com", monday(23, 30), false, false, "inside quiet hours"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := policy.Check(tt.calendar, tt.t)
+			if d.Allowed != tt.allowed || d.Drop != tt.drop || d.Reason != tt.reason {
+				t.Errorf("Check() = %+v, want allowed %v drop %v reason %q", d, tt.allowed, tt.drop, tt.reason)
+			}
+			if !d.Allowed && !d.Drop && d.QuietCmd == "" {
+				t.Error("quiet decision without a command")
+			}
+		})
+	}
+}
+
+func TestBuildPlan_Hours(t *testing.T) {
+	events := []Event{
+		timedEvent(t, "a", "Overseas sync", "2025-03-17T06:00:00+00:00", "2025-03-17T07:00:00+00:00"),
+		timedEvent(t, "b", "Planning", "2025-03-17T10:00:00+00:00", "2025-03-17T11:00:00+00:00"),
+	}
+	working := []config.Window{{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "18:00"}}
+
+	dropPlan := BuildPlan(events, Options{
+		CmdToExec: "lamp on",
+		Hours:     HoursPolicy{Default: config.Hours{WorkingHours: working, OutsideAction: config.OutsideDrop}},
+	}, planNow())
+	if len(dropPlan.Triggers) != 1 || dropPlan.Triggers[0].Summary != "Planning" {
+		t.Errorf("expected only Planning to be scheduled, got %+v", dropPlan.Triggers)
+	}
+	if len(dropPlan.Skipped) != 1 || !strings.Contains(dropPlan.Skipped[0].Reason, "start: 06:00 outside working hours, dropped") {
+		t.Errorf("expected dropped trigger in skipped list, got %+v", dropPlan.Skipped)
+	}
+
+	quietPlan := BuildPlan(events, Options{
+		CmdToExec: "lamp on",
+		Hours: HoursPolicy{Default: config.Hours{
+			WorkingHours:  working,
+			OutsideAction: config.OutsideQuiet,
+			QuietCmd:      "notify-send quiet",
+		}},
+	}, planNow())
+	if len(quietPlan.Triggers) != 2 {
+		t.Fatalf("expected 2 triggers, got %+v", quietPlan.Triggers)
+	}
+	first := quietPlan.Triggers[0]
+	if first.Cmd != "notify-send quiet" || first.Note != "outside working hours, quiet command" {
+		t.Errorf("expected quiet redirect, got %+v", first)
+	}
+	if quietPlan.Triggers[1].Cmd != "lamp on" || quietPlan.Triggers[1].Note != "" {
+		t.Errorf("expected normal trigger, got %+v", quietPlan.Triggers[1])
+	}
+}
+
+func TestBuildPlan_HoursPerEventCalendar(t *testing.T) {
+	work := timedEvent(t, "a", "Planning", "2025-03-17T10:00:00+00:00", "2025-03-17T11:00:00+00:00")
+	work.Calendar = "work@example.com"
+	errand := timedEvent(t, "b", "Dentist", "2025-03-17T10:00:00+00:00", "2025-03-17T11:00:00+00:00")
+	errand.Calendar = "personal@example.com"
+	dinner := timedEvent(t, "c", "Dinner", "2025-03-17T19:00:00+00:00", "2025-03-17T20:00:00+00:00")
+	dinner.Calendar = "personal@example.com"
+	unknown := timedEvent(t, "d", "Review", "2025-03-17T19:30:00+00:00", "2025-03-17T20:00:00+00:00")
+
+	plan := BuildPlan([]Event{work, errand, dinner, unknown}, Options{
+		CmdToExec:  "lamp on",
+		CalendarID: "work@example.com",
+		Hours: HoursPolicy{
+			Default: config.Hours{WorkingHours: []config.Window{{Start: "09:00", End: "18:00"}}, OutsideAction: config.OutsideDrop},
+			PerCalendar: []config.CalendarHours{{
+				Calendar: "personal@example.com",
+				Hours:    config.Hours{WorkingHours: []config.Window{{Start: "18:00", End: "23:00"}}, OutsideAction: config.OutsideDrop},
+			}},
+		},
+	}, planNow())
+
+	var got []string
+	for _, trigger := range plan.Triggers {
+		got = append(got, trigger.Summary)
+	}
+	if strings.Join(got, ",") != "Planning,Dinner" {
+		t.Errorf("got triggers %v, want Planning and Dinner", got)
+	}
+	var skipped []string
+	for _, s := range plan.Skipped {
+		skipped = append(skipped, s.Summary)
+	}
+	if strings.Join(skipped, ",") != "Dentist,Review" {
+		t.Errorf("got skipped %v, want Dentist and Review", skipped)
+	}
+}
@@ -0,0 +1,75 @@
+package calendar
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// joinURLPattern matches video meeting links of common providers inside
+// free text such as the location or description. Links end at characters
+// that are special to the shell, as they are passed on to commands.
+var joinURLPattern = regexp.MustCompile(`https://(?:` +
+	`meet\.google\.com/[a-z0-9-]+` +
+	`|(?:[\w-]+\.)?zoom\.us/(?:j|my|w)/` + urlTail +
+	`|teams\.microsoft\.com/l/meetup-join/` + urlTail +
+	`|teams\.live\.com/meet/` + urlTail +
+	`|[\w-]+\.webex\.com/` + urlTail +
+	`|whereby\.com/` + urlTail +
+	`|meet\.jit\.si/` + urlTail +
+	`)`)
+
+// urlTail matches the rest of a link up to whitespace, quotes, brackets
+// or shell expansions.
+const urlTail = "[^\\s\"'<>)`$\\\\]+"
+
+// JoinURL returns the best video join link of item: its first video link,
+// then the first known meeting link in the location or description. It
+// returns an empty string when the event has none.
+func JoinURL(item Event) string {
+	for _, link := range item.Links {
+		if link.Kind == LinkVideo {
+			return link.URL
+		}
+	}
+	for _, text := range []string{item.Location, item.Description} {
+		if url := joinURLPattern.FindString(text); url != "" {
+			return strings.TrimRight(url, ".,;")
+		}
+	}
+	return ""
+}
+
+// SelectMeeting picks the meeting to join among the events that pass the
+// attendance filter and rules and have a join link. Without next it returns
+// the meeting in progress at now, falling back to the next one; with next
+// it returns the first meeting that has not started yet.
+func SelectMeeting(events []Event, opts Options, now time.Time, next bool) (Event, string, error) {
+	var upcoming *Event
+
+	for i, item := range events {
+		if item.AllDay {
+			continue
+		}
+		if ok, _ := shouldTrigger(item, opts); !ok {
+			continue
+		}
+		url := JoinURL(item)
+		if url == "" {
+			continue
+		}
+
+		if !next && !item.Start.After(now) && item.End.After(now) {
+			return item, url, nil
+		}
+		if item.Start.After(now) && (upcoming == nil || item.Start.Before(upcoming.Start)) {
+			upcoming = &events[i]
+		}
+	}
+
+	if upcoming == nil {
+		return Event{}, "", fmt.Errorf("no meeting with a join link found")
+	}
+	return *upcoming, JoinURL(*upcoming), nil
+}
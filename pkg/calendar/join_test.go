@@ -0,0 +1,108 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJoinURL(t *testing.T) {
+	tests := []struct {
+		name  string
+		event Event
+		want  string
+	}{
+		{
+			name: "first video link",
+			event: Event{
+				Location: "https://meet.jit.si/standup",
+				Links: []Link{
+					{Kind: LinkPhone, URL: "tel:+1-555-0100"},
+					{Kind: LinkVideo, URL: "https://zoom.us/j/123456789?pwd=abc"},
+					{Kind: LinkVideo, URL: "https://meet.google.com/aaa-bbbb-ccc"},
+				},
+			},
+			want: "https://zoom.us/j/123456789?pwd=abc",
+		},
+		{
+			name:  "zoom in location",
+			event: Event{Location: "Room 4 / https://acme.zoom.us/j/987654321?pwd=xyz."},
+			want:  "https://acme.zoom.us/j/987654321?pwd=xyz",
+		},
+		{
+			name: "teams in description",
+			event: Event{Description: "Join on your computer:\n" +
+				"<a href=\"https://teams.microsoft.com/l/meetup-join/19%3ameeting_abc%40thread.v2/0\">Click here</a>"},
+			want: "https://teams.microsoft.com/l/meetup-join/19%3ameeting_abc%40thread.v2/0",
+		},
+		{
+			name:  "location wins over description",
+			event: Event{Location: "https://meet.jit.si/standup", Description: "https://whereby.com/team"},
+			want:  "https://meet.jit.si/standup",
+		},
+		{
+			name:  "shell expansions end the link",
+			event: Event{Location: "https://zoom.us/j/1`touch${IFS}/tmp/pwned`", Description: "https://whereby.com/team$(id)"},
+			want:  "https://zoom.us/j/1",
+		},
+		{
+			name:  "unrelated links ignored",
+			event: Event{Location: "Building B", Description: "Agenda: https://docs.example.com/agenda"},
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := JoinURL(tt.event); got != tt.want {
+				t.Errorf("JoinURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectMeeting(t *testing.T) {
+	current := timedEvent(t, "cur", "Standup", "2025-03-17T10:00:00+00:00", "2025-03-17T10:30:00+00:00")
+	current.Links = []Link{{Kind: LinkVideo, URL: "https://meet.google.com/cur-rent-mtg"}}
+	noLink := timedEvent(t, "nolink", "Focus", "2025-03-17T10:30:00+00:00", "2025-03-17T11:00:00+00:00")
+	upcoming := timedEvent(t, "next", "Review", "2025-03-17T11:00:00+00:00", "2025-03-17T12:00:00+00:00")
+	upcoming.Location = "https://zoom.us/j/111"
+	declined := timedEvent(t, "declined", "Offsite", "2025-03-17T10:45:00+00:00", "2025-03-17T11:30:00+00:00")
+	declined.Links = []Link{{Kind: LinkVideo, URL: "https://meet.google.com/dec-line-ddd"}}
+	declined.Attendees = []Attendee{{Self: true, ResponseStatus: StatusDeclined}}
+
+	events := []Event{current, noLink, declined, upcoming}
+	opts := Options{Attendance: AttendanceFilter{Statuses: []string{StatusAccepted}}}
+
+	now := time.Date(2025, 3, 17, 10, 15, 0, 0, time.UTC)
+
+	item, url, err := SelectMeeting(events, opts, now, false)
+	if err != nil || item.ID != "cur" || url != "https://meet.google.com/cur-rent-mtg" {
+		t.Errorf("current meeting = %v, %q, %v", item, url, err)
+	}
+
+	item, url, err = SelectMeeting(events, opts, now, true)
+	if err != nil || item.ID != "next" || url != "https://zoom.us/j/111" {
+		t.Errorf("next meeting = %v, %q, %v", item, url, err)
+	}
+
+	later := time.Date(2025, 3, 17, 10, 40, 0, 0, time.UTC)
+	if item, _, err := SelectMeeting(events, opts, later, false); err != nil || item.ID != "next" {
+		t.Errorf("expected fallback to next meeting, got %v, %v", item, err)
+	}
+
+	if _, _, err := SelectMeeting(events, opts, time.Date(2025, 3, 17, 13, 0, 0, 0, time.UTC), false); err == nil {
+		t.Error("expected error when no meeting is left")
+	}
+}
+
+func TestTrigger_CronCommandJoinURL(t *testing.T) {
+	trigger := Trigger{
+		Cmd:     "open-meeting",
+		Events:  []EventRef{{"abc", "Sync"}},
+		JoinURL: "https://teams.microsoft.com/l/meetup-join/19%3ameeting",
+	}
+	want := `TIMEOTTER_EVENT_IDS=abc TIMEOTTER_JOIN_URL='https://teams.microsoft.com/l/meetup-join/19\%3ameeting' open-meeting`
+	if got := trigger.CronCommand(); got != want {
+		t.Errorf("CronCommand() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,257 @@
+package calendar
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/config"
+)
+
+// Trigger is a single scheduled command execution derived from an event.
+type Trigger struct {
+	// EventID and Summary identify the first event behind the trigger.
+	EventID string
+	Summary string
+	// Events lists every event behind the trigger; it has more than one
+	// entry when triggers were coalesced.
+	Events []EventRef
+	// Name identifies the trigger point, e.g. "prep" or "join".
+	Name string
+	At   time.Time
+	Cmd  string
+	// JoinURL is the video meeting link of the first event that has one.
+	JoinURL string
+	// Note records adjustments made while planning, e.g. a quiet command
+	// replacing the configured one.
+	Note string
+	// GapMinutes is the length of the free gap a free-slot trigger opens.
+	GapMinutes int
+}
+
+// EventRef identifies an event behind a trigger.
+type EventRef struct {
+	ID      string
+	Summary string
+}
+
+// Skipped records an event, or one of its trigger points, that produced no
+// trigger.
+type Skipped struct {
+	EventID string
+	Summary string
+	Reason  string
+}
+
+// Plan is the full set of triggers computed for a batch of events.
+type Plan struct {
+	Triggers []Trigger
+	Skipped  []Skipped
+	// CachedAt is non-zero when the plan was built from cached events.
+	CachedAt time.Time
+
+	away []AwaySpan
+}
+
+// allDayTriggerName is the trigger point name used for all-day events.
+const allDayTriggerName = "all-day"
+
+// BuildPlan turns events into triggers without touching the crontab.
+// Triggers that would fire at or before now are dropped.
+func BuildPlan(events []Event, opts Options, now time.Time) Plan {
+	plan := Plan{CachedAt: opts.CachedAt, away: opts.awaySpans(events)}
+
+	var admitted []Event
+	for _, item := range events {
+		if until, ok := opts.Acked[item.ID]; ok && now.Before(until) {
+			plan.skip(item, "acknowledged with timeotter ack")
+			continue
+		}
+		if opts.Away.DetectOutOfOffice && isOutOfOffice(item) {
+			plan.skip(item, "out-of-office block")
+			continue
+		}
+		if ok, reason := shouldTrigger(item, opts); !ok {
+			plan.skip(item, reason)
+			continue
+		}
+		admitted = append(admitted, item)
+	}
+	busy := busySpans(admitted)
+
+	for _, item := range admitted {
+		directive, directiveCmd, note := opts.directive(item)
+		if directive.Off {
+			plan.skip(item, "turned off by its timeotter directive")
+			continue
+		}
+		if item.AllDay {
+			times, err := AllDayTriggers(item, opts.AllDay, now)
+			if err != nil {
+				plan.skip(item, err.Error())
+				continue
+			}
+			if len(times) == 0 {
+				plan.skip(item, fmt.Sprintf("all-day event (policy %s)", opts.AllDay.Policy))
+				continue
+			}
+			for _, t := range times {
+				plan.schedule(item, allDayTriggerName, t, opts.CmdToExec, opts)
+			}
+			continue
+		}
+
+		for _, point := range opts.pointsFor(item, directive, directiveCmd) {
+			at := pointTime(item, point)
+			if !at.After(now) {
+				plan.skip(item, fmt.Sprintf("%s: %s is in the past", point.Name, at.Format(time.RFC3339)))
+				continue
+			}
+			// Leaving for an in-person meeting cannot wait for the
+			// previous one to end.
+			if prev, ok := stillBusy(busy, item, at, opts.BackToBackGap); ok && point.Name != travelTriggerName {
+				plan.skip(item, fmt.Sprintf("%s: still in %q", point.Name, prev.summary))
+				continue
+			}
+			cmd := point.Cmd
+			if cmd == "" {
+				cmd = opts.CmdToExec
+			}
+			if trigger := plan.schedule(item, point.Name, at, cmd, opts); trigger != nil && note != "" {
+				trigger.Note = joinNotes(trigger.Note, note)
+			}
+		}
+	}
+
+	if opts.FreeSlots.MinGap > 0 {
+		plan.scheduleFreeSlots(events, opts, now)
+	}
+
+	sort.SliceStable(plan.Triggers, func(i, j int) bool {
+		return plan.Triggers[i].At.Before(plan.Triggers[j].At)
+	})
+	if opts.Coalesce {
+		plan.Triggers = Coalesce(plan.Triggers)
+	}
+	return plan
+}
+
+// schedule adds a trigger after checking it against away spans and the
+// working and quiet hours, which may drop it or swap in another command.
+// It returns the added trigger, or nil when it was dropped.
+func (p *Plan) schedule(item Event, name string, at time.Time, cmd string, opts Options) *Trigger {
+	trigger := Trigger{
+		EventID: item.ID,
+		Summary: item.Summary,
+		Events:  []EventRef{{ID: item.ID, Summary: item.Summary}},
+		Name:    name,
+		At:      at,
+		Cmd:     cmd,
+		JoinURL: JoinURL(item),
+	}
+
+	if span, ok := awayAt(p.away, at); ok {
+		if !opts.Away.redirectsAway() {
+			p.skip(item, fmt.Sprintf("%s: during %s", name, span.Reason))
+			return nil
+		}
+		trigger.Cmd = opts.Away.Cmd
+		trigger.Note = fmt.Sprintf("during %s, away command", span.Reason)
+		return p.add(trigger)
+	}
+
+	decision := opts.Hours.Check(opts.calendarOf(item), at)
+	switch {
+	case decision.Drop:
+		p.skip(item, fmt.Sprintf("%s: %s", name, decision.describe(at)))
+		return nil
+	case !decision.Allowed:
+		trigger.Cmd = decision.QuietCmd
+		trigger.Note = decision.describe(at)
+	}
+	return p.add(trigger)
+}
+
+// joinNotes combines two trigger notes.
+func joinNotes(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + "; " + b
+}
+
+func (p *Plan) add(trigger Trigger) *Trigger {
+	p.Triggers = append(p.Triggers, trigger)
+	return &p.Triggers[len(p.Triggers)-1]
+}
+
+func (p *Plan) skip(item Event, reason string) {
+	p.Skipped = append(p.Skipped, Skipped{EventID: item.ID, Summary: item.Summary, Reason: reason})
+}
+
+// calendarOf returns the calendar whose overrides apply to item: the one
+// it was read from, or CalendarID when that is not known.
+func (opts Options) calendarOf(item Event) string {
+	if item.Calendar != "" {
+		return item.Calendar
+	}
+	return opts.CalendarID
+}
+
+// triggerPoints returns the configured trigger points, or the single
+// default point derived from TriggerBeforeMinutes.
+func (opts Options) triggerPoints() []config.TriggerPoint {
+	if len(opts.TriggerPoints) > 0 {
+		return opts.TriggerPoints
+	}
+	return []config.TriggerPoint{{
+		Name:          "start",
+		Anchor:        config.AnchorStart,
+		OffsetMinutes: -opts.TriggerBeforeMinutes,
+	}}
+}
+
+// pointTime resolves a trigger point against an event's start or end.
+func pointTime(item Event, point config.TriggerPoint) time.Time {
+	edge := item.Start
+	if point.Anchor == config.AnchorEnd {
+		edge = item.End
+	}
+	return edge.Add(time.Duration(point.OffsetMinutes) * time.Minute)
+}
+
+// Stale reports whether the plan was built from the offline cache.
+func (p Plan) Stale() bool {
+	return !p.CachedAt.IsZero()
+}
+
+func (p Plan) staleWarning() string {
+	return fmt.Sprintf("Warning: calendar unreachable, using events cached at %s", p.CachedAt.Format("Mon 2006-01-02 15:04"))
+}
+
+// Write prints the plan as a table, one row per trigger, followed by the
+// skipped events.
+func (p Plan) Write(w io.Writer) {
+	if p.Stale() {
+		fmt.Fprintln(w, p.staleWarning())
+	}
+	if len(p.Triggers) == 0 {
+		fmt.Fprintln(w, "No triggers planned.")
+	} else {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "WHEN\tTRIGGER\tEVENT\tCOMMAND\tNOTE")
+		for _, t := range p.Triggers {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", t.At.Format("Mon 2006-01-02 15:04"), t.Name, t.summaries(), t.Cmd, t.Note)
+		}
+		_ = tw.Flush()
+	}
+
+	if len(p.Skipped) > 0 {
+		fmt.Fprintln(w, "\nSkipped:")
+		for _, s := range p.Skipped {
+			fmt.Fprintf(w, "  %s: %s\n", s.Summary, s.Reason)
+		}
+	}
+}
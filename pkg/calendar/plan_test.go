@@ -0,0 +1,173 @@
+package calendar
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/config"
+)
+
+func timedEvent(t *testing.T, id, summary, start, end string) Event {
+	t.Helper()
+	return Event{ID: id, Summary: summary, Start: mustParseEventTime(t, start), End: mustParseEventTime(t, end)}
+}
+
+func mustParseEventTime(t *testing.T, s string) time.Time {
+	t.Helper()
+	parsed, err := ParseEventTime(s)
+	if err != nil {
+		t.Fatalf("parsing %q: %v", s, err)
+	}
+	return parsed
+}
+
+func planNow() time.Time {
+	return time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func TestBuildPlan_DefaultTriggerPoint(t *testing.T) {
+	events := []Event{
+		timedEvent(t, "a", "Standup", "2025-03-15T10:00:00+05:30", "2025-03-15T10:15:00+05:30"),
+	}
+	plan := BuildPlan(events, Options{CmdToExec: "echo hi", TriggerBeforeMinutes: 5}, planNow())
+
+	if len(plan.Triggers) != 1 {
+		t.Fatalf("expected 1 trigger, got %d", len(plan.Triggers))
+	}
+	got := plan.Triggers[0]
+	if got.Name != "start" || got.Cmd != "echo hi" || CronExpression(got.At) != "55 9 15 3 6" {
+		t.Errorf("unexpected trigger %+v (cron %s)", got, CronExpression(got.At))
+	}
+}
+
+func TestBuildPlan_MultipleTriggerPoints(t *testing.T) {
+	events := []Event{
+		timedEvent(t, "a", "Design review", "2025-03-15T10:00:00+05:30", "2025-03-15T11:00:00+05:30"),
+	}
+	opts := Options{
+		CmdToExec: "notify",
+		TriggerPoints: []config.TriggerPoint{
+			{Name: "prep", Anchor: config.AnchorStart, OffsetMinutes: -10, Cmd: "prep.sh"},
+			{Name: "join", Anchor: config.AnchorStart},
+			{Name: "log", Anchor: config.AnchorEnd, OffsetMinutes: 2, Cmd: "log-time.sh"},
+		},
+	}
+	plan := BuildPlan(events, opts, planNow())
+
+	want := []struct {
+		name, cron, cmd string
+	}{
+		{"prep", "50 9 15 3 6", "prep.sh"},
+		{"join", "0 10 15 3 6", "notify"},
+		{"log", "2 11 15 3 6", "log-time.sh"},
+	}
+	if len(plan.Triggers) != len(want) {
+		t.Fatalf("expected %d triggers, got %d", len(want), len(plan.Triggers))
+	}
+	for i, w := range want {
+		got := plan.Triggers[i]
+		if got.Name != w.name || CronExpression(got.At) != w.cron || got.Cmd != w.cmd {
+			t.Errorf("trigger %d = %s %s %s, want %s %s %s",
+				i, got.Name, CronExpression(got.At), got.Cmd, w.name, w.cron, w.cmd)
+		}
+	}
+}
+
+func TestBuildPlan_SortsAcrossEvents(t *testing.T) {
+	events := []Event{
+		timedEvent(t, "a", "First", "2025-03-15T10:00:00+05:30", "2025-03-15T12:00:00+05:30"),
+		timedEvent(t, "b", "Second", "2025-03-15T11:00:00+05:30", "2025-03-15T11:30:00+05:30"),
+	}
+	opts := Options{TriggerPoints: []config.TriggerPoint{
+		{Name: "start", Anchor: config.AnchorStart},
+		{Name: "end", Anchor: config.AnchorEnd},
+	}}
+	plan := BuildPlan(events, opts, planNow())
+
+	var order []string
+	for _, trigger := range plan.Triggers {
+		order = append(order, trigger.Summary+"/"+trigger.Name)
+	}
+	if got := strings.Join(order, ","); got != "First/start,Second/start,Second/end,First/end" {
+		t.Errorf("unexpected order %s", got)
+	}
+}
+
+func TestBuildPlan_DropsPastTriggers(t *testing.T) {
+	// Event in progress: the start trigger has passed, the end one has not.
+	events := []Event{
+		timedEvent(t, "a", "Ongoing", "2025-03-01T09:00:00+00:00", "2025-03-01T11:00:00+00:00"),
+	}
+	opts := Options{TriggerPoints: []config.TriggerPoint{
+		{Name: "start", Anchor: config.AnchorStart},
+		{Name: "end", Anchor: config.AnchorEnd},
+	}}
+	plan := BuildPlan(events, opts, time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC))
+
+	if len(plan.Triggers) != 1 || plan.Triggers[0].Name != "end" {
+		t.Errorf("expected only the end trigger, got %+v", plan.Triggers)
+	}
+	if len(plan.Skipped) != 1 || !strings.Contains(plan.Skipped[0].Reason, "in the past") {
+		t.Errorf("expected past start to be reported, got %+v", plan.Skipped)
+	}
+}
+
+func TestBuildPlan_SkipsFilteredEvents(t *testing.T) {
+	declined := timedEvent(t, "a", "Declined", "2025-03-15T10:00:00+05:30", "2025-03-15T11:00:00+05:30")
+	declined.Attendees = []Attendee{{Self: true, ResponseStatus: StatusDeclined}}
+	events := []Event{declined}
+
+	plan := BuildPlan(events, Options{Attendance: AttendanceFilter{Statuses: []string{StatusAccepted}}}, planNow())
+	if len(plan.Triggers) != 0 || len(plan.Skipped) != 1 {
+		t.Errorf("expected declined event to be skipped, got %+v", plan)
+	}
+}
+
+func TestPlan_Write(t *testing.T) {
+	loc := time.FixedZone("IST", 5*3600+1800)
+	plan := Plan{
+		Triggers: []Trigger{
+			{Summary: "Design review", Name: "prep", At: time.Date(2025, 3, 15, 9, 50, 0, 0, loc), Cmd: "prep.sh"},
+			{Summary: "Design review", Name: "join", At: time.Date(2025, 3, 15, 10, 0, 0, 0, loc), Cmd: "join.sh"},
+		},
+		Skipped: []Skipped{{Summary: "Lunch", Reason: `excluded by rule "no-lunch"`}},
+	}
+
+	var buf bytes.Buffer
+	plan.Write(&buf)
+	out := buf.String()
+
+	for _, want := range []string{
+		"Sat 2025-03-15 09:50  prep",
+		"Sat 2025-03-15 10:00  join",
+		"prep.sh",
+		`Lunch: excluded by rule "no-lunch"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("plan output missing %q:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	Plan{}.Write(&buf)
+	if !strings.Contains(buf.String(), "No triggers planned.") {
+		t.Errorf("unexpected empty plan output: %q", buf.String())
+	}
+}
+
+func TestParseEventTime(t *testing.T) {
+	for _, input := range []string{
+		"2025-02-02T20:29:00+05:30",
+		"2025/02/02T20:29:00+05:30",
+		"2025-02-02T14:59:00Z",
+	} {
+		if _, err := ParseEventTime(input); err != nil {
+			t.Errorf("ParseEventTime(%q) failed: %v", input, err)
+		}
+	}
+	if _, err := ParseEventTime("2025-02-02"); err == nil {
+		t.Error("expected error for date-only value")
+	}
+}
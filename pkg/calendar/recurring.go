@@ -0,0 +1,108 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/rrule"
+)
+
+// ExpandRecurring replaces the recurring series in events with their
+// instances that overlap [from, to), for sources whose provider delivers a
+// series as a single event with recurrence lines. Occurrences are computed
+// in the series' own time zone, so they keep their wall-clock time across
+// DST changes.
+//
+// Overrides, i.e. events with a SeriesID and a RecurrenceID, replace the
+// occurrence they were split from; cancelled overrides remove it. A series
+// that cannot be expanded is left out and reported in the returned error,
+// alongside the events that could be expanded.
+func ExpandRecurring(events []Event, from, to time.Time) ([]Event, error) {
+	overridden := make(map[string]bool)
+	for _, item := range events {
+		if item.SeriesID != "" && !item.RecurrenceID.IsZero() {
+			overridden[instanceKey(item.SeriesID, item.RecurrenceID)] = true
+		}
+	}
+
+	var out []Event
+	var errs []error
+	for _, item := range events {
+		if item.Status == "cancelled" {
+			continue
+		}
+		if len(item.Recurrence) == 0 {
+			if item.End.After(from) && item.Start.Before(to) {
+				out = append(out, item)
+			}
+			continue
+		}
+
+		instances, err := expandSeries(item, from, to)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("expanding %q: %w", item.Summary, err))
+			continue
+		}
+		for _, instance := range instances {
+			if !overridden[instanceKey(item.ID, instance.RecurrenceID)] {
+				out = append(out, instance)
+			}
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	return out, errors.Join(errs...)
+}
+
+// expandSeries returns the instances of a recurring event overlapping
+// [from, to).
+func expandSeries(series Event, from, to time.Time) ([]Event, error) {
+	loc := series.Start.Location()
+	if series.TimeZone != "" {
+		tz, err := time.LoadLocation(series.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("unknown time zone %q", series.TimeZone)
+		}
+		loc = tz
+	}
+	dtstart := series.Start.In(loc)
+	if series.AllDay {
+		dtstart = time.Date(dtstart.Year(), dtstart.Month(), dtstart.Day(), 0, 0, 0, 0, loc)
+	}
+
+	set, err := rrule.ParseSet(dtstart, series.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+
+	duration := series.End.Sub(series.Start)
+	days := int(duration.Round(24*time.Hour) / (24 * time.Hour))
+
+	var instances []Event
+	// Start the search early enough to catch an occurrence already under
+	// way at from.
+	for _, start := range set.Between(from.Add(-duration-24*time.Hour), to) {
+		instance := series
+		instance.SeriesID = series.ID
+		instance.Recurrence = nil
+		instance.RecurrenceID = start
+		instance.ID = instanceKey(series.ID, start)
+		instance.Start, instance.End = start, start.Add(duration)
+		if series.AllDay {
+			instance.ID = series.ID + "_" + start.Format("20060102")
+			instance.End = start.AddDate(0, 0, days)
+		}
+		if instance.End.After(from) {
+			instances = append(instances, instance)
+		}
+	}
+	return instances, nil
+}
+
+// instanceKey identifies one occurrence of a series, in the same form the
+// Google Calendar API uses for instance IDs.
+func instanceKey(seriesID string, start time.Time) string {
+	return seriesID + "_" + start.UTC().Format("20060102T150405Z")
+}
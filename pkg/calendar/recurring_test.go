@@ -0,0 +1,90 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpandRecurring(t *testing.T) {
+	ny := mustLoadLocation(t, "America/New_York")
+	series := timedEvent(t, "standup", "Standup", "2025-10-27T09:00:00-04:00", "2025-10-27T09:15:00-04:00")
+	series.TimeZone = "America/New_York"
+	series.Recurrence = []string{
+		"RRULE:FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR",
+		"EXDATE;TZID=America/New_York:20251029T090000",
+	}
+
+	moved := timedEvent(t, "standup_20251030T130000Z", "Standup (moved)", "2025-10-30T11:00:00-04:00", "2025-10-30T11:15:00-04:00")
+	moved.SeriesID = "standup"
+	moved.RecurrenceID = time.Date(2025, 10, 30, 9, 0, 0, 0, ny)
+
+	cancelled := timedEvent(t, "standup_20251103T140000Z", "Standup", "2025-11-03T09:00:00-05:00", "2025-11-03T09:15:00-05:00")
+	cancelled.SeriesID = "standup"
+	cancelled.RecurrenceID = time.Date(2025, 11, 3, 9, 0, 0, 0, ny)
+	cancelled.Status = "cancelled"
+
+	single := timedEvent(t, "lunch", "Lunch", "2025-10-31T12:00:00-04:00", "2025-10-31T13:00:00-04:00")
+
+	from := time.Date(2025, 10, 28, 0, 0, 0, 0, ny)
+	to := time.Date(2025, 11, 5, 0, 0, 0, 0, ny)
+	got, err := ExpandRecurring([]Event{series, moved, cancelled, single}, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	for _, item := range got {
+		lines = append(lines, item.ID+" "+item.Start.Format(time.RFC3339)+" "+item.End.Format("15:04"))
+	}
+	want := []string{
+		"standup_20251028T130000Z 2025-10-28T09:00:00-04:00 09:15",
+		"standup_20251030T130000Z 2025-10-30T11:00:00-04:00 11:15",
+		"standup_20251031T130000Z 2025-10-31T09:00:00-04:00 09:15",
+		"lunch 2025-10-31T12:00:00-04:00 13:00",
+		"standup_20251104T140000Z 2025-11-04T09:00:00-05:00 09:15",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+	if got[0].SeriesID != "standup" || got[0].Recurrence != nil || got[0].Summary != "Standup" {
+		t.Errorf("instance not derived from series: %+v", got[0])
+	}
+}
+
+func TestExpandRecurring_AllDay(t *testing.T) {
+	loc := mustLoadLocation(t, "Europe/Berlin")
+	series := allDayEvent(t, loc, "2025-03-28", "2025-03-30")
+	series.ID = "trip"
+	series.TimeZone = "Europe/Berlin"
+	series.Recurrence = []string{"RRULE:FREQ=WEEKLY;COUNT=3", "EXDATE;VALUE=DATE:20250404"}
+
+	got, err := ExpandRecurring([]Event{series}, time.Date(2025, 3, 29, 12, 0, 0, 0, loc), time.Date(2025, 5, 1, 0, 0, 0, 0, loc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected the running and the third occurrence, got %+v", got)
+	}
+	// The two-day span crosses the DST change at the end of March.
+	if got[0].ID != "trip_20250328" || got[0].End.Format("2006-01-02 15:04") != "2025-03-30 00:00" {
+		t.Errorf("unexpected first instance %s %s .. %s", got[0].ID, got[0].Start, got[0].End)
+	}
+	if got[1].ID != "trip_20250411" || !got[1].AllDay {
+		t.Errorf("unexpected second instance %s %s", got[1].ID, got[1].Start)
+	}
+}
+
+func TestExpandRecurring_Invalid(t *testing.T) {
+	broken := timedEvent(t, "x", "Broken", "2025-03-17T09:00:00Z", "2025-03-17T10:00:00Z")
+	broken.Recurrence = []string{"RRULE:FREQ=SOMETIMES"}
+	ok := timedEvent(t, "y", "Fine", "2025-03-17T11:00:00Z", "2025-03-17T12:00:00Z")
+
+	got, err := ExpandRecurring([]Event{broken, ok}, time.Date(2025, 3, 17, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 18, 0, 0, 0, 0, time.UTC))
+	if err == nil || !strings.Contains(err.Error(), "Broken") {
+		t.Errorf("expected error naming the broken series, got %v", err)
+	}
+	if len(got) != 1 || got[0].ID != "y" {
+		t.Errorf("expected the valid event to survive, got %+v", got)
+	}
+}
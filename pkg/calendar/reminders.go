@@ -0,0 +1,96 @@
+package calendar
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/bupd/timeotter/pkg/config"
+)
+
+// ReminderOptions controls deriving trigger times from event reminders.
+type ReminderOptions struct {
+	// Enabled switches from the fixed trigger points to reminder minutes.
+	Enabled bool
+	// Methods restricts which reminder methods count, e.g. ["popup"].
+	// An empty list accepts every method.
+	Methods []string
+	// FallbackMinutes is used for events without any matching reminder.
+	// When empty, such events use the fixed trigger points.
+	FallbackMinutes []int
+}
+
+// ReminderMinutes returns the distinct reminder lead times of item, in
+// descending order. Only reminders whose method is listed in methods are
+// returned, unless methods is empty.
+func ReminderMinutes(item Event, methods []string) []int {
+	seen := make(map[int]bool)
+	var minutes []int
+	for _, reminder := range item.Reminders {
+		if !methodAllowed(reminder.Method, methods) {
+			continue
+		}
+		m := reminder.Minutes
+		if !seen[m] {
+			seen[m] = true
+			minutes = append(minutes, m)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(minutes)))
+	return minutes
+}
+
+func methodAllowed(method string, methods []string) bool {
+	if len(methods) == 0 {
+		return true
+	}
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// reminderPoints turns lead times into start-anchored trigger points.
+func reminderPoints(minutes []int) []config.TriggerPoint {
+	points := make([]config.TriggerPoint, 0, len(minutes))
+	for _, m := range minutes {
+		points = append(points, config.TriggerPoint{
+			Name:          fmt.Sprintf("reminder-%dm", m),
+			Anchor:        config.AnchorStart,
+			OffsetMinutes: -m,
+		})
+	}
+	return points
+}
+
+// pointsFor returns the trigger points that apply to item. In reminder mode
+// they come from the event's reminders, then FallbackMinutes, and finally
+// the fixed trigger points. Tasks use their own single point instead. Routes
+// and then the event's own directive may replace these points or their
+// command, and events at a location with a known travel time get an extra
+// "leave" point.
+func (opts Options) pointsFor(item Event, d Directive, cmd string) []config.TriggerPoint {
+	if item.Type == EventTypeTask {
+		return applyDirective(item, opts.Routes.Points(item, []config.TriggerPoint{opts.taskPoint()}), d, cmd)
+	}
+	points := applyDirective(item, opts.Routes.Points(item, opts.leadPoints(item)), d, cmd)
+	if leave, ok := opts.Travel.Point(item); ok {
+		points = append([]config.TriggerPoint{leave}, points...)
+	}
+	return points
+}
+
+// leadPoints returns the reminder or fixed trigger points of item.
+func (opts Options) leadPoints(item Event) []config.TriggerPoint {
+	if !opts.Reminders.Enabled {
+		return opts.triggerPoints()
+	}
+	if minutes := ReminderMinutes(item, opts.Reminders.Methods); len(minutes) > 0 {
+		return reminderPoints(minutes)
+	}
+	if len(opts.Reminders.FallbackMinutes) > 0 {
+		return reminderPoints(opts.Reminders.FallbackMinutes)
+	}
+	return opts.triggerPoints()
+}
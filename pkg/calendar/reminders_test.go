@@ -0,0 +1,109 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bupd/timeotter/pkg/config"
+)
+
+func TestReminderMinutes(t *testing.T) {
+	tests := []struct {
+		name      string
+		reminders []Reminder
+		methods   []string
+		want      []int
+	}{
+		{
+			name:      "descending order",
+			reminders: []Reminder{{Method: "popup", Minutes: 2}, {Method: "popup", Minutes: 15}},
+			want:      []int{15, 2},
+		},
+		{
+			name:      "popup only",
+			reminders: []Reminder{{Method: "popup", Minutes: 10}, {Method: "email", Minutes: 60}},
+			methods:   []string{"popup"},
+			want:      []int{10},
+		},
+		{
+			name:      "duplicates collapse",
+			reminders: []Reminder{{Method: "popup", Minutes: 10}, {Method: "email", Minutes: 10}},
+			want:      []int{10},
+		},
+		{
+			name: "no reminders",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ReminderMinutes(Event{Reminders: tt.reminders}, tt.methods)
+			if len(got) != len(tt.want) {
+				t.Fatalf("ReminderMinutes() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("ReminderMinutes() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildPlan_ReminderMode(t *testing.T) {
+	withOverrides := timedEvent(t, "a", "Interview", "2025-03-15T10:00:00+05:30", "2025-03-15T11:00:00+05:30")
+	withOverrides.Reminders = []Reminder{
+		{Method: "popup", Minutes: 30},
+		{Method: "email", Minutes: 1440},
+	}
+	noReminders := timedEvent(t, "b", "Focus", "2025-03-15T14:00:00+05:30", "2025-03-15T15:00:00+05:30")
+
+	events := []Event{withOverrides, noReminders}
+
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{
+			name: "popup reminders with fallback minutes",
+			opts: Options{
+				CmdToExec: "notify",
+				Reminders: ReminderOptions{Enabled: true, Methods: []string{"popup"}, FallbackMinutes: []int{3}},
+			},
+			want: []string{"Interview/reminder-30m 30 9", "Focus/reminder-3m 57 13"},
+		},
+		{
+			name: "fallback to fixed trigger points",
+			opts: Options{
+				CmdToExec:     "notify",
+				TriggerPoints: []config.TriggerPoint{{Name: "join", Anchor: config.AnchorStart}},
+				Reminders:     ReminderOptions{Enabled: true, Methods: []string{"popup"}},
+			},
+			want: []string{"Interview/reminder-30m 30 9", "Focus/join 0 14"},
+		},
+		{
+			name: "disabled uses fixed points",
+			opts: Options{CmdToExec: "notify", TriggerBeforeMinutes: 5},
+			want: []string{"Interview/start 55 9", "Focus/start 55 13"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := BuildPlan(events, tt.opts, planNow())
+			var got []string
+			for _, trigger := range plan.Triggers {
+				cronParts := strings.Fields(CronExpression(trigger.At))
+				got = append(got, trigger.Summary+"/"+trigger.Name+" "+cronParts[0]+" "+cronParts[1])
+				if trigger.Cmd != "notify" {
+					t.Errorf("trigger %s has command %q, want notify", trigger.Name, trigger.Cmd)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("triggers = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package calendar
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bupd/timeotter/pkg/config"
+)
+
+// RouteSet is an ordered list of routes that pick the command and lead time
+// of each event. Depending on the mode, the first matching route or every
+// matching route applies; events matching none use the default route, or
+// the usual trigger points and CmdToExec when there is no default route.
+type RouteSet struct {
+	routes       []route
+	defaultRoute *route
+	all          bool
+}
+
+type route struct {
+	name           string
+	summary        *regexp.Regexp
+	calendar       string
+	attendeeDomain string
+	eventType      string
+	cmd            string
+	beforeMinutes  *int
+}
+
+// NewRouteSet compiles the configured routes. mode is "first" or "all".
+func NewRouteSet(routes []config.Route, mode string) (*RouteSet, error) {
+	rs := &RouteSet{all: strings.EqualFold(mode, config.RouteAll)}
+
+	for i, r := range routes {
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("route %d", i+1)
+		}
+		compiled := route{
+			name:           name,
+			calendar:       r.Calendar,
+			attendeeDomain: strings.ToLower(strings.TrimPrefix(r.AttendeeDomain, "@")),
+			eventType:      r.EventType,
+			cmd:            r.Cmd,
+			beforeMinutes:  r.BeforeMinutes,
+		}
+		if err := compileOptional(&compiled.summary, r.Summary); err != nil {
+			return nil, fmt.Errorf("%s: invalid Summary pattern: %w", name, err)
+		}
+		if r.Default {
+			rs.defaultRoute = &compiled
+			continue
+		}
+		rs.routes = append(rs.routes, compiled)
+	}
+
+	return rs, nil
+}
+
+// Match returns the names of the routes that apply to item, or nil when
+// it keeps the usual trigger points.
+func (rs *RouteSet) Match(item Event) []string {
+	var names []string
+	for _, r := range rs.match(item) {
+		names = append(names, r.name)
+	}
+	return names
+}
+
+func (rs *RouteSet) match(item Event) []route {
+	if rs == nil {
+		return nil
+	}
+	var matched []route
+	for _, r := range rs.routes {
+		if !r.matches(item) {
+			continue
+		}
+		matched = append(matched, r)
+		if !rs.all {
+			break
+		}
+	}
+	if len(matched) == 0 && rs.defaultRoute != nil {
+		matched = append(matched, *rs.defaultRoute)
+	}
+	return matched
+}
+
+// Points returns the trigger points of item after routing. base holds the
+// points item would get without routes; a matching route either replaces
+// them with a single point BeforeMinutes before the start, or keeps them
+// and runs its command, if it has one, at each.
+func (rs *RouteSet) Points(item Event, base []config.TriggerPoint) []config.TriggerPoint {
+	matched := rs.match(item)
+	if len(matched) == 0 {
+		return base
+	}
+
+	var points []config.TriggerPoint
+	for _, r := range matched {
+		if r.beforeMinutes != nil {
+			points = append(points, config.TriggerPoint{
+				Name:          r.name,
+				Anchor:        config.AnchorStart,
+				OffsetMinutes: -*r.beforeMinutes,
+				Cmd:           r.cmd,
+			})
+			continue
+		}
+		for _, point := range base {
+			if r.cmd != "" {
+				point.Cmd = r.cmd
+			}
+			points = append(points, point)
+		}
+	}
+	return points
+}
+
+func (r route) matches(item Event) bool {
+	if r.summary != nil && !r.summary.MatchString(item.Summary) {
+		return false
+	}
+	if r.calendar != "" && item.Calendar != r.calendar {
+		return false
+	}
+	if r.eventType != "" && eventType(item) != r.eventType {
+		return false
+	}
+	if r.attendeeDomain != "" && !hasAttendeeAt(item, r.attendeeDomain) {
+		return false
+	}
+	return true
+}
+
+// hasAttendeeAt reports whether a guest of item other than the user has an
+// email address at domain.
+func hasAttendeeAt(item Event, domain string) bool {
+	for _, attendee := range item.Attendees {
+		if attendee.Self {
+			continue
+		}
+		_, at, ok := strings.Cut(strings.ToLower(attendee.Email), "@")
+		if ok && at == domain {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package calendar
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bupd/timeotter/pkg/config"
+)
+
+func intPtr(n int) *int { return &n }
+
+func testRoutes() []config.Route {
+	return []config.Route{
+		{Name: "interview", Summary: `(?i)interview`, AttendeeDomain: "@Candidates.example", Cmd: "open-doc", BeforeMinutes: intPtr(15)},
+		{Name: "standup", Summary: `(?i)standup`, Cmd: "start-timer", BeforeMinutes: intPtr(0)},
+		{Name: "team", Calendar: "team@example.com", Cmd: "notify-team"},
+		{Name: "tasks", EventType: EventTypeTask, Cmd: "todo"},
+	}
+}
+
+// routedPlan renders the triggers of the plan as "15:04 name cmd" lines.
+func routedPlan(t *testing.T, routes []config.Route, mode string, events []Event) []string {
+	t.Helper()
+	routeSet, err := NewRouteSet(routes, mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{
+		CmdToExec:            "notify",
+		TriggerBeforeMinutes: 5,
+		Routes:               routeSet,
+		Tasks:                TaskOptions{Cmd: "task-notify", BeforeMinutes: 30},
+	}
+	var got []string
+	for _, trigger := range BuildPlan(events, opts, planNow()).Triggers {
+		got = append(got, trigger.At.Format("15:04")+" "+trigger.Name+" "+trigger.Cmd)
+	}
+	return got
+}
+
+func routeEvents(t *testing.T) []Event {
+	t.Helper()
+	interview := timedEvent(t, "a", "Interview: backend", "2025-03-15T10:00:00Z", "2025-03-15T11:00:00Z")
+	interview.Attendees = []Attendee{
+		{Email: "me@example.com", Self: true},
+		{Email: "jane@candidates.example"},
+	}
+	standup := timedEvent(t, "b", "Team standup", "2025-03-15T12:00:00Z", "2025-03-15T12:15:00Z")
+	standup.Calendar = "team@example.com"
+	planning := timedEvent(t, "c", "Planning", "2025-03-15T14:00:00Z", "2025-03-15T15:00:00Z")
+	planning.Calendar = "team@example.com"
+	other := timedEvent(t, "d", "Dentist", "2025-03-15T16:00:00Z", "2025-03-15T17:00:00Z")
+	task := Event{ID: "t", Summary: "File taxes", Type: EventTypeTask, Start: mustParseEventTime(t, "2025-03-15T18:00:00Z"), End: mustParseEventTime(t, "2025-03-15T18:00:00Z")}
+	return []Event{interview, standup, planning, other, task}
+}
+
+func TestBuildPlan_RoutesFirstMatch(t *testing.T) {
+	got := routedPlan(t, testRoutes(), config.RouteFirst, routeEvents(t))
+	want := []string{
+		"09:45 interview open-doc",
+		"12:00 standup start-timer",
+		"13:55 start notify-team",
+		"15:55 start notify",
+		"17:30 due todo",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestBuildPlan_RoutesAllMatch(t *testing.T) {
+	got := routedPlan(t, testRoutes(), config.RouteAll, routeEvents(t))
+	want := []string{
+		"09:45 interview open-doc",
+		"11:55 start notify-team",
+		"12:00 standup start-timer",
+		"13:55 start notify-team",
+		"15:55 start notify",
+		"17:30 due todo",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestBuildPlan_RoutesDefault(t *testing.T) {
+	routes := append(testRoutes(), config.Route{Name: "fallback", Cmd: "notify-send 'Soon'", BeforeMinutes: intPtr(10), Default: true})
+	got := routedPlan(t, routes, config.RouteFirst, routeEvents(t))
+	want := []string{
+		"09:45 interview open-doc",
+		"12:00 standup start-timer",
+		"13:55 start notify-team",
+		"15:50 fallback notify-send 'Soon'",
+		"17:30 due todo",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestRouteSet_AttendeeDomainIgnoresSelf(t *testing.T) {
+	routeSet, err := NewRouteSet([]config.Route{{Name: "external", AttendeeDomain: "example.com"}}, config.RouteFirst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := Event{Attendees: []Attendee{{Email: "me@example.com", Self: true}, {Email: "bob@other.org"}}}
+	if names := routeSet.Match(item); len(names) != 0 {
+		t.Errorf("expected no route, got %v", names)
+	}
+	item.Attendees = append(item.Attendees, Attendee{Email: "Alice@Example.com"})
+	if names := routeSet.Match(item); len(names) != 1 || names[0] != "external" {
+		t.Errorf("expected the external route, got %v", names)
+	}
+}
+
+func TestNewRouteSet_InvalidPattern(t *testing.T) {
+	_, err := NewRouteSet([]config.Route{{Name: "bad", Summary: "("}}, config.RouteFirst)
+	if err == nil || !strings.Contains(err.Error(), "bad: invalid Summary pattern") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestExplainEvent_Routes(t *testing.T) {
+	routeSet, err := NewRouteSet(testRoutes(), config.RouteAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	ExplainEvent(&buf, routeEvents(t)[1], Options{Routes: routeSet})
+	if !strings.Contains(buf.String(), "Routes:     standup, team\n") {
+		t.Errorf("unexpected explanation:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,176 @@
+package calendar
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bupd/timeotter/pkg/config"
+)
+
+// RuleSet is an ordered list of include/exclude rules. The first rule that
+// matches an event decides whether it produces a trigger; events matching
+// no rule fall back to the default action.
+type RuleSet struct {
+	rules          []rule
+	defaultInclude bool
+}
+
+type rule struct {
+	name          string
+	include       bool
+	summary       *regexp.Regexp
+	description   *regexp.Regexp
+	location      *regexp.Regexp
+	organizer     *regexp.Regexp
+	colorID       string
+	eventType     string
+	minAttendees  int
+	maxAttendees  int
+	hasConference *bool
+}
+
+// RuleTrace records how a single rule evaluated against an event.
+type RuleTrace struct {
+	Name     string
+	Include  bool
+	Matched  bool
+	Mismatch string
+}
+
+// Decision is the outcome of evaluating an event against a RuleSet.
+type Decision struct {
+	Include bool
+	// Rule is the name of the matching rule, or empty when the default applied.
+	Rule  string
+	Trace []RuleTrace
+}
+
+// NewRuleSet compiles the configured rules. defaultAction is used for events
+// that match no rule and must be "include" or "exclude".
+func NewRuleSet(rules []config.Rule, defaultAction string) (*RuleSet, error) {
+	rs := &RuleSet{defaultInclude: !strings.EqualFold(defaultAction, config.RuleExclude)}
+
+	for i, r := range rules {
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("rule %d", i+1)
+		}
+		compiled := rule{
+			name:          name,
+			include:       !strings.EqualFold(r.Action, config.RuleExclude),
+			colorID:       r.ColorID,
+			eventType:     r.EventType,
+			minAttendees:  r.MinAttendees,
+			maxAttendees:  r.MaxAttendees,
+			hasConference: r.HasConference,
+		}
+		if err := compileOptional(&compiled.summary, r.Summary); err != nil {
+			return nil, fmt.Errorf("%s: invalid Summary pattern: %w", name, err)
+		}
+		if err := compileOptional(&compiled.description, r.Description); err != nil {
+			return nil, fmt.Errorf("%s: invalid Description pattern: %w", name, err)
+		}
+		if err := compileOptional(&compiled.location, r.Location); err != nil {
+			return nil, fmt.Errorf("%s: invalid Location pattern: %w", name, err)
+		}
+		if err := compileOptional(&compiled.organizer, r.Organizer); err != nil {
+			return nil, fmt.Errorf("%s: invalid Organizer pattern: %w", name, err)
+		}
+		rs.rules = append(rs.rules, compiled)
+	}
+
+	return rs, nil
+}
+
+// compileOptional compiles pattern into dst, leaving dst nil when the
+// pattern is empty and the field is not matched on.
+func compileOptional(dst **regexp.Regexp, pattern string) error {
+	if pattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	*dst = re
+	return nil
+}
+
+// Evaluate runs the rules in order against item and reports the decision
+// together with a trace of every rule that was consulted.
+func (rs *RuleSet) Evaluate(item Event) Decision {
+	if rs == nil {
+		return Decision{Include: true}
+	}
+
+	var trace []RuleTrace
+	for _, r := range rs.rules {
+		mismatch := r.mismatch(item)
+		trace = append(trace, RuleTrace{
+			Name:     r.name,
+			Include:  r.include,
+			Matched:  mismatch == "",
+			Mismatch: mismatch,
+		})
+		if mismatch == "" {
+			return Decision{Include: r.include, Rule: r.name, Trace: trace}
+		}
+	}
+
+	return Decision{Include: rs.defaultInclude, Trace: trace}
+}
+
+// mismatch returns a description of the first criterion that item fails,
+// or an empty string when every configured criterion matches.
+func (r rule) mismatch(item Event) string {
+	if r.summary != nil && !r.summary.MatchString(item.Summary) {
+		return fmt.Sprintf("summary does not match %q", r.summary)
+	}
+	if r.description != nil && !r.description.MatchString(item.Description) {
+		return fmt.Sprintf("description does not match %q", r.description)
+	}
+	if r.location != nil && !r.location.MatchString(item.Location) {
+		return fmt.Sprintf("location does not match %q", r.location)
+	}
+	if r.organizer != nil && !matchesOrganizer(r.organizer, item.Organizer) {
+		return fmt.Sprintf("organizer does not match %q", r.organizer)
+	}
+	if r.colorID != "" && item.ColorID != r.colorID {
+		return fmt.Sprintf("colorId is %q, want %q", item.ColorID, r.colorID)
+	}
+	if r.eventType != "" && eventType(item) != r.eventType {
+		return fmt.Sprintf("eventType is %q, want %q", eventType(item), r.eventType)
+	}
+	count := len(item.Attendees)
+	if r.minAttendees > 0 && count < r.minAttendees {
+		return fmt.Sprintf("%d attendees, want at least %d", count, r.minAttendees)
+	}
+	if r.maxAttendees > 0 && count > r.maxAttendees {
+		return fmt.Sprintf("%d attendees, want at most %d", count, r.maxAttendees)
+	}
+	if r.hasConference != nil && hasConference(item) != *r.hasConference {
+		if *r.hasConference {
+			return "event has no conference link"
+		}
+		return "event has a conference link"
+	}
+	return ""
+}
+
+func matchesOrganizer(re *regexp.Regexp, organizer Person) bool {
+	return re.MatchString(organizer.Email) || re.MatchString(organizer.Name)
+}
+
+// eventType returns the event's type, defaulting to "default" as the
+// Calendar API does.
+func eventType(item Event) string {
+	if item.Type == "" {
+		return "default"
+	}
+	return item.Type
+}
+
+func hasConference(item Event) bool {
+	return len(item.Links) > 0
+}
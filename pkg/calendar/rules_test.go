@@ -0,0 +1,150 @@
+package calendar
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bupd/timeotter/pkg/config"
+)
+
+func boolPtr(b bool) *bool { return &b }
+
+func TestRuleSet_Evaluate(t *testing.T) {
+	rules := []config.Rule{
+		{Name: "no-lunch", Action: "exclude", Summary: "(?i)^lunch$"},
+		{Name: "no-working-location", Action: "exclude", EventType: "workingLocation"},
+		{Name: "meet-only", Action: "include", HasConference: boolPtr(true)},
+		{Name: "red", Action: "include", ColorID: "11"},
+		{Name: "big-from-boss", Action: "include", Organizer: "boss@example\\.com", MinAttendees: 3},
+	}
+	rs, err := NewRuleSet(rules, "exclude")
+	if err != nil {
+		t.Fatalf("NewRuleSet: %v", err)
+	}
+
+	attendees := func(n int) []Attendee {
+		return make([]Attendee, n)
+	}
+	meet := []Link{{Kind: LinkVideo, URL: "https://meet.google.com/x"}}
+
+	tests := []struct {
+		name     string
+		event    Event
+		include  bool
+		wantRule string
+	}{
+		{"lunch excluded even with meet link", Event{Summary: "Lunch", Links: meet}, false, "no-lunch"},
+		{"working location excluded", Event{Summary: "Office", Type: "workingLocation"}, false, "no-working-location"},
+		{"meet link included", Event{Summary: "Sync", Links: meet}, true, "meet-only"},
+		{
+			"phone entry point included",
+			Event{Summary: "Call", Links: []Link{{Kind: LinkPhone, URL: "tel:+1-555-0100"}}},
+			true,
+			"meet-only",
+		},
+		{"color 11 included", Event{Summary: "Deadline", ColorID: "11"}, true, "red"},
+		{
+			"boss with enough attendees",
+			Event{Summary: "All hands", Organizer: Person{Email: "boss@example.com"}, Attendees: attendees(4)},
+			true,
+			"big-from-boss",
+		},
+		{
+			"boss with too few attendees falls to default",
+			Event{Summary: "1:1", Organizer: Person{Email: "boss@example.com"}, Attendees: attendees(2)},
+			false,
+			"",
+		},
+		{"nothing matches uses default", Event{Summary: "Random"}, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := rs.Evaluate(tt.event)
+			if d.Include != tt.include || d.Rule != tt.wantRule {
+				t.Errorf("Evaluate() = include %v rule %q, want include %v rule %q",
+					d.Include, d.Rule, tt.include, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestRuleSet_FirstMatchWins(t *testing.T) {
+	rs, err := NewRuleSet([]config.Rule{
+		{Action: "include", Summary: "Standup"},
+		{Action: "exclude", Summary: "Standup"},
+	}, "include")
+	if err != nil {
+		t.Fatalf("NewRuleSet: %v", err)
+	}
+
+	d := rs.Evaluate(Event{Summary: "Daily Standup"})
+	if !d.Include || d.Rule != "rule 1" {
+		t.Errorf("expected first rule to win, got include %v rule %q", d.Include, d.Rule)
+	}
+	if len(d.Trace) != 1 {
+		t.Errorf("expected evaluation to stop after first match, trace has %d entries", len(d.Trace))
+	}
+}
+
+func TestRuleSet_NilIncludesEverything(t *testing.T) {
+	var rs *RuleSet
+	if d := rs.Evaluate(Event{Summary: "Anything"}); !d.Include {
+		t.Error("nil rule set should include every event")
+	}
+}
+
+func TestNewRuleSet_InvalidPattern(t *testing.T) {
+	_, err := NewRuleSet([]config.Rule{{Name: "broken", Action: "include", Location: "("}}, "include")
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("expected error naming the rule, got %v", err)
+	}
+}
+
+func TestExplainEvent(t *testing.T) {
+	rs, err := NewRuleSet([]config.Rule{
+		{Name: "no-lunch", Action: "exclude", Summary: "Lunch"},
+		{Name: "meet-only", Action: "include", HasConference: boolPtr(true)},
+	}, "exclude")
+	if err != nil {
+		t.Fatalf("NewRuleSet: %v", err)
+	}
+	opts := Options{
+		Attendance: AttendanceFilter{Statuses: []string{StatusAccepted}},
+		Rules:      rs,
+	}
+
+	var buf bytes.Buffer
+	ExplainEvent(&buf, Event{
+		ID:      "evt1",
+		Summary: "Design review",
+		Links:   []Link{{Kind: LinkVideo, URL: "https://meet.google.com/abc"}},
+		Start:   mustParseEventTime(t, "2025-03-15T10:00:00+05:30"),
+	}, opts)
+
+	out := buf.String()
+	for _, want := range []string{
+		"Design review (evt1)",
+		"no-lunch (exclude): summary does not match",
+		"meet-only (include): matched",
+		`Decision:   trigger (rule "meet-only")`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("explain output missing %q:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	ExplainEvent(&buf, Event{
+		ID:      "evt2",
+		Summary: "Offsite",
+		Start:   mustParseEventTime(t, "2025-03-15T10:00:00+05:30"),
+		Attendees: []Attendee{
+			{Self: true, ResponseStatus: StatusDeclined},
+		},
+	}, opts)
+	if !strings.Contains(buf.String(), "Attendance: skipped, response status is declined") {
+		t.Errorf("expected attendance skip in output:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,176 @@
+package calendar
+
+import (
+	"slices"
+	"sort"
+	"time"
+)
+
+// snoozeTriggerName names the one-off triggers added by `timeotter snooze`.
+const snoozeTriggerName = "snooze"
+
+// Lookup returns the trigger stored under key, whether a sync or a snooze
+// installed it.
+func (s TriggerState) Lookup(key string) (StoredTrigger, bool) {
+	if t, ok := s.Triggers[key]; ok {
+		return t, true
+	}
+	t, ok := s.Snoozed[key]
+	return t, ok
+}
+
+// Resolve finds the trigger ref refers to: a trigger key, or the ID of an
+// event behind some triggers. For an event it picks the last of its
+// triggers due by now, or else the next one.
+func (s TriggerState) Resolve(ref string, now time.Time) (string, StoredTrigger, bool) {
+	if t, ok := s.Lookup(ref); ok {
+		return ref, t, true
+	}
+
+	var keys []string
+	for _, triggers := range []map[string]StoredTrigger{s.Triggers, s.Snoozed} {
+		for key, t := range triggers {
+			if slices.Contains(t.eventIDs(), ref) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return "", StoredTrigger{}, false
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, _ := s.Lookup(keys[i])
+		b, _ := s.Lookup(keys[j])
+		return a.At.Before(b.At) || (a.At.Equal(b.At) && keys[i] < keys[j])
+	})
+	pick := keys[0]
+	for _, key := range keys {
+		if t, _ := s.Lookup(key); !t.At.After(now) {
+			pick = key
+		}
+	}
+	t, _ := s.Lookup(pick)
+	return pick, t, true
+}
+
+// Snooze adds a one-off trigger repeating t at at, rounded up to the
+// minute as cron runs it, and returns its key and the new trigger.
+func (s *TriggerState) Snooze(t StoredTrigger, at time.Time) (string, StoredTrigger) {
+	if truncated := at.Truncate(time.Minute); !truncated.Equal(at) {
+		at = truncated.Add(time.Minute)
+	}
+	t.Name = snoozeTriggerName
+	t.At = at
+	key := t.key()
+	if s.Snoozed == nil {
+		s.Snoozed = make(map[string]StoredTrigger)
+	}
+	s.Snoozed[key] = t
+	return key, t
+}
+
+// Ack records that the user has dealt with the event occurrences ids, so
+// their remaining triggers, snoozes included, do not fire. Each ack lasts
+// until the event and its last trigger are over.
+func (s *TriggerState) Ack(ids []string, now time.Time) {
+	if s.Acked == nil {
+		s.Acked = make(map[string]time.Time)
+	}
+	for _, id := range ids {
+		until := now
+		for _, triggers := range []map[string]StoredTrigger{s.Triggers, s.Snoozed} {
+			for _, t := range triggers {
+				for _, item := range t.Events {
+					if item.ID != id {
+						continue
+					}
+					until = latest(until, t.At, item.End)
+				}
+			}
+		}
+		s.Acked[id] = until.Add(time.Minute)
+	}
+	for key, t := range s.Snoozed {
+		if _, ok := s.Unacked(t, now); !ok {
+			delete(s.Snoozed, key)
+		}
+	}
+}
+
+// Unacked returns t without its acknowledged events. It reports false when
+// every event behind t was acknowledged and t should not fire.
+func (s TriggerState) Unacked(t StoredTrigger, now time.Time) (StoredTrigger, bool) {
+	if len(t.Events) == 0 {
+		return t, true
+	}
+	var events []Event
+	for _, item := range t.Events {
+		if until, ok := s.Acked[item.ID]; !ok || !now.Before(until) {
+			events = append(events, item)
+		}
+	}
+	if len(events) == 0 {
+		return t, false
+	}
+	if len(events) < len(t.Events) {
+		t.Events = events
+		t.Summary = events[0].Summary
+	}
+	return t, true
+}
+
+// ActiveAcks returns the acknowledged event IDs whose ack has not expired
+// by now, with their expiry.
+func (s TriggerState) ActiveAcks(now time.Time) map[string]time.Time {
+	active := make(map[string]time.Time)
+	for id, until := range s.Acked {
+		if now.Before(until) {
+			active[id] = until
+		}
+	}
+	return active
+}
+
+// Carry keeps the snoozes and acks of previous that are still pending at
+// now, so a sync does not undo them.
+func (s *TriggerState) Carry(previous TriggerState, now time.Time) {
+	for key, t := range previous.Snoozed {
+		if !t.At.After(now) {
+			continue
+		}
+		if s.Snoozed == nil {
+			s.Snoozed = make(map[string]StoredTrigger)
+		}
+		s.Snoozed[key] = t
+	}
+	if acked := previous.ActiveAcks(now); len(acked) > 0 {
+		s.Acked = acked
+	}
+}
+
+// key is the Trigger.Key of the stored trigger.
+func (t StoredTrigger) key() string {
+	trigger := Trigger{Name: t.Name, At: t.At, Cmd: t.Cmd}
+	for _, item := range t.Events {
+		trigger.Events = append(trigger.Events, EventRef{ID: item.ID, Summary: item.Summary})
+	}
+	return trigger.Key()
+}
+
+func (t StoredTrigger) eventIDs() []string {
+	ids := make([]string, 0, len(t.Events))
+	for _, item := range t.Events {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func latest(times ...time.Time) time.Time {
+	var last time.Time
+	for _, t := range times {
+		if t.After(last) {
+			last = t
+		}
+	}
+	return last
+}
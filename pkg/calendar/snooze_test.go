@@ -0,0 +1,113 @@
+package calendar
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/config"
+)
+
+// snoozeState is the state of a sync with "soon" and "start" triggers for a
+// standup and a review that start together and are coalesced.
+func snoozeState(t *testing.T) (TriggerState, []Event) {
+	t.Helper()
+	standup := timedEvent(t, "a", "Standup", "2025-03-15T10:00:00Z", "2025-03-15T10:15:00Z")
+	review := timedEvent(t, "b", "Review", "2025-03-15T10:00:00Z", "2025-03-15T11:00:00Z")
+	events := []Event{standup, review}
+	opts := Options{CmdToExec: "notify", Coalesce: true, TriggerPoints: []config.TriggerPoint{
+		{Name: "soon", Anchor: "start", OffsetMinutes: -5},
+		{Name: "start", Anchor: "start"},
+	}}
+	return NewTriggerState(BuildPlan(events, opts, planNow()), events, planNow()), events
+}
+
+func TestTriggerState_Snooze(t *testing.T) {
+	state, _ := snoozeState(t)
+	now := time.Date(2025, 3, 15, 9, 56, 20, 0, time.UTC)
+
+	key, trigger, ok := state.Resolve("a", now)
+	if !ok || trigger.Name != "soon" {
+		t.Fatalf("Resolve picked %s %+v, want the trigger that fired last", key, trigger)
+	}
+	if other, _, _ := state.Resolve(key, now); other != key {
+		t.Errorf("Resolve(%s) = %s", key, other)
+	}
+	if _, _, ok := state.Resolve("missing", now); ok {
+		t.Error("Resolve found a missing event")
+	}
+
+	snoozeKey, snoozed := state.Snooze(trigger, now.Add(5*time.Minute))
+	if want := time.Date(2025, 3, 15, 10, 2, 0, 0, time.UTC); !snoozed.At.Equal(want) || snoozed.Name != "snooze" || snoozed.Cmd != "notify" {
+		t.Errorf("unexpected snooze %+v, want one at %s", snoozed, want)
+	}
+	if got, ok := state.Lookup(snoozeKey); !ok || got.Summary != trigger.Summary || len(got.Events) != 2 {
+		t.Errorf("Lookup(%s) = %+v, %v", snoozeKey, got, ok)
+	}
+	if cmd := FireKeyCommand("/usr/bin/timeotter", snoozeKey); cmd != "'/usr/bin/timeotter' fire "+snoozeKey {
+		t.Errorf("FireKeyCommand = %q", cmd)
+	}
+
+	// A sync keeps pending snoozes and drops those that have fired.
+	next, _ := snoozeState(t)
+	next.Carry(state, now)
+	if _, ok := next.Snoozed[snoozeKey]; !ok {
+		t.Errorf("sync dropped the pending snooze: %+v", next.Snoozed)
+	}
+	later := TriggerState{}
+	later.Carry(state, snoozed.At)
+	if len(later.Snoozed) != 0 {
+		t.Errorf("sync kept a fired snooze: %+v", later.Snoozed)
+	}
+}
+
+func TestTriggerState_Ack(t *testing.T) {
+	state, events := snoozeState(t)
+	now := time.Date(2025, 3, 15, 9, 56, 0, 0, time.UTC)
+	_, trigger, _ := state.Resolve("a", now)
+	snoozeKey, _ := state.Snooze(trigger, now.Add(10*time.Minute))
+
+	state.Ack([]string{"a"}, now)
+	if until := state.Acked["a"]; !until.Equal(time.Date(2025, 3, 15, 10, 16, 0, 0, time.UTC)) {
+		t.Errorf("ack of a lasts until %s, want a minute after the event ends", until)
+	}
+	// The snooze still fires for the review, without the standup.
+	got, ok := state.Unacked(state.Snoozed[snoozeKey], now)
+	if !ok || len(got.Events) != 1 || got.Events[0].ID != "b" || got.Summary != "Review" {
+		t.Errorf("Unacked = %+v, %v", got, ok)
+	}
+
+	state.Ack([]string{"b"}, now)
+	if _, ok := state.Unacked(trigger, now); ok {
+		t.Error("a trigger for acknowledged events still fires")
+	}
+	if len(state.Snoozed) != 0 {
+		t.Errorf("acknowledged snoozes kept: %+v", state.Snoozed)
+	}
+	if _, ok := state.Unacked(trigger, state.Acked["b"]); !ok {
+		t.Error("ack did not expire")
+	}
+
+	// Acks survive a save and a sync, and BuildPlan skips the events.
+	path := filepath.Join(t.TempDir(), "triggers.json")
+	if err := state.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadTriggerState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var next TriggerState
+	next.Carry(loaded, now)
+	acked := next.ActiveAcks(now)
+	if len(acked) != 2 {
+		t.Fatalf("ActiveAcks = %v", acked)
+	}
+	plan := BuildPlan(events, Options{CmdToExec: "notify", TriggerBeforeMinutes: 1, Acked: acked}, now)
+	if len(plan.Triggers) != 0 || len(plan.Skipped) != 2 || plan.Skipped[0].Reason != "acknowledged with timeotter ack" {
+		t.Errorf("unexpected plan %+v", plan)
+	}
+	if len(next.ActiveAcks(now.Add(2*time.Hour))) != 0 {
+		t.Error("expired acks are still active")
+	}
+}
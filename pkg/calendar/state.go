@@ -0,0 +1,117 @@
+package calendar
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// TriggerState records the triggers installed by the last sync, with the
+// full events behind them, so `timeotter fire` can hand those to the
+// command it runs.
+type TriggerState struct {
+	SyncedAt time.Time `json:"syncedAt"`
+	// Triggers is keyed by Trigger.Key.
+	Triggers map[string]StoredTrigger `json:"triggers"`
+	// Snoozed holds the one-off triggers added by `timeotter snooze`,
+	// carried across syncs until they fire.
+	Snoozed map[string]StoredTrigger `json:"snoozed,omitempty"`
+	// Acked maps the IDs of event occurrences acknowledged with
+	// `timeotter ack` to when the ack expires.
+	Acked map[string]time.Time `json:"acked,omitempty"`
+}
+
+// StoredTrigger is an installed trigger as recorded in the state file.
+type StoredTrigger struct {
+	Name       string    `json:"name"`
+	Summary    string    `json:"summary"`
+	At         time.Time `json:"at"`
+	Cmd        string    `json:"cmd"`
+	JoinURL    string    `json:"joinUrl,omitempty"`
+	GapMinutes int       `json:"gapMinutes,omitempty"`
+	Events     []Event   `json:"events,omitempty"`
+}
+
+// Key identifies the trigger in the state file and in its crontab entry.
+// It is derived from the time, name, command and events of the trigger, so
+// it stays the same across syncs as long as those do.
+func (t Trigger) Key() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s", t.At.UTC().Format(time.RFC3339), t.Name, t.Cmd, strings.Join(t.EventIDs(), ","))
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// FireCommand returns the crontab command that runs the trigger through
+// `timeotter fire`, with bin the path of the timeotter executable.
+func (t Trigger) FireCommand(bin string) string {
+	return FireKeyCommand(bin, t.Key())
+}
+
+// FireKeyCommand returns the crontab command that fires the trigger stored
+// under key.
+func FireKeyCommand(bin, key string) string {
+	return cronQuote(bin) + " fire " + key
+}
+
+// NewTriggerState records the triggers of plan, looking up the events
+// behind each of them in events.
+func NewTriggerState(plan Plan, events []Event, now time.Time) TriggerState {
+	byID := make(map[string]Event, len(events))
+	for _, item := range events {
+		byID[item.ID] = item
+	}
+
+	state := TriggerState{SyncedAt: now, Triggers: make(map[string]StoredTrigger, len(plan.Triggers))}
+	for _, t := range plan.Triggers {
+		stored := StoredTrigger{
+			Name:       t.Name,
+			Summary:    t.summaries(),
+			At:         t.At,
+			Cmd:        t.Cmd,
+			JoinURL:    t.JoinURL,
+			GapMinutes: t.GapMinutes,
+		}
+		for _, id := range t.EventIDs() {
+			if item, ok := byID[id]; ok {
+				stored.Events = append(stored.Events, item)
+			}
+		}
+		state.Triggers[t.Key()] = stored
+	}
+	return state
+}
+
+// Save writes the state to path, replacing it atomically.
+func (s TriggerState) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadTriggerState reads the state written by the last sync.
+func LoadTriggerState(path string) (TriggerState, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return TriggerState{}, err
+	}
+	var state TriggerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return TriggerState{}, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return state, nil
+}
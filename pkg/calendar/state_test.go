@@ -0,0 +1,72 @@
+package calendar
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTriggerState(t *testing.T) {
+	standup := timedEvent(t, "a", "Standup", "2025-03-15T10:00:00+05:30", "2025-03-15T10:15:00+05:30")
+	standup.Calendar = "work@example.com"
+	standup.Attendees = []Attendee{{Email: "me@example.com", Self: true}, {Email: "lead@example.com"}}
+	sync := timedEvent(t, "b", "Sync", "2025-03-15T10:00:00+05:30", "2025-03-15T10:30:00+05:30")
+
+	plan := BuildPlan([]Event{standup, sync}, Options{CmdToExec: "notify", TriggerBeforeMinutes: 5, Coalesce: true}, planNow())
+	if len(plan.Triggers) != 1 {
+		t.Fatalf("expected one coalesced trigger, got %+v", plan.Triggers)
+	}
+	trigger := plan.Triggers[0]
+
+	key := trigger.Key()
+	if len(key) != 12 || key != trigger.Key() {
+		t.Errorf("unexpected key %q", key)
+	}
+	if cmd := trigger.FireCommand("/usr/bin/timeotter"); cmd != "'/usr/bin/timeotter' fire "+key {
+		t.Errorf("FireCommand = %q", cmd)
+	}
+
+	path := filepath.Join(t.TempDir(), "state", "triggers.json")
+	synced := time.Date(2025, 3, 15, 4, 0, 0, 0, time.UTC)
+	if err := NewTriggerState(plan, []Event{standup, sync}, synced).Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	state, err := LoadTriggerState(path)
+	if err != nil {
+		t.Fatalf("LoadTriggerState: %v", err)
+	}
+
+	stored, ok := state.Triggers[key]
+	if !ok {
+		t.Fatalf("trigger %s missing from %+v", key, state.Triggers)
+	}
+	if stored.Name != "start" || stored.Cmd != "notify" || !stored.At.Equal(trigger.At) || !state.SyncedAt.Equal(synced) {
+		t.Errorf("unexpected stored trigger %+v", stored)
+	}
+	if len(stored.Events) != 2 || stored.Events[0].Calendar != "work@example.com" || len(stored.Events[0].Attendees) != 2 {
+		t.Errorf("events not stored in full: %+v", stored.Events)
+	}
+	if stored.Events[0].Start.Format(time.RFC3339) != "2025-03-15T10:00:00+05:30" {
+		t.Errorf("start offset lost: %s", stored.Events[0].Start)
+	}
+
+	if _, err := LoadTriggerState(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected error for a missing state file")
+	}
+}
+
+func TestTrigger_KeyChanges(t *testing.T) {
+	base := Trigger{Name: "start", At: time.Date(2025, 3, 15, 9, 55, 0, 0, time.UTC), Cmd: "notify", Events: []EventRef{{ID: "a"}}}
+	keys := map[string]bool{base.Key(): true}
+	for _, changed := range []Trigger{
+		{Name: "prep", At: base.At, Cmd: base.Cmd, Events: base.Events},
+		{Name: base.Name, At: base.At.Add(time.Minute), Cmd: base.Cmd, Events: base.Events},
+		{Name: base.Name, At: base.At, Cmd: "other", Events: base.Events},
+		{Name: base.Name, At: base.At, Cmd: base.Cmd, Events: []EventRef{{ID: "b"}}},
+	} {
+		if keys[changed.Key()] {
+			t.Errorf("key of %+v collides", changed)
+		}
+		keys[changed.Key()] = true
+	}
+}
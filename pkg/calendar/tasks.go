@@ -0,0 +1,178 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/config"
+	"google.golang.org/api/tasks/v1"
+)
+
+// EventTypeTask is the Type of events converted from Google Tasks.
+const EventTypeTask = "task"
+
+// taskTriggerName is the trigger point name used for task due times.
+const taskTriggerName = "due"
+
+// TaskOptions controls the triggers of tasks, which use their own command
+// and lead time instead of the event trigger points.
+type TaskOptions struct {
+	// Cmd defaults to CmdToExec when empty.
+	Cmd           string
+	BeforeMinutes int
+}
+
+// TaskSource reads tasks with a due date from Google Tasks and presents
+// them as zero-length events of type EventTypeTask.
+type TaskSource struct {
+	Service *tasks.Service
+	// TaskLists restricts the source to these task list IDs; empty reads
+	// every list.
+	TaskLists []string
+	// DueTime is the "HH:MM" local time at which tasks that only carry a
+	// due date are due.
+	DueTime  string
+	Location *time.Location
+}
+
+// Events lists the open tasks due in the queried range, across the
+// configured task lists. Tasks that cannot be converted are logged and left
+// out.
+func (s *TaskSource) Events(ctx context.Context, q Query) ([]Event, error) {
+	lists, err := s.lists(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	for _, list := range lists {
+		// Due dates are stored at midnight UTC, so widen the range by a day
+		// and filter on the resolved due time instead.
+		call := s.Service.Tasks.List(list).Context(ctx).ShowCompleted(false).
+			DueMin(q.From.Add(-24 * time.Hour).UTC().Format(time.RFC3339))
+		if !q.To.IsZero() {
+			call = call.DueMax(q.To.Add(24 * time.Hour).UTC().Format(time.RFC3339))
+		}
+		err := call.Pages(ctx, func(page *tasks.Tasks) error {
+			for _, task := range page.Items {
+				event, ok, err := FromTask(task, s.DueTime, s.location())
+				if err != nil {
+					log.Printf("Skipping task %q: %v", task.Title, err)
+					continue
+				}
+				if !ok || !event.Start.After(q.From) || (!q.To.IsZero() && !event.Start.Before(q.To)) {
+					continue
+				}
+				event.Calendar = list
+				events = append(events, event)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("listing tasks of %s: %w", list, err)
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+	if q.MaxResults > 0 && int64(len(events)) > q.MaxResults {
+		events = events[:q.MaxResults]
+	}
+	return events, nil
+}
+
+// Event fetches a single task by ID, looking through the configured lists.
+func (s *TaskSource) Event(ctx context.Context, id string) (Event, error) {
+	lists, err := s.lists(ctx)
+	if err != nil {
+		return Event{}, err
+	}
+	for _, list := range lists {
+		task, err := s.Service.Tasks.Get(list, id).Context(ctx).Do()
+		if err != nil {
+			continue
+		}
+		event, ok, err := FromTask(task, s.DueTime, s.location())
+		if err != nil {
+			return Event{}, err
+		}
+		if !ok {
+			return Event{}, fmt.Errorf("task %q is completed or has no due date", task.Title)
+		}
+		event.Calendar = list
+		return event, nil
+	}
+	return Event{}, fmt.Errorf("task %s not found", id)
+}
+
+// lists returns the configured task list IDs, or every list of the user.
+func (s *TaskSource) lists(ctx context.Context) ([]string, error) {
+	if len(s.TaskLists) > 0 {
+		return s.TaskLists, nil
+	}
+	var ids []string
+	err := s.Service.Tasklists.List().Context(ctx).Pages(ctx, func(page *tasks.TaskLists) error {
+		for _, list := range page.Items {
+			ids = append(ids, list.Id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing task lists: %w", err)
+	}
+	return ids, nil
+}
+
+func (s *TaskSource) location() *time.Location {
+	if s.Location == nil {
+		return time.Local
+	}
+	return s.Location
+}
+
+// FromTask converts a Google Task. The second result is false for tasks
+// that should not trigger: completed, deleted or hidden tasks and tasks
+// without a due date.
+//
+// The Tasks API reports the due date as midnight UTC and drops any time of
+// day, so such tasks are due at dueTime on that date in loc. A due
+// timestamp with a time of day is used as is.
+func FromTask(task *tasks.Task, dueTime string, loc *time.Location) (Event, bool, error) {
+	if task.Status == "completed" || task.Completed != nil || task.Deleted || task.Hidden || task.Due == "" {
+		return Event{}, false, nil
+	}
+
+	due, err := time.Parse(time.RFC3339, task.Due)
+	if err != nil {
+		return Event{}, false, fmt.Errorf("parsing due %q: %w", task.Due, err)
+	}
+	if due.Equal(due.Truncate(24 * time.Hour)) {
+		hour, minute, err := config.ParseClock(dueTime)
+		if err != nil {
+			return Event{}, false, fmt.Errorf("invalid due time: %w", err)
+		}
+		due = time.Date(due.Year(), due.Month(), due.Day(), hour, minute, 0, 0, loc)
+	}
+
+	return Event{
+		ID:          task.Id,
+		Summary:     task.Title,
+		Description: task.Notes,
+		Status:      "confirmed",
+		Type:        EventTypeTask,
+		Start:       due,
+		End:         due,
+	}, true, nil
+}
+
+// taskPoint is the single trigger point of a task.
+func (opts Options) taskPoint() config.TriggerPoint {
+	return config.TriggerPoint{
+		Name:          taskTriggerName,
+		Anchor:        config.AnchorStart,
+		OffsetMinutes: -opts.Tasks.BeforeMinutes,
+		Cmd:           opts.Tasks.Cmd,
+	}
+}
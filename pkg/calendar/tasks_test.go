@@ -0,0 +1,118 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/tasks/v1"
+)
+
+func TestFromTask(t *testing.T) {
+	loc := mustLoadLocation(t, "Asia/Kolkata")
+	done := "2025-03-14T10:00:00.000Z"
+
+	tests := []struct {
+		name   string
+		task   tasks.Task
+		wantOK bool
+		want   string
+	}{
+		{"date only uses the due time", tasks.Task{Id: "t1", Title: "File taxes", Due: "2025-03-15T00:00:00.000Z", Status: "needsAction"}, true, "2025-03-15T17:30:00+05:30"},
+		{"explicit time is kept", tasks.Task{Id: "t2", Title: "Submit", Due: "2025-03-15T11:45:00Z"}, true, "2025-03-15T11:45:00Z"},
+		{"completed", tasks.Task{Id: "t3", Due: "2025-03-15T00:00:00.000Z", Status: "completed"}, false, ""},
+		{"completed timestamp", tasks.Task{Id: "t4", Due: "2025-03-15T00:00:00.000Z", Completed: &done}, false, ""},
+		{"deleted", tasks.Task{Id: "t5", Due: "2025-03-15T00:00:00.000Z", Deleted: true}, false, ""},
+		{"no due date", tasks.Task{Id: "t6", Title: "Someday"}, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := tt.task
+			event, ok, err := FromTask(&task, "17:30", loc)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if event.Start.Format(time.RFC3339) != tt.want || !event.End.Equal(event.Start) || event.Type != EventTypeTask {
+				t.Errorf("unexpected event %+v", event)
+			}
+		})
+	}
+
+	if _, _, err := FromTask(&tasks.Task{Due: "soon"}, "09:00", loc); err == nil {
+		t.Error("expected error for malformed due date")
+	}
+}
+
+func TestTaskSource_Events(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body any
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/users/@me/lists"):
+			body = tasks.TaskLists{Items: []*tasks.TaskList{{Id: "work"}, {Id: "home"}}}
+		case strings.HasSuffix(r.URL.Path, "/lists/work/tasks"):
+			if r.URL.Query().Get("showCompleted") != "false" || r.URL.Query().Get("dueMin") == "" {
+				t.Errorf("unexpected query %s", r.URL.RawQuery)
+			}
+			body = tasks.Tasks{Items: []*tasks.Task{
+				{Id: "late", Title: "Report", Due: "2025-03-18T00:00:00.000Z"},
+				{Id: "past", Title: "Yesterday", Due: "2025-03-16T00:00:00.000Z"},
+			}}
+		case strings.HasSuffix(r.URL.Path, "/lists/home/tasks"):
+			body = tasks.Tasks{Items: []*tasks.Task{{Id: "soon", Title: "Rent", Due: "2025-03-17T00:00:00.000Z"}}}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+	defer server.Close()
+
+	srv, err := tasks.NewService(context.Background(), option.WithEndpoint(server.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &TaskSource{Service: srv, DueTime: "09:00", Location: time.UTC}
+
+	now := time.Date(2025, 3, 17, 8, 0, 0, 0, time.UTC)
+	events, err := src.Events(context.Background(), Query{From: now, To: now.AddDate(0, 0, 7)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 2 || events[0].ID != "soon" || events[1].ID != "late" {
+		t.Fatalf("expected the two open tasks in due order, got %+v", events)
+	}
+}
+
+func TestBuildPlan_Tasks(t *testing.T) {
+	task := Event{ID: "t1", Summary: "File taxes", Type: EventTypeTask,
+		Start: time.Date(2025, 3, 15, 17, 0, 0, 0, time.UTC), End: time.Date(2025, 3, 15, 17, 0, 0, 0, time.UTC)}
+	meeting := timedEvent(t, "m1", "Sync", "2025-03-15T10:00:00Z", "2025-03-15T11:00:00Z")
+
+	opts := Options{CmdToExec: "notify", TriggerBeforeMinutes: 5, Tasks: TaskOptions{Cmd: "deadline", BeforeMinutes: 60}}
+	plan := BuildPlan([]Event{task, meeting}, opts, planNow())
+	if len(plan.Triggers) != 2 {
+		t.Fatalf("expected two triggers, got %+v", plan.Triggers)
+	}
+	got := plan.Triggers[1]
+	if got.EventID != "t1" || got.Name != "due" || got.Cmd != "deadline" || got.At.Format("15:04") != "16:00" {
+		t.Errorf("unexpected task trigger %+v", got)
+	}
+
+	opts.Tasks.Cmd = ""
+	plan = BuildPlan([]Event{task}, opts, planNow())
+	if len(plan.Triggers) != 1 || plan.Triggers[0].Cmd != "notify" {
+		t.Errorf("expected task to fall back to CmdToExec, got %+v", plan.Triggers)
+	}
+}
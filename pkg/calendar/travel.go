@@ -0,0 +1,56 @@
+package calendar
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/bupd/timeotter/pkg/config"
+)
+
+// travelTriggerName is the trigger point name of the "leave now" trigger.
+const travelTriggerName = "leave"
+
+// TravelTimes maps event locations to the time needed to get there. The
+// first entry whose pattern matches an event's location applies.
+type TravelTimes struct {
+	entries []travelEntry
+}
+
+type travelEntry struct {
+	location *regexp.Regexp
+	minutes  int
+	cmd      string
+}
+
+// NewTravelTimes compiles the configured travel entries.
+func NewTravelTimes(travel []config.Travel) (*TravelTimes, error) {
+	tt := &TravelTimes{}
+	for i, t := range travel {
+		re, err := regexp.Compile(t.Location)
+		if err != nil {
+			return nil, fmt.Errorf("travel entry %d: invalid Location pattern: %w", i+1, err)
+		}
+		tt.entries = append(tt.entries, travelEntry{location: re, minutes: t.Minutes, cmd: t.Cmd})
+	}
+	return tt, nil
+}
+
+// Point returns the "leave" trigger point for item, firing the travel time
+// before its start. Events without a location, or whose location matches
+// no entry, have none.
+func (tt *TravelTimes) Point(item Event) (config.TriggerPoint, bool) {
+	if tt == nil || item.Location == "" {
+		return config.TriggerPoint{}, false
+	}
+	for _, e := range tt.entries {
+		if e.location.MatchString(item.Location) {
+			return config.TriggerPoint{
+				Name:          travelTriggerName,
+				Anchor:        config.AnchorStart,
+				OffsetMinutes: -e.minutes,
+				Cmd:           e.cmd,
+			}, true
+		}
+	}
+	return config.TriggerPoint{}, false
+}
@@ -0,0 +1,75 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/config"
+)
+
+func TestBuildPlan_Travel(t *testing.T) {
+	travel, err := NewTravelTimes([]config.Travel{
+		{Location: `Building B`, Minutes: 10},
+		{Location: `(?i)\bHQ\b|Main St`, Minutes: 30, Cmd: "notify-send 'Leave now'"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hq := timedEvent(t, "a", "Offsite", "2025-03-15T10:00:00Z", "2025-03-15T11:00:00Z")
+	hq.Location = "Company hq, 1 Main St"
+	building := timedEvent(t, "b", "1:1", "2025-03-15T12:00:00Z", "2025-03-15T12:30:00Z")
+	building.Location = "Building B, room 4"
+	remote := timedEvent(t, "c", "Call", "2025-03-15T14:00:00Z", "2025-03-15T14:30:00Z")
+	elsewhere := timedEvent(t, "d", "Lunch", "2025-03-15T16:00:00Z", "2025-03-15T17:00:00Z")
+	elsewhere.Location = "Cafe"
+
+	opts := Options{CmdToExec: "notify", TriggerBeforeMinutes: 5, Travel: travel}
+	plan := BuildPlan([]Event{hq, building, remote, elsewhere}, opts, planNow())
+
+	var got []string
+	for _, trigger := range plan.Triggers {
+		got = append(got, trigger.At.Format("15:04")+" "+trigger.Name+" "+trigger.Cmd)
+	}
+	want := []string{
+		"09:30 leave notify-send 'Leave now'",
+		"09:55 start notify",
+		"11:50 leave notify",
+		"11:55 start notify",
+		"13:55 start notify",
+		"15:55 start notify",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestBuildPlan_TravelWhileBusy(t *testing.T) {
+	travel, err := NewTravelTimes([]config.Travel{{Location: "HQ", Minutes: 20}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	standup := timedEvent(t, "a", "Standup", "2025-03-15T09:00:00Z", "2025-03-15T09:58:00Z")
+	offsite := timedEvent(t, "b", "Offsite", "2025-03-15T10:00:00Z", "2025-03-15T11:00:00Z")
+	offsite.Location = "HQ"
+
+	opts := Options{CmdToExec: "notify", TriggerBeforeMinutes: 5, Travel: travel, BackToBackGap: 10 * time.Minute}
+	plan := BuildPlan([]Event{standup, offsite}, opts, planNow())
+
+	var names []string
+	for _, trigger := range plan.Triggers {
+		if trigger.EventID == "b" {
+			names = append(names, trigger.Name)
+		}
+	}
+	if strings.Join(names, ",") != "leave" {
+		t.Errorf("expected only the leave trigger to survive the back-to-back check, got %v", names)
+	}
+}
+
+func TestNewTravelTimes_InvalidPattern(t *testing.T) {
+	if _, err := NewTravelTimes([]config.Travel{{Location: "(", Minutes: 5}}); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
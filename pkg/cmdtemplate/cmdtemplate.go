@@ -0,0 +1,263 @@
+// Package cmdtemplate renders shell commands from text/template templates.
+//
+// Every value a template inserts is escaped for the shell quoting context
+// it appears in, so event details can never break out of their argument:
+// unquoted values are single-quoted, and values inside single or double
+// quotes are escaped for those quotes. The raw function opts out, and so
+// does shellquote outside quotes, where its output is already one word.
+package cmdtemplate
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+// Names of the escaping functions appended to every action.
+const (
+	escapeUnquoted = "_shellArg"
+	escapeSingle   = "_shellSingle"
+	escapeDouble   = "_shellDouble"
+)
+
+// Template is a parsed command template.
+type Template struct {
+	tmpl *template.Template
+}
+
+// Funcs are the helper functions available to command templates.
+var Funcs = template.FuncMap{
+	"fmtTime":    func(t time.Time) string { return t.Format("15:04") },
+	"fmtDate":    func(t time.Time) string { return t.Format("2006-01-02") },
+	"formatTime": func(layout string, t time.Time) string { return t.Format(layout) },
+	"truncate":   truncate,
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"join":       func(sep string, items []string) string { return strings.Join(items, sep) },
+	"shellquote": func(v any) string { return quoteArg(fmt.Sprint(v)) },
+	// raw inserts its argument without escaping.
+	"raw": func(v any) string { return fmt.Sprint(v) },
+}
+
+// unescaped lists the functions whose output is inserted as is when they
+// end a pipeline in the given quoting context. Inside quotes the output of
+// shellquote is escaped like any other value, as its quotes would close
+// the surrounding ones.
+var unescaped = map[string]func(quoteContext) bool{
+	"raw":        func(quoteContext) bool { return true },
+	"shellquote": func(ctx quoteContext) bool { return ctx == unquoted },
+}
+
+// Parse parses a command template and sets up escaping for its actions.
+func Parse(name, text string) (*Template, error) {
+	funcs := template.FuncMap{
+		escapeUnquoted: func(v any) string { return quoteArg(fmt.Sprint(v)) },
+		escapeSingle:   func(v any) string { return escapeInSingle(fmt.Sprint(v)) },
+		escapeDouble:   func(v any) string { return escapeInDouble(fmt.Sprint(v)) },
+	}
+	for k, v := range Funcs {
+		funcs[k] = v
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(tmpl.Templates()) > 1 {
+		return nil, fmt.Errorf("template: %s: {{define}} is not supported in commands", name)
+	}
+	if tmpl.Tree == nil || tmpl.Tree.Root == nil {
+		return &Template{tmpl: tmpl}, nil
+	}
+
+	e := escaper{tree: tmpl.Tree}
+	end, err := e.list(tmpl.Tree.Root, unquoted)
+	if err != nil {
+		return nil, fmt.Errorf("template: %s: %w", name, err)
+	}
+	if end != unquoted {
+		return nil, fmt.Errorf("template: %s: unterminated %s quote", name, end)
+	}
+	return &Template{tmpl: tmpl}, nil
+}
+
+// ParseText parses a template for plain text, such as a notification body,
+// with the same functions as commands but without shell escaping.
+func ParseText(name, text string) (*Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(Funcs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(tmpl.Templates()) > 1 {
+		return nil, fmt.Errorf("template: %s: {{define}} is not supported", name)
+	}
+	return &Template{tmpl: tmpl}, nil
+}
+
+// Execute renders the command for data.
+func (t *Template) Execute(data any) (string, error) {
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// quoteContext is the shell quoting state at a point in the command.
+type quoteContext int
+
+const (
+	unquoted quoteContext = iota
+	singleQuoted
+	doubleQuoted
+)
+
+func (c quoteContext) String() string {
+	switch c {
+	case singleQuoted:
+		return "single"
+	case doubleQuoted:
+		return "double"
+	default:
+		return "no"
+	}
+}
+
+// escaper walks a template tree in output order, tracking the quoting
+// context of the literal text and appending the matching escaping
+// function to each action.
+type escaper struct {
+	tree *parse.Tree
+}
+
+func (e escaper) list(list *parse.ListNode, ctx quoteContext) (quoteContext, error) {
+	if list == nil {
+		return ctx, nil
+	}
+	for _, node := range list.Nodes {
+		var err error
+		switch n := node.(type) {
+		case *parse.TextNode:
+			ctx = scan(ctx, n.Text)
+		case *parse.ActionNode:
+			e.escape(n.Pipe, ctx)
+		case *parse.IfNode:
+			err = e.branch("if", &n.BranchNode, ctx)
+		case *parse.RangeNode:
+			err = e.branch("range", &n.BranchNode, ctx)
+		case *parse.WithNode:
+			err = e.branch("with", &n.BranchNode, ctx)
+		case *parse.TemplateNode:
+			err = fmt.Errorf("{{template}} is not supported in commands")
+		}
+		if err != nil {
+			return ctx, err
+		}
+	}
+	return ctx, nil
+}
+
+// branch escapes the bodies of an if, range or with action, which must
+// leave the quoting context as they found it.
+func (e escaper) branch(name string, n *parse.BranchNode, ctx quoteContext) error {
+	for _, body := range []*parse.ListNode{n.List, n.ElseList} {
+		end, err := e.list(body, ctx)
+		if err != nil {
+			return err
+		}
+		if end != ctx {
+			return fmt.Errorf("quotes opened inside {{%s}} must be closed there", name)
+		}
+	}
+	return nil
+}
+
+// escape appends the escaping function for ctx to an output pipeline.
+func (e escaper) escape(pipe *parse.PipeNode, ctx quoteContext) {
+	if len(pipe.Decl) > 0 || len(pipe.Cmds) == 0 {
+		return
+	}
+	last := pipe.Cmds[len(pipe.Cmds)-1]
+	if ident, ok := last.Args[0].(*parse.IdentifierNode); ok {
+		if skip, ok := unescaped[ident.Ident]; ok && skip(ctx) {
+			return
+		}
+	}
+
+	name := escapeUnquoted
+	switch ctx {
+	case singleQuoted:
+		name = escapeSingle
+	case doubleQuoted:
+		name = escapeDouble
+	}
+	ident := parse.NewIdentifier(name).SetTree(e.tree).SetPos(pipe.Pos)
+	pipe.Cmds = append(pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: pipe.Pos, Args: []parse.Node{ident}})
+}
+
+// scan returns the quoting context after the shell text s, starting in ctx.
+func scan(ctx quoteContext, s []byte) quoteContext {
+	for i := 0; i < len(s); i++ {
+		switch ctx {
+		case unquoted:
+			switch s[i] {
+			case '\\':
+				i++
+			case '\'':
+				ctx = singleQuoted
+			case '"':
+				ctx = doubleQuoted
+			}
+		case singleQuoted:
+			if s[i] == '\'' {
+				ctx = unquoted
+			}
+		case doubleQuoted:
+			switch s[i] {
+			case '\\':
+				i++
+			case '"':
+				ctx = unquoted
+			}
+		}
+	}
+	return ctx
+}
+
+// quoteArg single-quotes s as one shell word.
+func quoteArg(s string) string {
+	return "'" + escapeInSingle(s) + "'"
+}
+
+// escapeInSingle escapes s for use between single quotes.
+func escapeInSingle(s string) string {
+	return strings.ReplaceAll(s, "'", `'\''`)
+}
+
+// escapeInDouble escapes s for use between double quotes.
+func escapeInDouble(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\', '"', '$', '`':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// truncate shortens s to at most n runes, ending it with an ellipsis when
+// anything was cut.
+func truncate(n int, s string) string {
+	runes := []rune(s)
+	if n <= 0 || len(runes) <= n {
+		return s
+	}
+	if n == 1 {
+		return "…"
+	}
+	return string(runes[:n-1]) + "…"
+}
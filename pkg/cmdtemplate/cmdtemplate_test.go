@@ -0,0 +1,209 @@
+package cmdtemplate
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testData struct {
+	Summary string
+	Start   time.Time
+	Emails  []string
+	Count   int
+}
+
+func sample() testData {
+	return testData{
+		Summary: "Standup",
+		Start:   time.Date(2025, 3, 15, 9, 30, 0, 0, time.UTC),
+		Emails:  []string{"a@example.com", "b@example.com"},
+		Count:   3,
+	}
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"no actions", `notify-send "Meeting"`, `notify-send "Meeting"`},
+		{"unquoted", `notify-send {{.Summary}}`, `notify-send 'Standup'`},
+		{"double quoted", `notify-send "{{.Summary}}" "starts {{.Start | fmtTime}}"`, `notify-send "Standup" "starts 09:30"`},
+		{"single quoted", `echo '{{.Summary}} at {{fmtTime .Start}}'`, `echo 'Standup at 09:30'`},
+		{"escaped quote is literal", `echo \"{{.Summary}}`, `echo \"'Standup'`},
+		{"quote inside other quotes", `echo "it's {{.Summary}}"`, `echo "it's Standup"`},
+		{"fmtDate", `echo {{.Start | fmtDate}}`, `echo '2025-03-15'`},
+		{"formatTime", `echo "{{formatTime "Mon 15:04" .Start}}"`, `echo "Sat 09:30"`},
+		{"truncate", `echo "{{truncate 4 .Summary}}"`, `echo "Sta…"`},
+		{"upper", `echo {{upper .Summary}}`, `echo 'STANDUP'`},
+		{"join", `echo {{join "," .Emails}}`, `echo 'a@example.com,b@example.com'`},
+		{"range", `mail{{range .Emails}} {{.}}{{end}}`, `mail 'a@example.com' 'b@example.com'`},
+		{"if", `echo{{if .Count}} "{{.Count}} left"{{end}}`, `echo "3 left"`},
+		{"variable", `{{$s := .Summary}}echo {{$s}}`, `echo 'Standup'`},
+		{"shellquote", `echo {{shellquote .Summary}}`, `echo 'Standup'`},
+		{"shellquote pipeline", `echo {{.Summary | shellquote}}`, `echo 'Standup'`},
+		{"shellquote in double quotes", `echo "{{.Summary | shellquote}}"`, `echo "'Standup'"`},
+		{"shellquote in single quotes", `echo '{{shellquote .Summary}}'`, `echo ''\''Standup'\'''`},
+		{"raw", `echo {{raw "$HOME"}}`, `echo $HOME`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Parse(tt.name, tt.text)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			got, err := tmpl.Execute(sample())
+			if err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestExecute_Injection runs the rendered commands through sh and checks
+// that hostile summaries come out as literal text in every context.
+func TestExecute_Injection(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	summaries := []string{
+		`'; touch pwned; echo '`,
+		`"; touch pwned; echo "`,
+		"$(touch pwned)",
+		"`touch pwned`",
+		`\"; touch pwned #`,
+		"a\nb",
+		"${HOME}",
+	}
+	templates := []string{
+		`printf %s {{.Summary}}`,
+		`printf %s "{{.Summary}}"`,
+		`printf %s '{{.Summary}}'`,
+		`printf %s "pre {{.Summary}} post"`,
+		`printf %s x{{.Summary}}y`,
+	}
+	for _, text := range templates {
+		tmpl, err := Parse("test", text)
+		if err != nil {
+			t.Fatalf("Parse(%s): %v", text, err)
+		}
+		for _, summary := range summaries {
+			cmd, err := tmpl.Execute(testData{Summary: summary})
+			if err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			dir := t.TempDir()
+			c := exec.Command("sh", "-c", cmd)
+			c.Dir = dir
+			out, err := c.Output()
+			if err != nil {
+				t.Fatalf("%s: %v", cmd, err)
+			}
+			want := strings.NewReplacer(`"`, "", "'", "", "{{.Summary}}", summary).Replace(strings.TrimPrefix(text, "printf %s "))
+			if string(out) != want {
+				t.Errorf("%s printed %q, want %q", cmd, out, want)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "pwned")); err == nil {
+				t.Errorf("%s ran an injected command", cmd)
+			}
+		}
+	}
+}
+
+// TestExecute_ShellquoteInjection checks that shellquote cannot break out
+// of the quotes around it: inside them its output is escaped again and
+// printed with its quotes.
+func TestExecute_ShellquoteInjection(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	summary := `a'; touch pwned; echo '$(touch pwned)`
+	quoted := `'a'\''; touch pwned; echo '\''$(touch pwned)'`
+	tests := map[string]string{
+		`printf %s {{shellquote .Summary}}`:     summary,
+		`printf %s "{{.Summary | shellquote}}"`: quoted,
+		`printf %s '{{shellquote .Summary}}'`:   quoted,
+	}
+	for text, want := range tests {
+		tmpl, err := Parse("test", text)
+		if err != nil {
+			t.Fatalf("Parse(%s): %v", text, err)
+		}
+		cmd, err := tmpl.Execute(testData{Summary: summary})
+		if err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+		dir := t.TempDir()
+		c := exec.Command("sh", "-c", cmd)
+		c.Dir = dir
+		out, err := c.Output()
+		if err != nil {
+			t.Fatalf("%s: %v", cmd, err)
+		}
+		if string(out) != want {
+			t.Errorf("%s printed %q, want %q", cmd, out, want)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "pwned")); err == nil {
+			t.Errorf("%s ran an injected command", cmd)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"syntax", `echo {{.Summary`, "unclosed action"},
+		{"unknown function", `echo {{shout .Summary}}`, `function "shout" not defined`},
+		{"unterminated quote", `echo "{{.Summary}}`, "unterminated double quote"},
+		{"quote opened in if", `echo {{if .Count}}"{{end}}x"`, "must be closed there"},
+		{"define", `{{define "x"}}y{{end}}echo`, "{{define}} is not supported"},
+		{"template", `echo {{template "x"}}`, "{{template}} is not supported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.name, tt.text)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecute_UnknownField(t *testing.T) {
+	tmpl, err := Parse("test", `echo {{.Title}}`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if _, err := tmpl.Execute(sample()); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+}
+
+func TestParseText(t *testing.T) {
+	tmpl, err := ParseText("body", `{{.Summary}} at {{fmtTime .Start}}: "it's {{.Count}}" {{join ", " .Emails}}`)
+	if err != nil {
+		t.Fatalf("ParseText: %v", err)
+	}
+	got, err := tmpl.Execute(sample())
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if want := `Standup at 09:30: "it's 3" a@example.com, b@example.com`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := ParseText("body", `{{define "x"}}y{{end}}`); err == nil {
+		t.Error("expected an error for {{define}}")
+	}
+}
@@ -4,9 +4,14 @@ package config
 import (
 	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/bupd/timeotter/pkg/cmdtemplate"
 	"github.com/spf13/viper"
 )
 
@@ -21,6 +26,338 @@ type Config struct {
 	TriggerBeforeMinutes int    `mapstructure:"TriggerBeforeMinutes"`
 	CronMarker           string `mapstructure:"CronMarker"`
 	ShowDeleted          bool   `mapstructure:"ShowDeleted"`
+
+	AttendanceStatuses []string `mapstructure:"AttendanceStatuses"`
+	SkipFreeEvents     bool     `mapstructure:"SkipFreeEvents"`
+
+	Rules       []Rule `mapstructure:"Rules"`
+	RuleDefault string `mapstructure:"RuleDefault"`
+
+	AllDayPolicy      string `mapstructure:"AllDayPolicy"`
+	AllDayTime        string `mapstructure:"AllDayTime"`
+	AllDayEveningTime string `mapstructure:"AllDayEveningTime"`
+
+	Triggers []TriggerPoint `mapstructure:"Triggers"`
+
+	TriggerMode             string   `mapstructure:"TriggerMode"`
+	ReminderMethods         []string `mapstructure:"ReminderMethods"`
+	ReminderFallbackMinutes []int    `mapstructure:"ReminderFallbackMinutes"`
+
+	Hours         Hours           `mapstructure:"Hours"`
+	CalendarHours []CalendarHours `mapstructure:"CalendarHours"`
+
+	DetectOutOfOffice bool     `mapstructure:"DetectOutOfOffice"`
+	HolidayCalendars  []string `mapstructure:"HolidayCalendars"`
+	AwayAction        string   `mapstructure:"AwayAction"`
+	AwayCmd           string   `mapstructure:"AwayCmd"`
+
+	Coalesce             bool `mapstructure:"Coalesce"`
+	BackToBackGapMinutes int  `mapstructure:"BackToBackGapMinutes"`
+
+	JoinOpener string `mapstructure:"JoinOpener"`
+
+	Source string `mapstructure:"Source"`
+
+	CacheFile        string `mapstructure:"CacheFile"`
+	MaxCacheAgeHours int    `mapstructure:"MaxCacheAgeHours"`
+	StateFile        string `mapstructure:"StateFile"`
+
+	Tasks             bool     `mapstructure:"Tasks"`
+	TaskLists         []string `mapstructure:"TaskLists"`
+	TaskCmd           string   `mapstructure:"TaskCmd"`
+	TaskBeforeMinutes int      `mapstructure:"TaskBeforeMinutes"`
+	TaskDueTime       string   `mapstructure:"TaskDueTime"`
+
+	FreeSlotMinutes   int      `mapstructure:"FreeSlotMinutes"`
+	FreeSlotCmd       string   `mapstructure:"FreeSlotCmd"`
+	FreeBusyCalendars []string `mapstructure:"FreeBusyCalendars"`
+
+	Travel []Travel `mapstructure:"Travel"`
+
+	Routes    []Route `mapstructure:"Routes"`
+	RouteMode string  `mapstructure:"RouteMode"`
+
+	// Directives enables per-event overrides written in event descriptions
+	// and extended properties. Their cmd= selects from NamedCommands.
+	Directives    bool              `mapstructure:"Directives"`
+	NamedCommands map[string]string `mapstructure:"NamedCommands"`
+
+	Exec        Exec          `mapstructure:"Exec"`
+	CommandExec []CommandExec `mapstructure:"CommandExec"`
+	LogMaxKB    int           `mapstructure:"LogMaxKB"`
+	LogBackups  int           `mapstructure:"LogBackups"`
+
+	HistoryFile          string `mapstructure:"HistoryFile"`
+	HistoryRetentionDays int    `mapstructure:"HistoryRetentionDays"`
+
+	// Actions are built-in alternatives to shell commands, run by writing
+	// "action:<Name>" wherever a command is expected.
+	Actions []Action `mapstructure:"Actions"`
+}
+
+// Exec controls how `timeotter fire` runs commands. Empty fields inherit
+// from Exec when set in a CommandExec override.
+type Exec struct {
+	// Timeout and KillGrace are Go durations, e.g. "5m". After Timeout the
+	// command gets SIGTERM, and KillGrace later SIGKILL. "0" disables the
+	// timeout.
+	Timeout   string `mapstructure:"Timeout"`
+	KillGrace string `mapstructure:"KillGrace"`
+	Dir       string `mapstructure:"Dir"`
+	// Env adds KEY=VALUE variables to the environment cron provides.
+	Env []string `mapstructure:"Env"`
+	// LogFile receives the output and exit status of every run; "-" leaves
+	// the output to cron.
+	LogFile string `mapstructure:"LogFile"`
+}
+
+// CommandExec replaces Exec settings for the commands matching a pattern.
+type CommandExec struct {
+	// Match is a regular expression matched against the configured command.
+	Match string `mapstructure:"Match"`
+	Exec  `mapstructure:",squash"`
+}
+
+// Action is a built-in action that a command of the form "action:<Name>"
+// runs instead of sh.
+type Action struct {
+	Name string `mapstructure:"Name"`
+	// Type is what the action does: notify shows a desktop notification,
+	// webhook POSTs the trigger's JSON payload to URL and mqtt publishes it
+	// to Broker.
+	Type string `mapstructure:"Type"`
+
+	// Summary and Body are text templates with the same data and
+	// functions as commands, without shell escaping.
+	Summary string `mapstructure:"Summary"`
+	Body    string `mapstructure:"Body"`
+	// Urgency is low, normal or critical.
+	Urgency string `mapstructure:"Urgency"`
+	// Icon is an icon name from the desktop theme or an image path.
+	Icon    string `mapstructure:"Icon"`
+	AppName string `mapstructure:"AppName"`
+	// Timeout is a Go duration. For notify it is how long the notification
+	// stays up: "0" keeps it until dismissed and empty leaves it to the
+	// desktop. For webhook it bounds each request and for mqtt connecting
+	// and publishing.
+	Timeout string   `mapstructure:"Timeout"`
+	Buttons []Button `mapstructure:"Buttons"`
+	// ButtonWait is how long fire waits for a button to be clicked.
+	ButtonWait string `mapstructure:"ButtonWait"`
+	// Bus is the session bus address; empty finds the user's session bus
+	// even when run from cron.
+	Bus string `mapstructure:"Bus"`
+
+	URL     string            `mapstructure:"URL"`
+	Headers map[string]string `mapstructure:"Headers"`
+	// Secret signs the request body with HMAC-SHA256.
+	Secret string `mapstructure:"Secret"`
+	// Retries is how often a failed request is repeated, after Backoff and
+	// then twice as long each time.
+	Retries *int   `mapstructure:"Retries"`
+	Backoff string `mapstructure:"Backoff"`
+
+	// Broker is the MQTT broker URL, e.g. tcp://host:1883 or
+	// ssl://host:8883.
+	Broker string `mapstructure:"Broker"`
+	// Topic is a text template for the topic published to.
+	Topic  string `mapstructure:"Topic"`
+	QoS    int    `mapstructure:"QoS"`
+	Retain bool   `mapstructure:"Retain"`
+	// Username and Password authenticate to the broker.
+	Username string `mapstructure:"Username"`
+	Password string `mapstructure:"Password"`
+	// CAFile replaces the system roots for TLS; CertFile and KeyFile are a
+	// client certificate.
+	CAFile   string `mapstructure:"CAFile"`
+	CertFile string `mapstructure:"CertFile"`
+	KeyFile  string `mapstructure:"KeyFile"`
+}
+
+// Button is a notification button. When clicked it runs Cmd, snoozes the
+// trigger for the Snooze duration, or, with Ack set, cancels the remaining
+// triggers of the events. Buttons whose Cmd renders empty for an event are
+// not shown.
+type Button struct {
+	Label  string `mapstructure:"Label"`
+	Cmd    string `mapstructure:"Cmd"`
+	Snooze string `mapstructure:"Snooze"`
+	Ack    bool   `mapstructure:"Ack"`
+}
+
+// Action types.
+const (
+	ActionNotify  = "notify"
+	ActionWebhook = "webhook"
+	ActionMQTT    = "mqtt"
+)
+
+// actionPrefix starts a command that runs a configured action.
+const actionPrefix = "action:"
+
+// ActionRef returns the name of the action cmd runs, if it names one.
+func ActionRef(cmd string) (string, bool) {
+	name, ok := strings.CutPrefix(strings.TrimSpace(cmd), actionPrefix)
+	return strings.ToLower(strings.TrimSpace(name)), ok
+}
+
+// Action returns the action called name.
+func (config *Config) Action(name string) (Action, bool) {
+	for _, action := range config.Actions {
+		if action.Name == name {
+			return action, true
+		}
+	}
+	return Action{}, false
+}
+
+// Route sends the events it matches to its own command and lead time. All
+// criteria that are set must match; a route without criteria matches every
+// event.
+type Route struct {
+	Name string `mapstructure:"Name"`
+	// Summary is a regular expression matched against the event title.
+	Summary  string `mapstructure:"Summary"`
+	Calendar string `mapstructure:"Calendar"`
+	// AttendeeDomain matches events with a guest, other than the user,
+	// whose email is at this domain.
+	AttendeeDomain string `mapstructure:"AttendeeDomain"`
+	EventType      string `mapstructure:"EventType"`
+	// Cmd defaults to CmdToExec when empty.
+	Cmd string `mapstructure:"Cmd"`
+	// BeforeMinutes replaces the event's trigger points with a single one
+	// this long before the start. When unset the usual trigger points run
+	// Cmd.
+	BeforeMinutes *int `mapstructure:"BeforeMinutes"`
+	// Default marks the route applied to events that match no other route.
+	Default bool `mapstructure:"Default"`
+}
+
+// Route modes.
+const (
+	// RouteFirst applies the first matching route.
+	RouteFirst = "first"
+	// RouteAll applies every matching route.
+	RouteAll = "all"
+)
+
+// Travel is the time needed to reach locations matching a pattern. Events
+// there get an extra "leave" trigger Minutes before they start.
+type Travel struct {
+	// Location is a regular expression matched against the event location.
+	Location string `mapstructure:"Location"`
+	Minutes  int    `mapstructure:"Minutes"`
+	// Cmd defaults to CmdToExec when empty.
+	Cmd string `mapstructure:"Cmd"`
+}
+
+// Calendar sources TimeOtter can read events from.
+const (
+	SourceGoogle = "google"
+)
+
+// Actions for triggers that fall into an out-of-office or holiday span.
+const (
+	AwaySuppress = "suppress"
+	AwayCommand  = "command"
+)
+
+// Hours restricts when triggers may fire. Triggers outside every working
+// window, or inside a quiet window, are dropped or run QuietCmd instead.
+type Hours struct {
+	WorkingHours []Window `mapstructure:"WorkingHours"`
+	QuietHours   []Window `mapstructure:"QuietHours"`
+	// OutsideAction is "drop" or "quiet".
+	OutsideAction string `mapstructure:"OutsideAction"`
+	QuietCmd      string `mapstructure:"QuietCmd"`
+}
+
+// CalendarHours replaces Hours for the events of a single calendar.
+type CalendarHours struct {
+	Calendar string `mapstructure:"Calendar"`
+	Hours    `mapstructure:",squash"`
+}
+
+// Window is a daily time range. End before Start wraps past midnight, and
+// Days (e.g. "mon", "Tuesday") defaults to every day.
+type Window struct {
+	Days  []string `mapstructure:"Days"`
+	Start string   `mapstructure:"Start"`
+	End   string   `mapstructure:"End"`
+}
+
+// Actions for triggers outside the allowed hours.
+const (
+	OutsideDrop  = "drop"
+	OutsideQuiet = "quiet"
+)
+
+// Trigger modes.
+const (
+	// TriggerModeFixed uses Triggers, or TriggerBeforeMinutes, for every event.
+	TriggerModeFixed = "fixed"
+	// TriggerModeReminders derives trigger times from each event's reminders.
+	TriggerModeReminders = "reminders"
+)
+
+// TriggerPoint is one moment, relative to an event's start or end, at which
+// a command runs. Without any configured trigger points a single one fires
+// TriggerBeforeMinutes before the start with CmdToExec.
+type TriggerPoint struct {
+	Name string `mapstructure:"Name"`
+	// Anchor is "start" or "end".
+	Anchor string `mapstructure:"Anchor"`
+	// OffsetMinutes is added to the anchor; negative values fire before it.
+	OffsetMinutes int `mapstructure:"OffsetMinutes"`
+	// Cmd defaults to CmdToExec when empty.
+	Cmd string `mapstructure:"Cmd"`
+}
+
+// Trigger anchors.
+const (
+	AnchorStart = "start"
+	AnchorEnd   = "end"
+)
+
+// All-day event policies.
+const (
+	AllDaySkip          = "skip"
+	AllDayAt            = "at"
+	AllDayEveningBefore = "evening-before"
+)
+
+// Rule is one entry of the ordered include/exclude rule list. All criteria
+// that are set must match; the first matching rule decides.
+type Rule struct {
+	Name          string `mapstructure:"Name"`
+	Action        string `mapstructure:"Action"`
+	Summary       string `mapstructure:"Summary"`
+	Description   string `mapstructure:"Description"`
+	Location      string `mapstructure:"Location"`
+	Organizer     string `mapstructure:"Organizer"`
+	ColorID       string `mapstructure:"ColorID"`
+	EventType     string `mapstructure:"EventType"`
+	MinAttendees  int    `mapstructure:"MinAttendees"`
+	MaxAttendees  int    `mapstructure:"MaxAttendees"`
+	HasConference *bool  `mapstructure:"HasConference"`
+}
+
+// Rule actions.
+const (
+	RuleInclude = "include"
+	RuleExclude = "exclude"
+)
+
+// DefaultAttendanceStatuses are the response statuses that produce triggers
+// when AttendanceStatuses is not set.
+var DefaultAttendanceStatuses = []string{"accepted", "tentative"}
+
+// validAttendanceStatuses mirrors the responseStatus values of the Calendar API.
+var validAttendanceStatuses = map[string]bool{
+	"accepted":    true,
+	"tentative":   true,
+	"needsAction": true,
+	"declined":    true,
 }
 
 // ReadConfig reads the configuration file using Viper and returns the config instance.
@@ -39,6 +376,34 @@ func ReadConfig() (*viper.Viper, error) {
 	v.SetDefault("TriggerBeforeMinutes", 5)
 	v.SetDefault("CronMarker", "# custom crons below this can be deleted.")
 	v.SetDefault("ShowDeleted", false)
+	v.SetDefault("AttendanceStatuses", DefaultAttendanceStatuses)
+	v.SetDefault("SkipFreeEvents", false)
+	v.SetDefault("RuleDefault", RuleInclude)
+	v.SetDefault("AllDayPolicy", AllDaySkip)
+	v.SetDefault("AllDayTime", "08:00")
+	v.SetDefault("AllDayEveningTime", "18:00")
+	v.SetDefault("TriggerMode", TriggerModeFixed)
+	v.SetDefault("DetectOutOfOffice", true)
+	v.SetDefault("AwayAction", AwaySuppress)
+	v.SetDefault("Coalesce", true)
+	v.SetDefault("BackToBackGapMinutes", 0)
+	v.SetDefault("JoinOpener", "xdg-open")
+	v.SetDefault("Source", SourceGoogle)
+	v.SetDefault("CacheFile", fmt.Sprintf("%s/.cache/timeotter/events.json", dirname))
+	v.SetDefault("MaxCacheAgeHours", 24)
+	v.SetDefault("StateFile", fmt.Sprintf("%s/.local/state/timeotter/triggers.json", dirname))
+	v.SetDefault("Tasks", false)
+	v.SetDefault("TaskBeforeMinutes", 30)
+	v.SetDefault("TaskDueTime", "09:00")
+	v.SetDefault("FreeSlotMinutes", 0)
+	v.SetDefault("Directives", true)
+	v.SetDefault("Exec.Timeout", "0")
+	v.SetDefault("Exec.KillGrace", "5s")
+	v.SetDefault("Exec.LogFile", fmt.Sprintf("%s/.local/state/timeotter/commands.log", dirname))
+	v.SetDefault("LogMaxKB", 1024)
+	v.SetDefault("LogBackups", 3)
+	v.SetDefault("HistoryFile", fmt.Sprintf("%s/.local/state/timeotter/history.jsonl", dirname))
+	v.SetDefault("HistoryRetentionDays", 30)
 
 	// Read the configuration file
 	if err := v.ReadInConfig(); err != nil {
@@ -85,14 +450,751 @@ func ValidateConfig(config *Config) error {
 		config.TriggerBeforeMinutes = 0
 	}
 
+	if err := validateAttendance(config); err != nil {
+		return err
+	}
+
+	if err := validateRules(config); err != nil {
+		return err
+	}
+
+	if err := validateAllDay(config); err != nil {
+		return err
+	}
+
+	if err := validateTriggers(config); err != nil {
+		return err
+	}
+
+	if err := validateReminders(config); err != nil {
+		return err
+	}
+
+	if err := validateCalendarHours(config); err != nil {
+		return err
+	}
+
+	if err := validateSource(config); err != nil {
+		return err
+	}
+
+	if err := validateTasks(config); err != nil {
+		return err
+	}
+
+	if err := validateTravel(config); err != nil {
+		return err
+	}
+
+	if err := validateRoutes(config); err != nil {
+		return err
+	}
+
+	if err := validateNamedCommands(config); err != nil {
+		return err
+	}
+
+	if err := validateFreeSlots(config); err != nil {
+		return err
+	}
+
+	// Validate HistoryRetentionDays: 0 keeps every run
+	if config.HistoryRetentionDays < 0 {
+		config.HistoryRetentionDays = 0
+	}
+
+	// Validate BackToBackGapMinutes: must be non-negative
+	if config.BackToBackGapMinutes < 0 {
+		config.BackToBackGapMinutes = 0
+	}
+
+	if err := validateAway(config); err != nil {
+		return err
+	}
+
+	if err := validateActions(config); err != nil {
+		return err
+	}
+
+	if err := validateCommands(config); err != nil {
+		return err
+	}
+
+	if err := validateExec(config); err != nil {
+		return err
+	}
+
 	// Expand ~ in file paths
 	config.CredentialsFile = ExpandPath(config.CredentialsFile)
 	config.BackupFile = ExpandPath(config.BackupFile)
 	config.TokenFile = ExpandPath(config.TokenFile)
+	config.CacheFile = ExpandPath(config.CacheFile)
+	config.StateFile = ExpandPath(config.StateFile)
+	config.HistoryFile = ExpandPath(config.HistoryFile)
+
+	return nil
+}
+
+// validateAttendance defaults AttendanceStatuses and rejects unknown values.
+func validateAttendance(config *Config) error {
+	if len(config.AttendanceStatuses) == 0 {
+		config.AttendanceStatuses = append([]string(nil), DefaultAttendanceStatuses...)
+	}
+	for _, status := range config.AttendanceStatuses {
+		if !validAttendanceStatuses[status] {
+			return fmt.Errorf("invalid AttendanceStatuses value %q (want accepted, tentative, needsAction or declined)", status)
+		}
+	}
+	return nil
+}
+
+// validateCalendarHours checks Hours and the per-calendar overrides of it.
+func validateCalendarHours(config *Config) error {
+	if err := validateHours("Hours", &config.Hours); err != nil {
+		return err
+	}
+	for i := range config.CalendarHours {
+		override := &config.CalendarHours[i]
+		if override.Calendar == "" {
+			return fmt.Errorf("CalendarHours entry %d: Calendar is required", i+1)
+		}
+		if err := validateHours(fmt.Sprintf("CalendarHours %s", override.Calendar), &override.Hours); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSource checks the calendar provider and defaults the settings
+// for reaching it.
+func validateSource(config *Config) error {
+	// Validate Source: a known calendar provider
+	config.Source = strings.ToLower(config.Source)
+	if config.Source == "" {
+		config.Source = SourceGoogle
+	}
+	if config.Source != SourceGoogle {
+		return fmt.Errorf("invalid Source %q (want google)", config.Source)
+	}
+
+	if strings.TrimSpace(config.JoinOpener) == "" {
+		config.JoinOpener = "xdg-open"
+	}
 
+	// Validate MaxCacheAgeHours: must be non-negative, 0 disables the fallback
+	if config.MaxCacheAgeHours < 0 {
+		config.MaxCacheAgeHours = 0
+	}
 	return nil
 }
 
+// validateTasks checks the task trigger settings.
+func validateTasks(config *Config) error {
+	if config.TaskBeforeMinutes < 0 {
+		config.TaskBeforeMinutes = 0
+	}
+	if config.TaskDueTime == "" {
+		config.TaskDueTime = "09:00"
+	}
+	if _, _, err := ParseClock(config.TaskDueTime); err != nil {
+		return fmt.Errorf("invalid TaskDueTime: %w", err)
+	}
+	return nil
+}
+
+// validateNamedCommands lower-cases the NamedCommands names, which are
+// matched case-insensitively.
+func validateNamedCommands(config *Config) error {
+	named := make(map[string]string, len(config.NamedCommands))
+	for name, cmd := range config.NamedCommands {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || strings.ContainsAny(name, " \t=") {
+			return fmt.Errorf("invalid NamedCommands name %q", name)
+		}
+		if cmd == "" {
+			return fmt.Errorf("NamedCommands %s: command is empty", name)
+		}
+		named[name] = cmd
+	}
+	config.NamedCommands = named
+	return nil
+}
+
+// validateFreeSlots checks the free-slot trigger settings.
+func validateFreeSlots(config *Config) error {
+	// Validate FreeSlotMinutes: 0 disables free-slot triggers
+	if config.FreeSlotMinutes < 0 {
+		config.FreeSlotMinutes = 0
+	}
+	if config.FreeSlotMinutes > 0 && config.FreeSlotCmd == "" {
+		return fmt.Errorf("FreeSlotCmd is required when FreeSlotMinutes is set")
+	}
+	return nil
+}
+
+// validateAway checks AwayAction and the command it needs.
+func validateAway(config *Config) error {
+	// Validate AwayAction: suppress or run AwayCmd
+	config.AwayAction = strings.ToLower(config.AwayAction)
+	if config.AwayAction == "" {
+		config.AwayAction = AwaySuppress
+	}
+	switch config.AwayAction {
+	case AwaySuppress:
+	case AwayCommand:
+		if config.AwayCmd == "" {
+			return fmt.Errorf("AwayCmd is required when AwayAction is command")
+		}
+	default:
+		return fmt.Errorf("invalid AwayAction %q (want suppress or command)", config.AwayAction)
+	}
+	return nil
+}
+
+// Command is a configured command template and the setting it came from.
+type Command struct {
+	Setting string
+	Cmd     string
+}
+
+// Commands lists every command template set in config.
+func (config *Config) Commands() []Command {
+	commands := []Command{{"CmdToExec", config.CmdToExec}}
+	add := func(setting, cmd string) {
+		if cmd != "" {
+			commands = append(commands, Command{setting, cmd})
+		}
+	}
+	for _, trigger := range config.Triggers {
+		add(fmt.Sprintf("trigger %q Cmd", trigger.Name), trigger.Cmd)
+	}
+	add("Hours QuietCmd", config.Hours.QuietCmd)
+	for _, override := range config.CalendarHours {
+		add(fmt.Sprintf("CalendarHours %s QuietCmd", override.Calendar), override.QuietCmd)
+	}
+	add("AwayCmd", config.AwayCmd)
+	add("TaskCmd", config.TaskCmd)
+	add("FreeSlotCmd", config.FreeSlotCmd)
+	for i, travel := range config.Travel {
+		add(fmt.Sprintf("Travel entry %d Cmd", i+1), travel.Cmd)
+	}
+	for _, route := range config.Routes {
+		add(fmt.Sprintf("route %q Cmd", route.Name), route.Cmd)
+	}
+	names := make([]string, 0, len(config.NamedCommands))
+	for name := range config.NamedCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		add("NamedCommands "+name, config.NamedCommands[name])
+	}
+	for _, action := range config.Actions {
+		for _, button := range action.Buttons {
+			add(fmt.Sprintf("action %q button %q Cmd", action.Name, button.Label), button.Cmd)
+		}
+	}
+	return commands
+}
+
+// Texts lists every text template set in config, such as notification
+// bodies.
+func (config *Config) Texts() []Command {
+	var texts []Command
+	for _, action := range config.Actions {
+		switch action.Type {
+		case ActionNotify:
+			texts = append(texts,
+				Command{fmt.Sprintf("action %q Summary", action.Name), action.Summary},
+				Command{fmt.Sprintf("action %q Body", action.Name), action.Body},
+			)
+		case ActionMQTT:
+			texts = append(texts, Command{fmt.Sprintf("action %q Topic", action.Name), action.Topic})
+		}
+	}
+	return texts
+}
+
+// validateCommands checks that every command is a valid template and that
+// the actions commands refer to exist.
+func validateCommands(config *Config) error {
+	for _, command := range config.Commands() {
+		if _, err := cmdtemplate.Parse(command.Setting, command.Cmd); err != nil {
+			return fmt.Errorf("invalid %s: %w", command.Setting, err)
+		}
+		if name, ok := ActionRef(command.Cmd); ok {
+			if _, found := config.Action(name); !found {
+				return fmt.Errorf("invalid %s: no action named %q", command.Setting, name)
+			}
+		}
+	}
+	for _, text := range config.Texts() {
+		if _, err := cmdtemplate.ParseText(text.Setting, text.Cmd); err != nil {
+			return fmt.Errorf("invalid %s: %w", text.Setting, err)
+		}
+	}
+	return nil
+}
+
+// validateActions normalizes action names and types, fills in their
+// defaults and checks their settings.
+func validateActions(config *Config) error {
+	seen := make(map[string]bool)
+	for i := range config.Actions {
+		action := &config.Actions[i]
+		action.Name = strings.ToLower(strings.TrimSpace(action.Name))
+		if action.Name == "" || strings.ContainsAny(action.Name, " \t") {
+			return fmt.Errorf("Actions entry %d: invalid Name %q", i+1, action.Name)
+		}
+		if seen[action.Name] {
+			return fmt.Errorf("duplicate action name %q", action.Name)
+		}
+		seen[action.Name] = true
+
+		action.Type = strings.ToLower(action.Type)
+		switch action.Type {
+		case ActionNotify:
+			if err := validateNotify(action); err != nil {
+				return fmt.Errorf("action %s: %w", action.Name, err)
+			}
+		case ActionWebhook:
+			if err := validateWebhook(action); err != nil {
+				return fmt.Errorf("action %s: %w", action.Name, err)
+			}
+		case ActionMQTT:
+			if err := validateMQTT(action); err != nil {
+				return fmt.Errorf("action %s: %w", action.Name, err)
+			}
+		default:
+			return fmt.Errorf("action %s: invalid Type %q (want notify, webhook or mqtt)", action.Name, action.Type)
+		}
+	}
+	return nil
+}
+
+// validateNotify fills in the defaults of a notify action and checks it.
+func validateNotify(action *Action) error {
+	if action.Summary == "" {
+		action.Summary = "{{.Summary}}"
+	}
+	if action.Body == "" {
+		action.Body = "{{fmtTime .Start}}–{{fmtTime .End}}{{with .Location}}, {{.}}{{end}}"
+	}
+	if action.AppName == "" {
+		action.AppName = "timeotter"
+	}
+	action.Urgency = strings.ToLower(action.Urgency)
+	if action.Urgency == "" {
+		action.Urgency = "normal"
+	}
+	if action.Urgency != "low" && action.Urgency != "normal" && action.Urgency != "critical" {
+		return fmt.Errorf("invalid Urgency %q (want low, normal or critical)", action.Urgency)
+	}
+	if action.ButtonWait == "" {
+		action.ButtonWait = "10m"
+	}
+	if err := checkDurations(map[string]string{"Timeout": action.Timeout, "ButtonWait": action.ButtonWait}); err != nil {
+		return err
+	}
+	for i, button := range action.Buttons {
+		set := 0
+		for _, ok := range []bool{button.Cmd != "", button.Snooze != "", button.Ack} {
+			if ok {
+				set++
+			}
+		}
+		if button.Label == "" || set != 1 {
+			return fmt.Errorf("button %d needs a Label and one of Cmd, Snooze or Ack", i+1)
+		}
+		if button.Snooze != "" {
+			d, err := time.ParseDuration(button.Snooze)
+			if err != nil || d < time.Minute {
+				return fmt.Errorf("button %q: invalid Snooze %q (want a duration of at least 1m)", button.Label, button.Snooze)
+			}
+		}
+		if _, ok := ActionRef(button.Cmd); ok {
+			return fmt.Errorf("button %q: Cmd cannot run another action", button.Label)
+		}
+	}
+	action.Icon = ExpandPath(action.Icon)
+	return nil
+}
+
+// validateWebhook fills in the defaults of a webhook action and checks it.
+func validateWebhook(action *Action) error {
+	u, err := url.Parse(action.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid URL %q (want an http or https URL)", action.URL)
+	}
+	for name := range action.Headers {
+		if name == "" || strings.ContainsAny(name, " \t:") {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+	}
+	if action.Timeout == "" {
+		action.Timeout = "10s"
+	}
+	if action.Backoff == "" {
+		action.Backoff = "1s"
+	}
+	if action.Retries == nil {
+		retries := 3
+		action.Retries = &retries
+	}
+	if *action.Retries < 0 {
+		return fmt.Errorf("Retries must be non-negative")
+	}
+	return checkDurations(map[string]string{"Timeout": action.Timeout, "Backoff": action.Backoff})
+}
+
+// mqttSchemes are the broker URL schemes the MQTT client supports.
+var mqttSchemes = map[string]bool{"tcp": true, "mqtt": true, "ssl": true, "tls": true, "mqtts": true, "ws": true, "wss": true}
+
+// validateMQTT fills in the defaults of an mqtt action and checks it.
+func validateMQTT(action *Action) error {
+	u, err := url.Parse(action.Broker)
+	if err != nil || !mqttSchemes[u.Scheme] || u.Host == "" {
+		return fmt.Errorf("invalid Broker %q (want a URL such as tcp://host:1883 or ssl://host:8883)", action.Broker)
+	}
+	if action.Topic == "" {
+		action.Topic = "timeotter/{{.Calendar}}/{{.Trigger}}"
+	}
+	if action.QoS < 0 || action.QoS > 2 {
+		return fmt.Errorf("invalid QoS %d (want 0, 1 or 2)", action.QoS)
+	}
+	if (action.CertFile == "") != (action.KeyFile == "") {
+		return fmt.Errorf("CertFile and KeyFile must be set together")
+	}
+	action.CAFile = ExpandPath(action.CAFile)
+	action.CertFile = ExpandPath(action.CertFile)
+	action.KeyFile = ExpandPath(action.KeyFile)
+	if action.Timeout == "" {
+		action.Timeout = "10s"
+	}
+	return checkDurations(map[string]string{"Timeout": action.Timeout})
+}
+
+// checkDurations checks that the named settings are empty or non-negative
+// Go durations.
+func checkDurations(settings map[string]string) error {
+	fields := make([]string, 0, len(settings))
+	for field := range settings {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		value := settings[field]
+		if value == "" {
+			continue
+		}
+		if duration, err := time.ParseDuration(value); err != nil || duration < 0 {
+			return fmt.Errorf("invalid %s %q (want a duration such as 30s or 5m)", field, value)
+		}
+	}
+	return nil
+}
+
+// validateRules normalizes rule actions and checks that every pattern compiles.
+func validateRules(config *Config) error {
+	config.RuleDefault = strings.ToLower(config.RuleDefault)
+	if config.RuleDefault == "" {
+		config.RuleDefault = RuleInclude
+	}
+	if config.RuleDefault != RuleInclude && config.RuleDefault != RuleExclude {
+		return fmt.Errorf("invalid RuleDefault %q (want include or exclude)", config.RuleDefault)
+	}
+
+	for i := range config.Rules {
+		rule := &config.Rules[i]
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		rule.Action = strings.ToLower(rule.Action)
+		if rule.Action != RuleInclude && rule.Action != RuleExclude {
+			return fmt.Errorf("%s: invalid Action %q (want include or exclude)", rule.Name, rule.Action)
+		}
+		patterns := []struct{ field, pattern string }{
+			{"Summary", rule.Summary},
+			{"Description", rule.Description},
+			{"Location", rule.Location},
+			{"Organizer", rule.Organizer},
+		}
+		for _, p := range patterns {
+			if _, err := regexp.Compile(p.pattern); err != nil {
+				return fmt.Errorf("%s: invalid %s pattern: %w", rule.Name, p.field, err)
+			}
+		}
+		if rule.MinAttendees < 0 || rule.MaxAttendees < 0 {
+			return fmt.Errorf("%s: attendee counts must be non-negative", rule.Name)
+		}
+		if rule.MaxAttendees > 0 && rule.MinAttendees > rule.MaxAttendees {
+			return fmt.Errorf("%s: MinAttendees is greater than MaxAttendees", rule.Name)
+		}
+	}
+
+	return nil
+}
+
+// validateTravel checks that every travel entry has a valid pattern and a
+// positive travel time.
+func validateTravel(config *Config) error {
+	for i, travel := range config.Travel {
+		if travel.Location == "" {
+			return fmt.Errorf("Travel entry %d: Location is required", i+1)
+		}
+		if _, err := regexp.Compile(travel.Location); err != nil {
+			return fmt.Errorf("Travel entry %d: invalid Location pattern: %w", i+1, err)
+		}
+		if travel.Minutes <= 0 {
+			return fmt.Errorf("Travel entry %d: Minutes must be positive", i+1)
+		}
+	}
+	return nil
+}
+
+// validateExec checks the execution settings and expands their paths.
+func validateExec(config *Config) error {
+	if err := checkExec("Exec", &config.Exec); err != nil {
+		return err
+	}
+	for i := range config.CommandExec {
+		override := &config.CommandExec[i]
+		name := fmt.Sprintf("CommandExec entry %d", i+1)
+		if override.Match == "" {
+			return fmt.Errorf("%s: Match is required", name)
+		}
+		if _, err := regexp.Compile(override.Match); err != nil {
+			return fmt.Errorf("%s: invalid Match pattern: %w", name, err)
+		}
+		if err := checkExec(name, &override.Exec); err != nil {
+			return err
+		}
+	}
+
+	// Validate LogMaxKB and LogBackups: 0 disables rotation
+	if config.LogMaxKB < 0 {
+		config.LogMaxKB = 0
+	}
+	if config.LogBackups < 0 {
+		config.LogBackups = 0
+	}
+	return nil
+}
+
+func checkExec(name string, exec *Exec) error {
+	for _, d := range []struct{ field, value string }{{"Timeout", exec.Timeout}, {"KillGrace", exec.KillGrace}} {
+		if d.value == "" {
+			continue
+		}
+		if duration, err := time.ParseDuration(d.value); err != nil || duration < 0 {
+			return fmt.Errorf("%s: invalid %s %q (want a duration such as 30s or 5m)", name, d.field, d.value)
+		}
+	}
+	for _, kv := range exec.Env {
+		if key, _, ok := strings.Cut(kv, "="); !ok || key == "" {
+			return fmt.Errorf("%s: invalid Env entry %q (want KEY=VALUE)", name, kv)
+		}
+	}
+	exec.Dir = ExpandPath(exec.Dir)
+	if exec.LogFile != "-" {
+		exec.LogFile = ExpandPath(exec.LogFile)
+	}
+	return nil
+}
+
+// ExecFor returns the execution settings of cmd: defaults, with the fields
+// set in the first matching override replacing them. Env entries of the
+// override are added after the default ones.
+func ExecFor(defaults Exec, overrides []CommandExec, cmd string) Exec {
+	exec := defaults
+	for _, override := range overrides {
+		re, err := regexp.Compile(override.Match)
+		if err != nil || !re.MatchString(cmd) {
+			continue
+		}
+		if override.Timeout != "" {
+			exec.Timeout = override.Timeout
+		}
+		if override.KillGrace != "" {
+			exec.KillGrace = override.KillGrace
+		}
+		if override.Dir != "" {
+			exec.Dir = override.Dir
+		}
+		if override.LogFile != "" {
+			exec.LogFile = override.LogFile
+		}
+		exec.Env = append(append([]string(nil), exec.Env...), override.Env...)
+		break
+	}
+	return exec
+}
+
+// validateRoutes names unnamed routes, normalizes RouteMode and checks the
+// route patterns and lead times.
+func validateRoutes(config *Config) error {
+	config.RouteMode = strings.ToLower(config.RouteMode)
+	if config.RouteMode == "" {
+		config.RouteMode = RouteFirst
+	}
+	if config.RouteMode != RouteFirst && config.RouteMode != RouteAll {
+		return fmt.Errorf("invalid RouteMode %q (want first or all)", config.RouteMode)
+	}
+
+	defaults := 0
+	for i := range config.Routes {
+		route := &config.Routes[i]
+		if route.Name == "" {
+			route.Name = fmt.Sprintf("route %d", i+1)
+		}
+		if _, err := regexp.Compile(route.Summary); err != nil {
+			return fmt.Errorf("%s: invalid Summary pattern: %w", route.Name, err)
+		}
+		if route.BeforeMinutes != nil && *route.BeforeMinutes < 0 {
+			return fmt.Errorf("%s: BeforeMinutes must be non-negative", route.Name)
+		}
+		if route.Default {
+			defaults++
+			if route.Summary != "" || route.Calendar != "" || route.AttendeeDomain != "" || route.EventType != "" {
+				return fmt.Errorf("%s: the default route cannot have match criteria", route.Name)
+			}
+		}
+		route.AttendeeDomain = strings.ToLower(strings.TrimPrefix(route.AttendeeDomain, "@"))
+	}
+	if defaults > 1 {
+		return fmt.Errorf("only one route can be the default")
+	}
+	return nil
+}
+
+// validateAllDay checks the all-day policy and its clock times.
+func validateAllDay(config *Config) error {
+	if config.AllDayPolicy == "" {
+		config.AllDayPolicy = AllDaySkip
+	}
+	if config.AllDayTime == "" {
+		config.AllDayTime = "08:00"
+	}
+	if config.AllDayEveningTime == "" {
+		config.AllDayEveningTime = "18:00"
+	}
+
+	switch config.AllDayPolicy {
+	case AllDaySkip, AllDayAt, AllDayEveningBefore:
+	default:
+		return fmt.Errorf("invalid AllDayPolicy %q (want skip, at or evening-before)", config.AllDayPolicy)
+	}
+	if _, _, err := ParseClock(config.AllDayTime); err != nil {
+		return fmt.Errorf("invalid AllDayTime: %w", err)
+	}
+	if _, _, err := ParseClock(config.AllDayEveningTime); err != nil {
+		return fmt.Errorf("invalid AllDayEveningTime: %w", err)
+	}
+	return nil
+}
+
+// validateTriggers fills in trigger point defaults and checks anchors and
+// name uniqueness.
+func validateTriggers(config *Config) error {
+	seen := make(map[string]bool)
+	for i := range config.Triggers {
+		trigger := &config.Triggers[i]
+		if trigger.Name == "" {
+			trigger.Name = fmt.Sprintf("trigger%d", i+1)
+		}
+		if seen[trigger.Name] {
+			return fmt.Errorf("duplicate trigger name %q", trigger.Name)
+		}
+		seen[trigger.Name] = true
+
+		trigger.Anchor = strings.ToLower(trigger.Anchor)
+		if trigger.Anchor == "" {
+			trigger.Anchor = AnchorStart
+		}
+		if trigger.Anchor != AnchorStart && trigger.Anchor != AnchorEnd {
+			return fmt.Errorf("trigger %q: invalid Anchor %q (want start or end)", trigger.Name, trigger.Anchor)
+		}
+	}
+	return nil
+}
+
+// validateReminders checks the trigger mode and reminder settings.
+func validateReminders(config *Config) error {
+	config.TriggerMode = strings.ToLower(config.TriggerMode)
+	if config.TriggerMode == "" {
+		config.TriggerMode = TriggerModeFixed
+	}
+	if config.TriggerMode != TriggerModeFixed && config.TriggerMode != TriggerModeReminders {
+		return fmt.Errorf("invalid TriggerMode %q (want fixed or reminders)", config.TriggerMode)
+	}
+	for _, method := range config.ReminderMethods {
+		if method != "popup" && method != "email" {
+			return fmt.Errorf("invalid ReminderMethods value %q (want popup or email)", method)
+		}
+	}
+	for _, minutes := range config.ReminderFallbackMinutes {
+		if minutes < 0 {
+			return fmt.Errorf("ReminderFallbackMinutes must be non-negative, got %d", minutes)
+		}
+	}
+	return nil
+}
+
+// validateHours checks the windows and outside-hours action of one Hours
+// section; name is used in error messages.
+func validateHours(name string, hours *Hours) error {
+	hours.OutsideAction = strings.ToLower(hours.OutsideAction)
+	if hours.OutsideAction == "" {
+		hours.OutsideAction = OutsideDrop
+	}
+	switch hours.OutsideAction {
+	case OutsideDrop:
+	case OutsideQuiet:
+		if hours.QuietCmd == "" {
+			return fmt.Errorf("%s: QuietCmd is required when OutsideAction is quiet", name)
+		}
+	default:
+		return fmt.Errorf("%s: invalid OutsideAction %q (want drop or quiet)", name, hours.OutsideAction)
+	}
+
+	windows := append(append([]Window{}, hours.WorkingHours...), hours.QuietHours...)
+	for _, window := range windows {
+		if _, _, err := ParseClock(window.Start); err != nil {
+			return fmt.Errorf("%s: invalid window Start: %w", name, err)
+		}
+		if _, _, err := ParseClock(window.End); err != nil {
+			return fmt.Errorf("%s: invalid window End: %w", name, err)
+		}
+		for _, day := range window.Days {
+			if _, err := ParseWeekday(day); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ParseWeekday parses a weekday name such as "mon" or "Monday".
+func ParseWeekday(s string) (time.Weekday, error) {
+	name := strings.ToLower(s)
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		full := strings.ToLower(d.String())
+		if name == full || name == full[:3] {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday %q", s)
+}
+
+// ParseClock parses a 24-hour "HH:MM" time of day.
+func ParseClock(s string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%q is not a HH:MM time", s)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
 // GetConfig loads, validates and returns the application configuration.
 func GetConfig() Config {
 	// Load the config file using Viper
@@ -112,12 +1214,6 @@ func GetConfig() Config {
 		log.Fatalf("Error validating config: %v", err)
 	}
 
-	// Now use the loaded config values
-	fmt.Printf("Token File: %s\n", config.TokenFile)
-	fmt.Printf("Calendar ID: %s\n", config.CalendarID)
-	fmt.Printf("Max Events: %d\n", config.MaxRes)
-	fmt.Printf("Command to Execute: %s\n", config.CmdToExec)
-
 	return config
 }
 
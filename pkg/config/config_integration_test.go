@@ -196,3 +196,132 @@ TokenFile = "/tmp/token.json"
 		t.Errorf("failed to read config with 0600 permissions: %v", err)
 	}
 }
+
+func TestIntegration_RulesFromTOML(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+
+	configDir := filepath.Join(tmpDir, ".config", "timeotter")
+	if err := os.MkdirAll(configDir, 0750); err != nil {
+		t.Fatalf("failed to create config dir: %v", err)
+	}
+
+	configContent := `
+CalendarID = "rules@calendar.google.com"
+CmdToExec = "echo hello"
+TokenFile = "/tmp/token.json"
+RuleDefault = "exclude"
+
+[[Rules]]
+Name = "no-lunch"
+Action = "exclude"
+Summary = "(?i)lunch"
+
+[[Rules]]
+Name = "meet"
+Action = "include"
+HasConference = true
+MinAttendees = 2
+`
+	configPath := filepath.Join(configDir, "config.toml")
+	if err := os.WriteFile(configPath, []byte(configContent), 0600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	v, err := ReadConfig()
+	if err != nil {
+		t.Fatalf("ReadConfig failed: %v", err)
+	}
+
+	var config Config
+	if err := v.Unmarshal(&config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if err := ValidateConfig(&config); err != nil {
+		t.Fatalf("ValidateConfig failed: %v", err)
+	}
+
+	if len(config.Rules) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(config.Rules))
+	}
+	if config.Rules[0].Name != "no-lunch" || config.Rules[0].Summary != "(?i)lunch" {
+		t.Errorf("first rule mismatch: %+v", config.Rules[0])
+	}
+	second := config.Rules[1]
+	if second.HasConference == nil || !*second.HasConference || second.MinAttendees != 2 {
+		t.Errorf("second rule mismatch: %+v", second)
+	}
+	if config.RuleDefault != RuleExclude {
+		t.Errorf("RuleDefault mismatch: %s", config.RuleDefault)
+	}
+}
+
+func TestIntegration_HoursFromTOML(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+
+	configDir := filepath.Join(tmpDir, ".config", "timeotter")
+	if err := os.MkdirAll(configDir, 0750); err != nil {
+		t.Fatalf("failed to create config dir: %v", err)
+	}
+
+	configContent := `
+CalendarID = "hours@calendar.google.com"
+CmdToExec = "lamp on"
+TokenFile = "/tmp/token.json"
+
+[Hours]
+OutsideAction = "quiet"
+QuietCmd = "notify-send -u low meeting"
+
+[[Hours.WorkingHours]]
+Days = ["mon", "tue", "wed", "thu", "fri"]
+Start = "09:00"
+End = "18:00"
+
+[[Hours.QuietHours]]
+Start = "22:00"
+End = "07:00"
+
+[[CalendarHours]]
+Calendar = "oncall@example.com"
+OutsideAction = "drop"
+
+[[CalendarHours.QuietHours]]
+Start = "23:00"
+End = "06:00"
+`
+	configPath := filepath.Join(configDir, "config.toml")
+	if err := os.WriteFile(configPath, []byte(configContent), 0600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	v, err := ReadConfig()
+	if err != nil {
+		t.Fatalf("ReadConfig failed: %v", err)
+	}
+	var config Config
+	if err := v.Unmarshal(&config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if err := ValidateConfig(&config); err != nil {
+		t.Fatalf("ValidateConfig failed: %v", err)
+	}
+
+	if len(config.Hours.WorkingHours) != 1 || len(config.Hours.WorkingHours[0].Days) != 5 {
+		t.Errorf("WorkingHours mismatch: %+v", config.Hours.WorkingHours)
+	}
+	if len(config.Hours.QuietHours) != 1 || config.Hours.QuietHours[0].Start != "22:00" {
+		t.Errorf("QuietHours mismatch: %+v", config.Hours.QuietHours)
+	}
+	if config.Hours.OutsideAction != OutsideQuiet {
+		t.Errorf("OutsideAction mismatch: %s", config.Hours.OutsideAction)
+	}
+	if len(config.CalendarHours) != 1 {
+		t.Fatalf("expected 1 CalendarHours entry, got %d", len(config.CalendarHours))
+	}
+	override := config.CalendarHours[0]
+	if override.Calendar != "oncall@example.com" || override.OutsideAction != OutsideDrop || len(override.QuietHours) != 1 {
+		t.Errorf("CalendarHours mismatch: %+v", override)
+	}
+}
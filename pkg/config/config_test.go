@@ -1,6 +1,7 @@
 package config
 
 import (
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -239,6 +240,8 @@ func TestValidateConfig_PathExpansion(t *testing.T) {
 		TokenFile:       "~/token.json",
 		CredentialsFile: "~/credentials.json",
 		BackupFile:      "~/backup.txt",
+		CacheFile:       "~/events.json",
+		StateFile:       "~/triggers.json",
 	}
 
 	err := ValidateConfig(&config)
@@ -255,6 +258,12 @@ func TestValidateConfig_PathExpansion(t *testing.T) {
 	if config.BackupFile != homeDir+"/backup.txt" {
 		t.Errorf("BackupFile not expanded, got %s", config.BackupFile)
 	}
+	if config.CacheFile != homeDir+"/events.json" {
+		t.Errorf("CacheFile not expanded, got %s", config.CacheFile)
+	}
+	if config.StateFile != homeDir+"/triggers.json" {
+		t.Errorf("StateFile not expanded, got %s", config.StateFile)
+	}
 }
 
 func TestReadConfig_MissingFile(t *testing.T) {
@@ -305,6 +314,44 @@ MaxRes = 10
 	}
 }
 
+// TestGetConfig_Quiet checks that loading the config prints nothing, so
+// commands such as `history --json` and `fire` control their own stdout.
+func TestGetConfig_Quiet(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	configDir := filepath.Join(tmpDir, ".config", "timeotter")
+	if err := os.MkdirAll(configDir, 0750); err != nil {
+		t.Fatalf("failed to create config dir: %v", err)
+	}
+	configContent := `
+CalendarID = "test@calendar.google.com"
+CmdToExec = "echo hello"
+TokenFile = "` + tmpDir + `/token.json"
+CredentialsFile = "` + tmpDir + `/credentials.json"
+`
+	if err := os.WriteFile(filepath.Join(configDir, "config.toml"), []byte(configContent), 0600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	conf := GetConfig()
+	os.Stdout = stdout
+	_ = w.Close()
+	out, _ := io.ReadAll(r)
+
+	if conf.CmdToExec != "echo hello" {
+		t.Errorf("CmdToExec mismatch, got %s", conf.CmdToExec)
+	}
+	if len(out) != 0 {
+		t.Errorf("GetConfig printed %q", out)
+	}
+}
+
 func TestReadConfig_DefaultValues(t *testing.T) {
 	tmpDir := t.TempDir()
 	t.Setenv("HOME", tmpDir)
@@ -343,4 +390,817 @@ TokenFile = "/path/to/token.json"
 	if v.GetString("CronMarker") != "# custom crons below this can be deleted." {
 		t.Errorf("default CronMarker mismatch, got %s", v.GetString("CronMarker"))
 	}
+	if got := v.GetStringSlice("AttendanceStatuses"); strings.Join(got, ",") != "accepted,tentative" {
+		t.Errorf("default AttendanceStatuses mismatch, got %v", got)
+	}
+	if v.GetBool("SkipFreeEvents") {
+		t.Errorf("default SkipFreeEvents should be false")
+	}
+	if !v.GetBool("DetectOutOfOffice") {
+		t.Errorf("default DetectOutOfOffice should be true")
+	}
+	if !v.GetBool("Coalesce") {
+		t.Errorf("default Coalesce should be true")
+	}
+	if v.GetString("JoinOpener") != "xdg-open" {
+		t.Errorf("default JoinOpener mismatch, got %s", v.GetString("JoinOpener"))
+	}
+	if v.GetInt("MaxCacheAgeHours") != 24 {
+		t.Errorf("default MaxCacheAgeHours mismatch, got %d", v.GetInt("MaxCacheAgeHours"))
+	}
+	if !strings.HasSuffix(v.GetString("CacheFile"), "/.cache/timeotter/events.json") {
+		t.Errorf("default CacheFile mismatch, got %s", v.GetString("CacheFile"))
+	}
+	if !strings.HasSuffix(v.GetString("StateFile"), "/.local/state/timeotter/triggers.json") {
+		t.Errorf("default StateFile mismatch, got %s", v.GetString("StateFile"))
+	}
+	if v.GetBool("Tasks") {
+		t.Errorf("default Tasks should be false")
+	}
+	if v.GetInt("TaskBeforeMinutes") != 30 || v.GetString("TaskDueTime") != "09:00" {
+		t.Errorf("default task lead time mismatch, got %d and %s", v.GetInt("TaskBeforeMinutes"), v.GetString("TaskDueTime"))
+	}
+	if !v.GetBool("Directives") {
+		t.Errorf("default Directives should be true")
+	}
+	if v.GetString("Exec.KillGrace") != "5s" || !strings.HasSuffix(v.GetString("Exec.LogFile"), "/.local/state/timeotter/commands.log") {
+		t.Errorf("default Exec mismatch, got %s and %s", v.GetString("Exec.KillGrace"), v.GetString("Exec.LogFile"))
+	}
+	if v.GetInt("LogMaxKB") != 1024 || v.GetInt("LogBackups") != 3 {
+		t.Errorf("default log rotation mismatch, got %d and %d", v.GetInt("LogMaxKB"), v.GetInt("LogBackups"))
+	}
+	if !strings.HasSuffix(v.GetString("HistoryFile"), "/.local/state/timeotter/history.jsonl") || v.GetInt("HistoryRetentionDays") != 30 {
+		t.Errorf("default history settings mismatch, got %s and %d", v.GetString("HistoryFile"), v.GetInt("HistoryRetentionDays"))
+	}
+}
+
+func TestValidateConfig_AttendanceStatuses(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       []string
+		want        []string
+		expectError bool
+	}{
+		{
+			name:  "empty uses defaults",
+			input: nil,
+			want:  DefaultAttendanceStatuses,
+		},
+		{
+			name:  "custom statuses kept",
+			input: []string{"accepted", "needsAction"},
+			want:  []string{"accepted", "needsAction"},
+		},
+		{
+			name:        "unknown status rejected",
+			input:       []string{"accepted", "maybe"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				CalendarID:         "test@calendar.google.com",
+				CmdToExec:          "echo hello",
+				TokenFile:          "/path/to/token.json",
+				AttendanceStatuses: tt.input,
+			}
+			err := ValidateConfig(&config)
+			if tt.expectError {
+				if err == nil || !strings.Contains(err.Error(), "AttendanceStatuses") {
+					t.Errorf("expected AttendanceStatuses error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(config.AttendanceStatuses, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("AttendanceStatuses = %v, want %v", config.AttendanceStatuses, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_Rules(t *testing.T) {
+	tests := []struct {
+		name        string
+		rules       []Rule
+		ruleDefault string
+		errorMsg    string
+	}{
+		{
+			name:  "valid rules",
+			rules: []Rule{{Action: "Exclude", Summary: "^Lunch$"}, {Action: "include", ColorID: "11"}},
+		},
+		{
+			name:     "unknown action",
+			rules:    []Rule{{Action: "skip"}},
+			errorMsg: "invalid Action",
+		},
+		{
+			name:     "bad regex",
+			rules:    []Rule{{Name: "meet", Action: "include", Description: "meet.google.com/("}},
+			errorMsg: "meet: invalid Description pattern",
+		},
+		{
+			name:     "min greater than max",
+			rules:    []Rule{{Action: "include", MinAttendees: 5, MaxAttendees: 2}},
+			errorMsg: "MinAttendees",
+		},
+		{
+			name:        "bad default",
+			ruleDefault: "maybe",
+			errorMsg:    "invalid RuleDefault",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				CalendarID:  "test@calendar.google.com",
+				CmdToExec:   "echo hello",
+				TokenFile:   "/path/to/token.json",
+				Rules:       tt.rules,
+				RuleDefault: tt.ruleDefault,
+			}
+			err := ValidateConfig(&config)
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if config.Rules[0].Action != RuleExclude || config.Rules[0].Name != "rule 1" {
+					t.Errorf("rule not normalized: %+v", config.Rules[0])
+				}
+				if config.RuleDefault != RuleInclude {
+					t.Errorf("RuleDefault = %q, want include", config.RuleDefault)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_AllDay(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   string
+		at       string
+		evening  string
+		errorMsg string
+	}{
+		{name: "defaults"},
+		{name: "at policy", policy: AllDayAt, at: "07:45"},
+		{name: "evening policy", policy: AllDayEveningBefore, evening: "20:00"},
+		{name: "unknown policy", policy: "morning", errorMsg: "invalid AllDayPolicy"},
+		{name: "bad time", policy: AllDayAt, at: "8am", errorMsg: "invalid AllDayTime"},
+		{name: "bad evening time", policy: AllDayEveningBefore, evening: "25:00", errorMsg: "invalid AllDayEveningTime"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				CalendarID:        "test@calendar.google.com",
+				CmdToExec:         "echo hello",
+				TokenFile:         "/path/to/token.json",
+				AllDayPolicy:      tt.policy,
+				AllDayTime:        tt.at,
+				AllDayEveningTime: tt.evening,
+			}
+			err := ValidateConfig(&config)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.policy == "" && config.AllDayPolicy != AllDaySkip {
+				t.Errorf("AllDayPolicy = %q, want skip", config.AllDayPolicy)
+			}
+		})
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	hour, minute, err := ParseClock("08:05")
+	if err != nil || hour != 8 || minute != 5 {
+		t.Errorf("ParseClock(08:05) = %d, %d, %v", hour, minute, err)
+	}
+	if _, _, err := ParseClock("24:00"); err == nil {
+		t.Error("expected error for 24:00")
+	}
+}
+
+func TestValidateConfig_Triggers(t *testing.T) {
+	valid := Config{
+		CalendarID: "test@calendar.google.com",
+		CmdToExec:  "echo hello",
+		TokenFile:  "/path/to/token.json",
+		Triggers: []TriggerPoint{
+			{OffsetMinutes: -10},
+			{Name: "log", Anchor: "END", Cmd: "log.sh"},
+		},
+	}
+	if err := ValidateConfig(&valid); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if valid.Triggers[0].Name != "trigger1" || valid.Triggers[0].Anchor != AnchorStart {
+		t.Errorf("defaults not applied: %+v", valid.Triggers[0])
+	}
+	if valid.Triggers[1].Anchor != AnchorEnd {
+		t.Errorf("anchor not normalized: %+v", valid.Triggers[1])
+	}
+
+	tests := []struct {
+		name     string
+		triggers []TriggerPoint
+		errorMsg string
+	}{
+		{"bad anchor", []TriggerPoint{{Name: "x", Anchor: "middle"}}, "invalid Anchor"},
+		{"duplicate names", []TriggerPoint{{Name: "x"}, {Name: "x"}}, "duplicate trigger name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				CalendarID: "test@calendar.google.com",
+				CmdToExec:  "echo hello",
+				TokenFile:  "/path/to/token.json",
+				Triggers:   tt.triggers,
+			}
+			err := ValidateConfig(&config)
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_Reminders(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		methods  []string
+		fallback []int
+		errorMsg string
+	}{
+		{name: "default mode"},
+		{name: "reminders mode", mode: "Reminders", methods: []string{"popup"}, fallback: []int{10}},
+		{name: "bad mode", mode: "events", errorMsg: "invalid TriggerMode"},
+		{name: "bad method", mode: TriggerModeReminders, methods: []string{"sms"}, errorMsg: "invalid ReminderMethods"},
+		{name: "negative fallback", mode: TriggerModeReminders, fallback: []int{-1}, errorMsg: "ReminderFallbackMinutes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				CalendarID:              "test@calendar.google.com",
+				CmdToExec:               "echo hello",
+				TokenFile:               "/path/to/token.json",
+				TriggerMode:             tt.mode,
+				ReminderMethods:         tt.methods,
+				ReminderFallbackMinutes: tt.fallback,
+			}
+			err := ValidateConfig(&config)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.mode == "" && config.TriggerMode != TriggerModeFixed {
+				t.Errorf("TriggerMode = %q, want fixed", config.TriggerMode)
+			}
+			if tt.mode == "Reminders" && config.TriggerMode != TriggerModeReminders {
+				t.Errorf("TriggerMode not normalized: %q", config.TriggerMode)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_Hours(t *testing.T) {
+	tests := []struct {
+		name     string
+		hours    Hours
+		perCal   []CalendarHours
+		errorMsg string
+	}{
+		{name: "empty hours"},
+		{
+			name: "valid windows",
+			hours: Hours{
+				WorkingHours: []Window{{Days: []string{"Mon", "friday"}, Start: "09:00", End: "17:30"}},
+				QuietHours:   []Window{{Start: "22:00", End: "07:00"}},
+			},
+		},
+		{
+			name:     "bad weekday",
+			hours:    Hours{WorkingHours: []Window{{Days: []string{"funday"}, Start: "09:00", End: "17:00"}}},
+			errorMsg: "unknown weekday",
+		},
+		{
+			name:     "bad clock",
+			hours:    Hours{QuietHours: []Window{{Start: "9", End: "17:00"}}},
+			errorMsg: "invalid window Start",
+		},
+		{
+			name:     "quiet without command",
+			hours:    Hours{OutsideAction: "quiet"},
+			errorMsg: "QuietCmd is required",
+		},
+		{
+			name:     "bad action",
+			hours:    Hours{OutsideAction: "snooze"},
+			errorMsg: "invalid OutsideAction",
+		},
+		{
+			name:     "per-calendar override validated",
+			perCal:   []CalendarHours{{Calendar: "team@example.com", Hours: Hours{OutsideAction: "quiet"}}},
+			errorMsg: "CalendarHours team@example.com",
+		},
+		{
+			name:     "per-calendar override needs a calendar",
+			perCal:   []CalendarHours{{}},
+			errorMsg: "Calendar is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				CalendarID:    "test@calendar.google.com",
+				CmdToExec:     "echo hello",
+				TokenFile:     "/path/to/token.json",
+				Hours:         tt.hours,
+				CalendarHours: tt.perCal,
+			}
+			err := ValidateConfig(&config)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if config.Hours.OutsideAction != OutsideDrop {
+				t.Errorf("OutsideAction = %q, want drop", config.Hours.OutsideAction)
+			}
+		})
+	}
+}
+
+func TestParseWeekday(t *testing.T) {
+	for input, want := range map[string]string{"mon": "Monday", "SUN": "Sunday", "Thursday": "Thursday"} {
+		got, err := ParseWeekday(input)
+		if err != nil || got.String() != want {
+			t.Errorf("ParseWeekday(%q) = %v, %v; want %s", input, got, err, want)
+		}
+	}
+	if _, err := ParseWeekday("th"); err == nil {
+		t.Error("expected error for ambiguous abbreviation")
+	}
+}
+
+func TestValidateConfig_AwayAction(t *testing.T) {
+	tests := []struct {
+		name     string
+		action   string
+		cmd      string
+		want     string
+		errorMsg string
+	}{
+		{name: "default", want: AwaySuppress},
+		{name: "command", action: "Command", cmd: "echo away", want: AwayCommand},
+		{name: "command without cmd", action: AwayCommand, errorMsg: "AwayCmd is required"},
+		{name: "unknown", action: "ignore", errorMsg: "invalid AwayAction"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				CalendarID: "test@calendar.google.com",
+				CmdToExec:  "echo hello",
+				TokenFile:  "/path/to/token.json",
+				AwayAction: tt.action,
+				AwayCmd:    tt.cmd,
+			}
+			err := ValidateConfig(&config)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if config.AwayAction != tt.want {
+				t.Errorf("AwayAction = %q, want %q", config.AwayAction, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_Source(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		want     string
+		errorMsg string
+	}{
+		{name: "default", want: SourceGoogle},
+		{name: "case insensitive", source: "Google", want: SourceGoogle},
+		{name: "unknown", source: "outlook", errorMsg: "invalid Source"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				CalendarID: "test@calendar.google.com",
+				CmdToExec:  "echo hello",
+				TokenFile:  "/path/to/token.json",
+				Source:     tt.source,
+			}
+			err := ValidateConfig(&config)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if config.Source != tt.want {
+				t.Errorf("Source = %q, want %q", config.Source, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_Tasks(t *testing.T) {
+	config := Config{
+		CalendarID:        "test@calendar.google.com",
+		CmdToExec:         "echo hello",
+		TokenFile:         "/path/to/token.json",
+		Tasks:             true,
+		TaskBeforeMinutes: -10,
+	}
+	if err := ValidateConfig(&config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.TaskBeforeMinutes != 0 || config.TaskDueTime != "09:00" {
+		t.Errorf("task defaults not applied: %d, %q", config.TaskBeforeMinutes, config.TaskDueTime)
+	}
+
+	config.TaskDueTime = "noon"
+	if err := ValidateConfig(&config); err == nil || !strings.Contains(err.Error(), "invalid TaskDueTime") {
+		t.Errorf("expected invalid TaskDueTime error, got %v", err)
+	}
+}
+
+func TestValidateConfig_FreeSlots(t *testing.T) {
+	config := Config{
+		CalendarID:      "test@calendar.google.com",
+		CmdToExec:       "echo hello",
+		TokenFile:       "/path/to/token.json",
+		FreeSlotMinutes: 45,
+	}
+	if err := ValidateConfig(&config); err == nil || !strings.Contains(err.Error(), "FreeSlotCmd") {
+		t.Errorf("expected FreeSlotCmd to be required, got %v", err)
+	}
+
+	config.FreeSlotCmd = "focus-timer"
+	if err := ValidateConfig(&config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	config.FreeSlotMinutes = -5
+	config.FreeSlotCmd = ""
+	if err := ValidateConfig(&config); err != nil || config.FreeSlotMinutes != 0 {
+		t.Errorf("expected negative FreeSlotMinutes to disable the feature, got %d, %v", config.FreeSlotMinutes, err)
+	}
+}
+
+func TestValidateConfig_Travel(t *testing.T) {
+	tests := []struct {
+		name     string
+		travel   Travel
+		errorMsg string
+	}{
+		{name: "valid", travel: Travel{Location: "(?i)building b", Minutes: 10}},
+		{name: "missing location", travel: Travel{Minutes: 10}, errorMsg: "Location is required"},
+		{name: "bad pattern", travel: Travel{Location: "(", Minutes: 10}, errorMsg: "invalid Location pattern"},
+		{name: "no minutes", travel: Travel{Location: "HQ"}, errorMsg: "Minutes must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				CalendarID: "test@calendar.google.com",
+				CmdToExec:  "echo hello",
+				TokenFile:  "/path/to/token.json",
+				Travel:     []Travel{tt.travel},
+			}
+			err := ValidateConfig(&config)
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_CommandTemplates(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(*Config)
+		errorMsg string
+	}{
+		{name: "plain command", modify: func(c *Config) {}},
+		{name: "template", modify: func(c *Config) { c.CmdToExec = `notify-send "{{.Summary}}" "starts {{.Start | fmtTime}}"` }},
+		{name: "syntax error", modify: func(c *Config) { c.CmdToExec = "notify-send {{.Summary" }, errorMsg: "invalid CmdToExec"},
+		{name: "unknown function", modify: func(c *Config) { c.TaskCmd = "echo {{shout .Summary}}" }, errorMsg: "invalid TaskCmd"},
+		{
+			name: "trigger command",
+			modify: func(c *Config) {
+				c.Triggers = []TriggerPoint{{Name: "end", Anchor: AnchorEnd, Cmd: `echo "{{.Summary}}`}}
+			},
+			errorMsg: `invalid trigger "end" Cmd`,
+		},
+		{
+			name:     "travel command",
+			modify:   func(c *Config) { c.Travel = []Travel{{Location: "HQ", Minutes: 10, Cmd: "echo {{"}} },
+			errorMsg: "invalid Travel entry 1 Cmd",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				CalendarID: "test@calendar.google.com",
+				CmdToExec:  "echo hello",
+				TokenFile:  "/path/to/token.json",
+			}
+			tt.modify(&config)
+			err := ValidateConfig(&config)
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_Routes(t *testing.T) {
+	five := 5
+	negative := -1
+	tests := []struct {
+		name     string
+		routes   []Route
+		mode     string
+		errorMsg string
+	}{
+		{name: "valid", routes: []Route{{Summary: "(?i)interview", Cmd: "open-doc", BeforeMinutes: &five}, {Default: true, Cmd: "notify"}}},
+		{name: "all mode", routes: []Route{{EventType: "focusTime"}}, mode: "ALL"},
+		{name: "bad mode", mode: "some", errorMsg: "invalid RouteMode"},
+		{name: "bad pattern", routes: []Route{{Name: "broken", Summary: "("}}, errorMsg: "broken: invalid Summary pattern"},
+		{name: "negative lead time", routes: []Route{{BeforeMinutes: &negative}}, errorMsg: "route 1: BeforeMinutes must be non-negative"},
+		{name: "default with criteria", routes: []Route{{Default: true, Calendar: "work"}}, errorMsg: "default route cannot have match criteria"},
+		{name: "two defaults", routes: []Route{{Default: true}, {Default: true}}, errorMsg: "only one route can be the default"},
+		{name: "bad command", routes: []Route{{Name: "x", Cmd: "echo {{"}}, errorMsg: `invalid route "x" Cmd`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				CalendarID: "test@calendar.google.com",
+				CmdToExec:  "echo hello",
+				TokenFile:  "/path/to/token.json",
+				Routes:     tt.routes,
+				RouteMode:  tt.mode,
+			}
+			err := ValidateConfig(&config)
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMsg, err)
+			}
+		})
+	}
+
+	config := Config{CalendarID: "c", CmdToExec: "echo", TokenFile: "t", Routes: []Route{{AttendeeDomain: "@Example.COM"}}}
+	if err := ValidateConfig(&config); err != nil {
+		t.Fatal(err)
+	}
+	if config.RouteMode != RouteFirst || config.Routes[0].Name != "route 1" || config.Routes[0].AttendeeDomain != "example.com" {
+		t.Errorf("routes not normalized: %s %+v", config.RouteMode, config.Routes[0])
+	}
+}
+
+func TestValidateConfig_NamedCommands(t *testing.T) {
+	config := Config{
+		CalendarID:    "test@calendar.google.com",
+		CmdToExec:     "echo hello",
+		TokenFile:     "/path/to/token.json",
+		NamedCommands: map[string]string{"Standup": "timew start standup"},
+	}
+	if err := ValidateConfig(&config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.NamedCommands["standup"] != "timew start standup" {
+		t.Errorf("names should be lowercased, got %v", config.NamedCommands)
+	}
+
+	for name, commands := range map[string]map[string]string{
+		"invalid NamedCommands name":        {"two words": "echo"},
+		"NamedCommands x: command is empty": {"x": ""},
+		"invalid NamedCommands loud":        {"loud": "echo {{.Nope"},
+	} {
+		config := Config{CalendarID: "c", CmdToExec: "echo", TokenFile: "t", NamedCommands: commands}
+		if err := ValidateConfig(&config); err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("expected error containing %q, got %v", name, err)
+		}
+	}
+}
+
+func TestValidateConfig_Exec(t *testing.T) {
+	tests := []struct {
+		name     string
+		exec     Exec
+		commands []CommandExec
+		errorMsg string
+	}{
+		{name: "valid", exec: Exec{Timeout: "5m", KillGrace: "10s", Env: []string{"DISPLAY=:0"}}, commands: []CommandExec{{Match: "^mpv", Exec: Exec{Timeout: "0"}}}},
+		{name: "bad timeout", exec: Exec{Timeout: "5"}, errorMsg: `Exec: invalid Timeout "5"`},
+		{name: "negative grace", exec: Exec{KillGrace: "-1s"}, errorMsg: "Exec: invalid KillGrace"},
+		{name: "bad env", exec: Exec{Env: []string{"DISPLAY"}}, errorMsg: `invalid Env entry "DISPLAY"`},
+		{name: "missing match", commands: []CommandExec{{}}, errorMsg: "CommandExec entry 1: Match is required"},
+		{name: "bad match", commands: []CommandExec{{Match: "("}}, errorMsg: "CommandExec entry 1: invalid Match pattern"},
+		{name: "bad override", commands: []CommandExec{{Match: "x", Exec: Exec{Env: []string{"=1"}}}}, errorMsg: "CommandExec entry 1: invalid Env entry"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				CalendarID:  "test@calendar.google.com",
+				CmdToExec:   "echo hello",
+				TokenFile:   "/path/to/token.json",
+				Exec:        tt.exec,
+				CommandExec: tt.commands,
+			}
+			err := ValidateConfig(&config)
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestExecFor(t *testing.T) {
+	defaults := Exec{Timeout: "5m", KillGrace: "5s", Dir: "/home/me", Env: []string{"A=1"}, LogFile: "/tmp/commands.log"}
+	overrides := []CommandExec{
+		{Match: "^mpv ", Exec: Exec{Timeout: "30s", Env: []string{"B=2"}}},
+		{Match: "mpv", Exec: Exec{Dir: "/never"}},
+		{Match: "^notify-send", Exec: Exec{LogFile: "-"}},
+	}
+
+	got := ExecFor(defaults, overrides, "mpv ~/alarm.mp3")
+	if got.Timeout != "30s" || got.KillGrace != "5s" || got.Dir != "/home/me" || strings.Join(got.Env, ",") != "A=1,B=2" {
+		t.Errorf("unexpected settings for mpv: %+v", got)
+	}
+	if got := ExecFor(defaults, overrides, "notify-send hi"); got.LogFile != "-" || got.Timeout != "5m" {
+		t.Errorf("unexpected settings for notify-send: %+v", got)
+	}
+	if got := ExecFor(defaults, overrides, "echo"); got.Timeout != "5m" || len(got.Env) != 1 {
+		t.Errorf("unexpected default settings: %+v", got)
+	}
+	if len(defaults.Env) != 1 {
+		t.Errorf("ExecFor modified the defaults: %v", defaults.Env)
+	}
+}
+
+func TestValidateConfig_Actions(t *testing.T) {
+	negative := -1
+	tests := []struct {
+		name      string
+		actions   []Action
+		cmdToExec string
+		errorMsg  string
+	}{
+		{name: "notify", actions: []Action{{Name: "Desktop", Type: "Notify", Urgency: "critical", Timeout: "0",
+			Buttons: []Button{{Label: "Join", Cmd: "{{if .JoinURL}}xdg-open {{.JoinURL}}{{end}}"}}}}, cmdToExec: "action:desktop"},
+		{name: "unknown action", cmdToExec: "action:desktop", errorMsg: `invalid CmdToExec: no action named "desktop"`},
+		{name: "no name", actions: []Action{{Type: "notify"}}, errorMsg: "Actions entry 1: invalid Name"},
+		{name: "duplicate", actions: []Action{{Name: "a", Type: "notify"}, {Name: "A", Type: "notify"}}, errorMsg: `duplicate action name "a"`},
+		{name: "bad type", actions: []Action{{Name: "a", Type: "pager"}}, errorMsg: `action a: invalid Type "pager"`},
+		{name: "bad urgency", actions: []Action{{Name: "a", Type: "notify", Urgency: "urgent"}}, errorMsg: `invalid Urgency "urgent"`},
+		{name: "bad timeout", actions: []Action{{Name: "a", Type: "notify", Timeout: "soon"}}, errorMsg: `invalid Timeout "soon"`},
+		{name: "bad body", actions: []Action{{Name: "a", Type: "notify", Body: "{{.Start"}}, errorMsg: `invalid action "a" Body`},
+		{name: "button without cmd", actions: []Action{{Name: "a", Type: "notify", Buttons: []Button{{Label: "Join"}}}}, errorMsg: "button 1 needs a Label and one of Cmd, Snooze or Ack"},
+		{name: "snooze and ack buttons", actions: []Action{{Name: "a", Type: "notify", Buttons: []Button{{Label: "Snooze", Snooze: "5m"}, {Label: "Done", Ack: true}}}}},
+		{name: "button with cmd and ack", actions: []Action{{Name: "a", Type: "notify", Buttons: []Button{{Label: "Done", Cmd: "true", Ack: true}}}}, errorMsg: "button 1 needs a Label and one of Cmd, Snooze or Ack"},
+		{name: "short snooze", actions: []Action{{Name: "a", Type: "notify", Buttons: []Button{{Label: "Snooze", Snooze: "30s"}}}}, errorMsg: `button "Snooze": invalid Snooze "30s"`},
+		{name: "button runs action", actions: []Action{{Name: "a", Type: "notify", Buttons: []Button{{Label: "Again", Cmd: "action:a"}}}}, errorMsg: "cannot run another action"},
+		{name: "webhook", actions: []Action{{Name: "lights", Type: "webhook", URL: "https://hooks.example.com/on-air", Headers: map[string]string{"authorization": "Bearer x"}}}},
+		{name: "webhook without url", actions: []Action{{Name: "lights", Type: "webhook"}}, errorMsg: `action lights: invalid URL ""`},
+		{name: "webhook bad scheme", actions: []Action{{Name: "lights", Type: "webhook", URL: "ftp://example.com"}}, errorMsg: "invalid URL"},
+		{name: "webhook bad header", actions: []Action{{Name: "lights", Type: "webhook", URL: "http://x", Headers: map[string]string{"x y": "1"}}}, errorMsg: `invalid header name "x y"`},
+		{name: "webhook bad backoff", actions: []Action{{Name: "lights", Type: "webhook", URL: "http://x", Backoff: "-1s"}}, errorMsg: `invalid Backoff "-1s"`},
+		{name: "webhook negative retries", actions: []Action{{Name: "lights", Type: "webhook", URL: "http://x", Retries: &negative}}, errorMsg: "Retries must be non-negative"},
+		{name: "mqtt", actions: []Action{{Name: "office", Type: "mqtt", Broker: "ssl://mqtt.example.com:8883", QoS: 1, Retain: true, CAFile: "~/ca.pem"}}},
+		{name: "mqtt bad broker", actions: []Action{{Name: "office", Type: "mqtt", Broker: "mqtt.example.com:1883"}}, errorMsg: "action office: invalid Broker"},
+		{name: "mqtt bad qos", actions: []Action{{Name: "office", Type: "mqtt", Broker: "tcp://x:1883", QoS: 3}}, errorMsg: "invalid QoS 3"},
+		{name: "mqtt cert without key", actions: []Action{{Name: "office", Type: "mqtt", Broker: "tcp://x:1883", CertFile: "c.pem"}}, errorMsg: "CertFile and KeyFile must be set together"},
+		{name: "mqtt bad topic", actions: []Action{{Name: "office", Type: "mqtt", Broker: "tcp://x:1883", Topic: "a/{{.Calendar"}}, errorMsg: `invalid action "office" Topic`},
+		{name: "bad button cmd", actions: []Action{{Name: "a", Type: "notify", Buttons: []Button{{Label: "Join", Cmd: "echo {{"}}}}, errorMsg: `invalid action "a" button "Join" Cmd`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				CalendarID: "test@calendar.google.com",
+				CmdToExec:  "echo hello",
+				TokenFile:  "/path/to/token.json",
+				Actions:    tt.actions,
+			}
+			if tt.cmdToExec != "" {
+				config.CmdToExec = tt.cmdToExec
+			}
+			err := ValidateConfig(&config)
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMsg, err)
+			}
+		})
+	}
+
+	config := Config{CalendarID: "c", CmdToExec: "action:Desktop", TokenFile: "t", Actions: []Action{{Name: " Desktop ", Type: "notify"}}}
+	if err := ValidateConfig(&config); err != nil {
+		t.Fatal(err)
+	}
+	action, ok := config.Action("desktop")
+	if !ok || action.Summary != "{{.Summary}}" || action.Urgency != "normal" || action.AppName != "timeotter" ||
+		action.ButtonWait != "10m" || action.Timeout != "" {
+		t.Errorf("notify defaults not applied: %+v", config.Actions)
+	}
+	config = Config{CalendarID: "c", CmdToExec: "action:lights", TokenFile: "t", Actions: []Action{{Name: "lights", Type: "webhook", URL: "http://x"}}}
+	if err := ValidateConfig(&config); err != nil {
+		t.Fatal(err)
+	}
+	if action := config.Actions[0]; action.Timeout != "10s" || action.Backoff != "1s" || action.Retries == nil || *action.Retries != 3 {
+		t.Errorf("webhook defaults not applied: %+v", action)
+	}
+	if texts := config.Texts(); len(texts) != 0 {
+		t.Errorf("webhook actions have no text templates, got %+v", texts)
+	}
+	config = Config{CalendarID: "c", CmdToExec: "action:office", TokenFile: "t", Actions: []Action{{Name: "office", Type: "mqtt", Broker: "tcp://x:1883"}}}
+	if err := ValidateConfig(&config); err != nil {
+		t.Fatal(err)
+	}
+	if action := config.Actions[0]; action.Topic != "timeotter/{{.Calendar}}/{{.Trigger}}" || action.Timeout != "10s" || action.QoS != 0 {
+		t.Errorf("mqtt defaults not applied: %+v", action)
+	}
+	if name, ok := ActionRef(" action: Desktop"); !ok || name != "desktop" {
+		t.Errorf("ActionRef = %q, %v", name, ok)
+	}
+	if _, ok := ActionRef("notify-send action:desktop"); ok {
+		t.Error("ActionRef matched a shell command")
+	}
 }
@@ -4,6 +4,7 @@ package cron
 import (
 	"fmt"
 	"log"
+	"os"
 	"os/user"
 	"runtime"
 	"strings"
@@ -11,22 +12,13 @@ import (
 	"github.com/bupd/timeotter/pkg/shell"
 )
 
-// AddCrons appends a new cron job entry to the system crontab.
+// AddCrons appends a new cron job entry to the system crontab. The entry
+// is written as is rather than through a shell, so event details in the
+// command cannot run while the crontab is written.
 func AddCrons(cronJob, cmdToExec string) error {
-	cronLocation := GetCronLocation()
-
 	// linux := "/var/spool/cron/bupd"
 	// termux := "/data/data/com.termux/files/usr/var/spool/cron/u0_a323"
-
-	// Shell script to backup the crontab and remove cron jobs below the comment
-	script := fmt.Sprintf(`
-    #!/bin/bash
-
-    # Add a new cron job manually (This is your cron job command)
-    echo "%s %s" >> "%s"
-    `, cronJob, cmdToExec, cronLocation)
-
-	err := shell.ExecuteShellCommand(script)
+	err := appendEntry(GetCronLocation(), cronJob+" "+cmdToExec)
 	if err != nil {
 		log.Fatalf("failed to execute cron add command: %v", err)
 	}
@@ -34,6 +26,22 @@ func AddCrons(cronJob, cmdToExec string) error {
 	return nil
 }
 
+// appendEntry adds one line to the crontab at path.
+func appendEntry(path, entry string) error {
+	if strings.ContainsAny(entry, "\r\n") {
+		return fmt.Errorf("cron entry %q spans several lines", entry)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(entry + "\n"); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 // GetCronLocation returns the platform-specific path to the user's crontab file.
 func GetCronLocation() string {
 	var cronLocation string
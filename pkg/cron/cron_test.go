@@ -1,6 +1,8 @@
 package cron
 
 import (
+	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
@@ -143,3 +145,34 @@ func TestEscapeAwkRegex_Idempotent(t *testing.T) {
 		t.Errorf("double escape failed: got %q", doubleEscaped)
 	}
 }
+
+// TestAppendEntry checks that entries reach the crontab verbatim, without
+// a shell expanding the event details they may carry.
+func TestAppendEntry(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "crontab")
+	pwned := filepath.Join(dir, "pwned")
+	entries := []string{
+		"0 9 * * * notify",
+		"55 9 15 3 * TIMEOTTER_JOIN_URL='https://zoom.us/j/1`touch " + pwned + "`$(touch " + pwned + ")' notify \\%",
+	}
+	for _, entry := range entries {
+		if err := appendEntry(path, entry); err != nil {
+			t.Fatalf("appendEntry(%q): %v", entry, err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Join(entries, "\n") + "\n"; string(data) != want {
+		t.Errorf("got crontab\n%s\nwant\n%s", data, want)
+	}
+	if _, err := os.Stat(pwned); err == nil {
+		t.Error("writing the crontab ran a command from the entry")
+	}
+	if err := appendEntry(path, "* * * * * a\nb"); err == nil {
+		t.Error("expected an error for a multi-line entry")
+	}
+}
@@ -0,0 +1,137 @@
+package fire
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/config"
+	"github.com/bupd/timeotter/pkg/notify"
+)
+
+// RunAction runs the built-in action a for the payload. Besides the outcome
+// it returns the notification button that was clicked, if any, for the
+// caller to carry out.
+func RunAction(ctx context.Context, a config.Action, p Payload) (Result, *config.Button, error) {
+	switch a.Type {
+	case config.ActionNotify:
+		return runNotify(ctx, a, p)
+	case config.ActionWebhook:
+		return runWebhook(ctx, a, p)
+	case config.ActionMQTT:
+		return runMQTT(ctx, a, p)
+	}
+	return Result{Started: time.Now(), ExitCode: -1}, nil, fmt.Errorf("unknown action type %q", a.Type)
+}
+
+// NewNotification renders the notification a shows for the payload. It
+// also returns the buttons shown, in the order of the notification's
+// action keys "0", "1", ... Ack buttons are left out for triggers without
+// events.
+func NewNotification(a config.Action, p Payload) (notify.Notification, []config.Button, error) {
+	n := notify.Notification{AppName: a.AppName, Icon: a.Icon, Timeout: -1}
+	var err error
+	if n.Summary, err = RenderText(a.Summary, p); err != nil {
+		return n, nil, fmt.Errorf("rendering Summary: %w", err)
+	}
+	if n.Body, err = RenderText(a.Body, p); err != nil {
+		return n, nil, fmt.Errorf("rendering Body: %w", err)
+	}
+	if n.Urgency, err = notify.ParseUrgency(a.Urgency); err != nil {
+		return n, nil, err
+	}
+	if a.Timeout != "" {
+		if n.Timeout, err = time.ParseDuration(a.Timeout); err != nil {
+			return n, nil, fmt.Errorf("invalid Timeout: %w", err)
+		}
+	}
+
+	var buttons []config.Button
+	for _, button := range a.Buttons {
+		switch {
+		case button.Ack:
+			if len(p.Events) == 0 {
+				continue
+			}
+		case button.Cmd != "":
+			cmd, err := Render(button.Cmd, p)
+			if err != nil {
+				return n, nil, fmt.Errorf("rendering button %q: %w", button.Label, err)
+			}
+			if strings.TrimSpace(cmd) == "" {
+				continue
+			}
+		}
+		n.Actions = append(n.Actions, notify.Action{Key: strconv.Itoa(len(buttons)), Label: button.Label})
+		buttons = append(buttons, button)
+	}
+	return n, buttons, nil
+}
+
+// runNotify shows the notification of a and, when it has buttons, waits
+// up to ButtonWait for one to be clicked.
+func runNotify(ctx context.Context, a config.Action, p Payload) (Result, *config.Button, error) {
+	result := Result{Started: time.Now(), ExitCode: -1}
+	var out strings.Builder
+	finish := func(button *config.Button, err error) (Result, *config.Button, error) {
+		result.Duration = time.Since(result.Started)
+		result.Output = []byte(out.String())
+		if err == nil {
+			result.ExitCode = 0
+		}
+		return result, button, err
+	}
+
+	n, buttons, err := NewNotification(a, p)
+	if err != nil {
+		return finish(nil, err)
+	}
+	client, err := notify.Dial(a.Bus)
+	if err != nil {
+		return finish(nil, err)
+	}
+	defer client.Close()
+
+	id, err := client.Notify(ctx, n)
+	if err != nil {
+		return finish(nil, err)
+	}
+	fmt.Fprintf(&out, "notification %d: %s\n", id, n.Summary)
+	if len(buttons) == 0 {
+		return finish(nil, nil)
+	}
+
+	waitCtx := ctx
+	wait, _ := time.ParseDuration(a.ButtonWait)
+	if wait > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, wait)
+		defer cancel()
+	}
+	key, err := client.Wait(waitCtx, id)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil:
+		// The buttons do nothing once fire has exited.
+		closeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		_ = client.CloseNotification(closeCtx, id)
+		fmt.Fprintf(&out, "no button clicked within %s\n", wait)
+		return finish(nil, nil)
+	case err != nil:
+		return finish(nil, err)
+	case key == "":
+		out.WriteString("closed without a button\n")
+		return finish(nil, nil)
+	}
+
+	i, err := strconv.Atoi(key)
+	if err != nil || i < 0 || i >= len(buttons) {
+		fmt.Fprintf(&out, "unknown action %q invoked\n", key)
+		return finish(nil, nil)
+	}
+	fmt.Fprintf(&out, "%q clicked\n", buttons[i].Label)
+	return finish(&buttons[i], nil)
+}
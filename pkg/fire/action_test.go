@@ -0,0 +1,128 @@
+package fire
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/config"
+	"github.com/bupd/timeotter/pkg/notify"
+	"github.com/bupd/timeotter/pkg/testutil"
+)
+
+func notifyAction() config.Action {
+	return config.Action{
+		Name:    "desktop",
+		Type:    config.ActionNotify,
+		Summary: "{{.Summary}}",
+		Body:    "{{fmtTime .Start}}–{{fmtTime .End}}{{with .Location}}, {{.}}{{end}}",
+		Urgency: "critical",
+		Icon:    "appointment-soon",
+		AppName: "timeotter",
+		Timeout: "30s",
+		Buttons: []config.Button{
+			{Label: "Join", Cmd: "{{if .JoinURL}}xdg-open {{.JoinURL}}{{end}}"},
+			{Label: "Snooze", Snooze: "5m"},
+			{Label: "Done", Ack: true},
+		},
+		ButtonWait: "2s",
+	}
+}
+
+func TestNewNotification(t *testing.T) {
+	payload := NewPayload("0123456789ab", testTrigger(), time.Now())
+	n, buttons, err := NewNotification(notifyAction(), payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := notify.Notification{
+		AppName: "timeotter",
+		Icon:    "appointment-soon",
+		Summary: "Standup",
+		Body:    "10:00–10:15, Room 1",
+		Urgency: notify.Critical,
+		Timeout: 30 * time.Second,
+		Actions: []notify.Action{{Key: "0", Label: "Join"}, {Key: "1", Label: "Snooze"}, {Key: "2", Label: "Done"}},
+	}
+	if n.Summary != want.Summary || n.Body != want.Body || n.Urgency != want.Urgency || n.Timeout != want.Timeout ||
+		n.Icon != want.Icon || n.AppName != want.AppName || len(n.Actions) != 3 || n.Actions[2] != want.Actions[2] {
+		t.Errorf("got %+v, want %+v", n, want)
+	}
+	if len(buttons) != 3 {
+		t.Errorf("got buttons %+v", buttons)
+	}
+
+	// Without a video link the Join button renders empty and is left out.
+	payload.JoinURL = ""
+	action := notifyAction()
+	action.Timeout = ""
+	n, buttons, err = NewNotification(action, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Timeout != -1 || len(n.Actions) != 2 || n.Actions[0] != (notify.Action{Key: "0", Label: "Snooze"}) || buttons[0].Label != "Snooze" {
+		t.Errorf("unexpected notification %+v with buttons %+v", n, buttons)
+	}
+
+	// There is nothing to acknowledge without events.
+	payload.Events = nil
+	n, buttons, err = NewNotification(action, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(n.Actions) != 1 || len(buttons) != 1 || buttons[0].Label != "Snooze" {
+		t.Errorf("unexpected notification %+v with buttons %+v", n, buttons)
+	}
+}
+
+func TestRunAction_Notify(t *testing.T) {
+	address := testutil.StartSessionBus(t, "")
+	server := testutil.StartNotificationServer(t, address)
+	server.OnNotify(func(s *testutil.NotificationServer, n testutil.Notification) {
+		if n.Summary == "Standup" {
+			s.Invoke(n.ID, "1")
+		}
+	})
+	payload := NewPayload("0123456789ab", testTrigger(), time.Now())
+
+	action := notifyAction()
+	action.Bus = address
+	result, next, err := RunAction(context.Background(), action, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next == nil || next.Snooze != "5m" || result.ExitCode != 0 || !strings.Contains(string(result.Output), `"Snooze" clicked`) {
+		t.Errorf("got %+v and %+v", next, result)
+	}
+
+	// Nobody clicks: the notification is taken down after ButtonWait.
+	action.Summary = "Ignored"
+	action.ButtonWait = "100ms"
+	result, next, err = RunAction(context.Background(), action, payload)
+	if err != nil || next != nil || !strings.Contains(string(result.Output), "no button clicked within 100ms") {
+		t.Errorf("got %+v, %+v, %v", next, result, err)
+	}
+	if closed := server.Closed(); len(closed) != 1 || closed[0] != 2 {
+		t.Errorf("got closed notifications %v, want [2]", closed)
+	}
+
+	// Without buttons fire does not wait.
+	action.Buttons = nil
+	result, next, err = RunAction(context.Background(), action, payload)
+	if err != nil || next != nil || result.ExitCode != 0 || len(server.Notifications()) != 3 {
+		t.Errorf("got %+v, %+v, %v", next, result, err)
+	}
+	if n := server.Notifications()[2]; n.Urgency != 2 || n.Timeout != 30000 || n.Icon != "appointment-soon" {
+		t.Errorf("unexpected notification %+v", n)
+	}
+}
+
+func TestRunAction_NoServer(t *testing.T) {
+	action := notifyAction()
+	action.Bus = testutil.StartSessionBus(t, "")
+	result, _, err := RunAction(context.Background(), action, NewPayload("k", testTrigger(), time.Now()))
+	if err == nil || result.ExitCode != -1 || Status(result, err) == "exit 0" {
+		t.Errorf("expected a failure without a notification server, got %+v, %v", result, err)
+	}
+}
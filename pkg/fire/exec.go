@@ -0,0 +1,147 @@
+package fire
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/config"
+)
+
+// outputTail is how much of a command's output a Result keeps.
+const outputTail = 64 << 10
+
+// minWaitDelay is how long Run waits for output after the command exits,
+// or after SIGTERM when there is no kill grace period.
+const minWaitDelay = 100 * time.Millisecond
+
+// ExecOptions controls how Run executes a command.
+type ExecOptions struct {
+	// Timeout stops the command with SIGTERM, and KillGrace later with
+	// SIGKILL; zero means no timeout.
+	Timeout   time.Duration
+	KillGrace time.Duration
+	// Dir is the working directory; empty keeps the current one.
+	Dir string
+	// Env is added to the environment after the TIMEOTTER_* variables.
+	Env []string
+}
+
+// NewExecOptions converts validated execution settings.
+func NewExecOptions(e config.Exec) (ExecOptions, error) {
+	opts := ExecOptions{Dir: e.Dir, Env: e.Env}
+	var err error
+	if e.Timeout != "" {
+		if opts.Timeout, err = time.ParseDuration(e.Timeout); err != nil {
+			return ExecOptions{}, fmt.Errorf("invalid Timeout: %w", err)
+		}
+	}
+	if e.KillGrace != "" {
+		if opts.KillGrace, err = time.ParseDuration(e.KillGrace); err != nil {
+			return ExecOptions{}, fmt.Errorf("invalid KillGrace: %w", err)
+		}
+	}
+	return opts, nil
+}
+
+// Result describes a finished run.
+type Result struct {
+	Started  time.Time
+	Duration time.Duration
+	// ExitCode is -1 when the command was killed by a signal or did not
+	// start.
+	ExitCode int
+	TimedOut bool
+	// Output is the end of the combined stdout and stderr.
+	Output []byte
+}
+
+// Run runs cmd with sh, adding the payload's variables to the environment
+// and writing the payload as JSON to its stdin. Output is copied to stdout
+// and stderr, which may be nil, and its tail kept in the Result. The
+// command runs in its own process group so a timeout stops everything it
+// started.
+func Run(ctx context.Context, cmd string, p Payload, opts ExecOptions, stdout, stderr io.Writer) (Result, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return Result{ExitCode: -1}, err
+	}
+
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	tail := &tailBuffer{max: outputTail}
+	c := exec.CommandContext(ctx, "sh", "-c", cmd)
+	c.Env = append(append(os.Environ(), p.Env()...), opts.Env...)
+	c.Dir = opts.Dir
+	c.Stdin = bytes.NewReader(data)
+	c.Stdout = teeTo(stdout, tail)
+	c.Stderr = teeTo(stderr, tail)
+	c.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	c.Cancel = func() error { return syscall.Kill(-c.Process.Pid, syscall.SIGTERM) }
+	// WaitDelay also bounds how long Wait keeps reading output from
+	// children sh left running in the background.
+	c.WaitDelay = max(opts.KillGrace, minWaitDelay)
+
+	result := Result{Started: time.Now(), ExitCode: -1}
+	err = c.Run()
+	result.Duration = time.Since(result.Started)
+	result.Output = tail.Bytes()
+	if c.ProcessState != nil {
+		result.ExitCode = c.ProcessState.ExitCode()
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		if c.Process != nil {
+			// Whatever survived SIGTERM is done now.
+			_ = syscall.Kill(-c.Process.Pid, syscall.SIGKILL)
+		}
+		result.TimedOut = true
+		return result, fmt.Errorf("timed out after %s", opts.Timeout)
+	}
+	if errors.Is(err, exec.ErrWaitDelay) {
+		// sh succeeded; only a background child still held its output.
+		err = nil
+	}
+	return result, err
+}
+
+func teeTo(w io.Writer, tail *tailBuffer) io.Writer {
+	if w == nil {
+		return tail
+	}
+	return io.MultiWriter(w, tail)
+}
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	mu  sync.Mutex
+	max int
+	buf []byte
+}
+
+func (t *tailBuffer) Write(b []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, b...)
+	if over := len(t.buf) - t.max; over > 0 {
+		t.buf = append(t.buf[:0], t.buf[over:]...)
+	}
+	return len(b), nil
+}
+
+func (t *tailBuffer) Bytes() []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]byte(nil), t.buf...)
+}
@@ -0,0 +1,86 @@
+package fire
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/config"
+)
+
+func TestRun_DirEnvAndOutput(t *testing.T) {
+	dir := t.TempDir()
+	opts := ExecOptions{Dir: dir, Env: []string{"GREETING=hello", "TIMEOTTER_SUMMARY=overridden"}}
+	result, err := Run(context.Background(), `pwd; echo "$GREETING $TIMEOTTER_SUMMARY"; echo oops >&2`, NewPayload("k", testTrigger(), time.Now()), opts, nil, nil)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	resolved, _ := filepath.EvalSymlinks(dir)
+	for _, want := range []string{resolved + "\n", "hello overridden\n", "oops\n"} {
+		if !strings.Contains(string(result.Output), want) {
+			t.Errorf("output %q is missing %q", result.Output, want)
+		}
+	}
+	if result.ExitCode != 0 || result.TimedOut || result.Duration <= 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestRun_Timeout(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "survived")
+	opts := ExecOptions{Timeout: 200 * time.Millisecond, KillGrace: 200 * time.Millisecond}
+	// The background child ignores SIGTERM, so only the SIGKILL after the
+	// grace period stops it.
+	cmd := `(trap '' TERM; sleep 1; touch ` + marker + `) & echo started; wait`
+
+	start := time.Now()
+	result, err := Run(context.Background(), cmd, NewPayload("k", testTrigger(), time.Now()), opts, nil, nil)
+	if err == nil || !result.TimedOut || !strings.Contains(err.Error(), "timed out after 200ms") {
+		t.Fatalf("expected a timeout, got %+v (%v)", result, err)
+	}
+	if elapsed := time.Since(start); elapsed > 900*time.Millisecond {
+		t.Errorf("Run took %s", elapsed)
+	}
+	if string(result.Output) != "started\n" {
+		t.Errorf("unexpected output %q", result.Output)
+	}
+
+	time.Sleep(1200 * time.Millisecond)
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("the command's process group outlived the timeout")
+	}
+}
+
+func TestRun_BackgroundChild(t *testing.T) {
+	result, err := Run(context.Background(), "sleep 5 & echo done", NewPayload("k", testTrigger(), time.Now()), ExecOptions{}, nil, nil)
+	if err != nil || result.ExitCode != 0 || result.Duration > 2*time.Second {
+		t.Errorf("a background child should not hold up the run: %+v (%v)", result, err)
+	}
+}
+
+func TestNewExecOptions(t *testing.T) {
+	opts, err := NewExecOptions(config.Exec{Timeout: "5m", KillGrace: "10s", Dir: "/tmp", Env: []string{"A=1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Timeout != 5*time.Minute || opts.KillGrace != 10*time.Second || opts.Dir != "/tmp" || len(opts.Env) != 1 {
+		t.Errorf("unexpected options %+v", opts)
+	}
+	if _, err := NewExecOptions(config.Exec{Timeout: "soon"}); err == nil {
+		t.Error("expected an error for an invalid timeout")
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	tail := &tailBuffer{max: 5}
+	for _, s := range []string{"abc", "defg", "h"} {
+		_, _ = tail.Write([]byte(s))
+	}
+	if got := string(tail.Bytes()); got != "defgh" {
+		t.Errorf("got %q", got)
+	}
+}
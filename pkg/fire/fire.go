@@ -0,0 +1,180 @@
+// Package fire runs the command of an installed trigger with the details of
+// the events behind it.
+package fire
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/calendar"
+	"github.com/bupd/timeotter/pkg/cmdtemplate"
+)
+
+// Payload is the JSON document passed to the command on stdin.
+type Payload struct {
+	Key         string    `json:"key"`
+	Trigger     string    `json:"trigger"`
+	Summary     string    `json:"summary"`
+	ScheduledAt time.Time `json:"scheduledAt"`
+	FiredAt     time.Time `json:"firedAt"`
+	JoinURL     string    `json:"joinUrl,omitempty"`
+	GapMinutes  int       `json:"gapMinutes,omitempty"`
+	// Event is the first event behind the trigger and Events all of them;
+	// both are empty for free-slot triggers.
+	Event  *calendar.Event  `json:"event,omitempty"`
+	Events []calendar.Event `json:"events,omitempty"`
+}
+
+// NewPayload describes the trigger stored under key, fired at now.
+func NewPayload(key string, t calendar.StoredTrigger, now time.Time) Payload {
+	p := Payload{
+		Key:         key,
+		Trigger:     t.Name,
+		Summary:     t.Summary,
+		ScheduledAt: t.At,
+		FiredAt:     now,
+		JoinURL:     t.JoinURL,
+		GapMinutes:  t.GapMinutes,
+		Events:      t.Events,
+	}
+	if len(t.Events) > 0 {
+		p.Event = &t.Events[0]
+	}
+	return p
+}
+
+// Env returns the TIMEOTTER_* variables describing the payload. Event
+// details come from the first event; TIMEOTTER_EVENT_IDS lists all of them.
+// Free-slot triggers describe the gap instead.
+func (p Payload) Env() []string {
+	env := []string{
+		"TIMEOTTER_KEY=" + p.Key,
+		"TIMEOTTER_TRIGGER=" + p.Trigger,
+	}
+	if p.JoinURL != "" {
+		env = append(env, "TIMEOTTER_JOIN_URL="+p.JoinURL)
+	}
+	if p.Event == nil {
+		env = append(env, "TIMEOTTER_SUMMARY="+p.Summary)
+		if p.GapMinutes > 0 {
+			env = append(env,
+				"TIMEOTTER_GAP_MINUTES="+strconv.Itoa(p.GapMinutes),
+				"TIMEOTTER_START="+p.ScheduledAt.Format(time.RFC3339),
+				"TIMEOTTER_END="+p.ScheduledAt.Add(time.Duration(p.GapMinutes)*time.Minute).Format(time.RFC3339),
+			)
+		}
+		return env
+	}
+
+	ids := make([]string, 0, len(p.Events))
+	for _, item := range p.Events {
+		ids = append(ids, item.ID)
+	}
+	attendees := make([]string, 0, len(p.Event.Attendees))
+	for _, attendee := range p.Event.Attendees {
+		attendees = append(attendees, attendee.Email)
+	}
+	return append(env,
+		"TIMEOTTER_EVENT_ID="+p.Event.ID,
+		"TIMEOTTER_EVENT_IDS="+strings.Join(ids, ","),
+		"TIMEOTTER_SUMMARY="+p.Event.Summary,
+		"TIMEOTTER_START="+p.Event.Start.Format(time.RFC3339),
+		"TIMEOTTER_END="+p.Event.End.Format(time.RFC3339),
+		"TIMEOTTER_LOCATION="+p.Event.Location,
+		"TIMEOTTER_CALENDAR="+p.Event.Calendar,
+		"TIMEOTTER_ATTENDEES="+strings.Join(attendees, ","),
+	)
+}
+
+// Data is what command templates are rendered against. It embeds the first
+// event behind the trigger, so templates refer to {{.Summary}} or
+// {{.Start}} directly. For free-slot triggers the event describes the gap.
+type Data struct {
+	calendar.Event
+	Key         string
+	Trigger     string
+	ScheduledAt time.Time
+	FiredAt     time.Time
+	JoinURL     string
+	GapMinutes  int
+	Events      []calendar.Event
+}
+
+// TemplateData returns the template data of the payload.
+func (p Payload) TemplateData() Data {
+	d := Data{
+		Key:         p.Key,
+		Trigger:     p.Trigger,
+		ScheduledAt: p.ScheduledAt,
+		FiredAt:     p.FiredAt,
+		JoinURL:     p.JoinURL,
+		GapMinutes:  p.GapMinutes,
+		Events:      p.Events,
+	}
+	if p.Event != nil {
+		d.Event = *p.Event
+	} else {
+		d.Event = calendar.Event{Summary: p.Summary, Start: p.ScheduledAt, End: p.ScheduledAt}
+		if p.GapMinutes > 0 {
+			d.End = p.ScheduledAt.Add(time.Duration(p.GapMinutes) * time.Minute)
+		}
+	}
+	return d
+}
+
+// Render renders the command template cmd for the payload.
+func Render(cmd string, p Payload) (string, error) {
+	tmpl, err := cmdtemplate.Parse("command", cmd)
+	if err != nil {
+		return "", err
+	}
+	return tmpl.Execute(p.TemplateData())
+}
+
+// RenderText renders the text template text, such as a notification body,
+// for the payload.
+func RenderText(text string, p Payload) (string, error) {
+	tmpl, err := cmdtemplate.ParseText("text", text)
+	if err != nil {
+		return "", err
+	}
+	return tmpl.Execute(p.TemplateData())
+}
+
+// Check renders cmd against a sample trigger, catching references to
+// fields or functions that a syntax check alone lets through.
+func Check(cmd string) error {
+	_, err := Render(cmd, samplePayload())
+	return err
+}
+
+// CheckText is Check for text templates.
+func CheckText(text string) error {
+	_, err := RenderText(text, samplePayload())
+	return err
+}
+
+func samplePayload() Payload {
+	start := time.Date(2025, time.January, 6, 9, 0, 0, 0, time.UTC)
+	event := calendar.Event{
+		ID:        "sample",
+		Start:     start,
+		End:       start.Add(30 * time.Minute),
+		Summary:   "Sample event",
+		Organizer: calendar.Person{Email: "organizer@example.com"},
+		Attendees: []calendar.Attendee{{Email: "attendee@example.com"}},
+		Links:     []calendar.Link{{Kind: calendar.LinkVideo, URL: "https://meet.example.com/sample"}},
+		Reminders: []calendar.Reminder{{Method: "popup", Minutes: 10}},
+	}
+	return Payload{
+		Key:         "sample",
+		Trigger:     "start",
+		Summary:     event.Summary,
+		ScheduledAt: start,
+		FiredAt:     start,
+		JoinURL:     event.Links[0].URL,
+		Event:       &event,
+		Events:      []calendar.Event{event},
+	}
+}
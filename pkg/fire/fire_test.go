@@ -0,0 +1,143 @@
+package fire
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/calendar"
+)
+
+func testTrigger() calendar.StoredTrigger {
+	start := time.Date(2025, 3, 15, 10, 0, 0, 0, time.FixedZone("IST", 19800))
+	return calendar.StoredTrigger{
+		Name:    "start",
+		Summary: "Standup + Sync",
+		At:      start.Add(-5 * time.Minute),
+		Cmd:     "notify",
+		JoinURL: "https://meet.google.com/abc-defg-hij",
+		Events: []calendar.Event{
+			{
+				ID:        "a",
+				Summary:   "Standup",
+				Start:     start,
+				End:       start.Add(15 * time.Minute),
+				Location:  "Room 1",
+				Calendar:  "work@example.com",
+				Attendees: []calendar.Attendee{{Email: "me@example.com", Self: true}, {Email: "lead@example.com"}},
+			},
+			{ID: "b", Summary: "Sync", Start: start, End: start.Add(30 * time.Minute)},
+		},
+	}
+}
+
+func TestPayload_Env(t *testing.T) {
+	payload := NewPayload("0123456789ab", testTrigger(), time.Now())
+	got := strings.Join(payload.Env(), "\n")
+	for _, want := range []string{
+		"TIMEOTTER_KEY=0123456789ab",
+		"TIMEOTTER_TRIGGER=start",
+		"TIMEOTTER_EVENT_ID=a",
+		"TIMEOTTER_EVENT_IDS=a,b",
+		"TIMEOTTER_SUMMARY=Standup",
+		"TIMEOTTER_START=2025-03-15T10:00:00+05:30",
+		"TIMEOTTER_END=2025-03-15T10:15:00+05:30",
+		"TIMEOTTER_LOCATION=Room 1",
+		"TIMEOTTER_JOIN_URL=https://meet.google.com/abc-defg-hij",
+		"TIMEOTTER_CALENDAR=work@example.com",
+		"TIMEOTTER_ATTENDEES=me@example.com,lead@example.com",
+	} {
+		if !strings.Contains(got, want+"\n") && !strings.HasSuffix(got, want) {
+			t.Errorf("missing %s in\n%s", want, got)
+		}
+	}
+}
+
+func TestPayload_EnvFreeSlot(t *testing.T) {
+	at := time.Date(2025, 3, 17, 14, 0, 0, 0, time.UTC)
+	payload := NewPayload("k", calendar.StoredTrigger{Name: "free-slot", Summary: "Free slot (90 min)", At: at, GapMinutes: 90}, at)
+	got := strings.Join(payload.Env(), "\n")
+	for _, want := range []string{
+		"TIMEOTTER_SUMMARY=Free slot (90 min)",
+		"TIMEOTTER_GAP_MINUTES=90",
+		"TIMEOTTER_START=2025-03-17T14:00:00Z",
+		"TIMEOTTER_END=2025-03-17T15:30:00Z",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %s in\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "TIMEOTTER_EVENT_ID=") {
+		t.Errorf("free slot should not have an event ID:\n%s", got)
+	}
+}
+
+func TestRun(t *testing.T) {
+	payload := NewPayload("0123456789ab", testTrigger(), time.Date(2025, 3, 15, 4, 25, 0, 0, time.UTC))
+
+	var stdout, stderr bytes.Buffer
+	cmd := `printf '%s|%s\n' "$TIMEOTTER_SUMMARY" "$TIMEOTTER_ATTENDEES"; cat`
+	if _, err := Run(context.Background(), cmd, payload, ExecOptions{}, &stdout, &stderr); err != nil {
+		t.Fatalf("Run: %v (%s)", err, stderr.String())
+	}
+
+	first, rest, _ := strings.Cut(stdout.String(), "\n")
+	if first != "Standup|me@example.com,lead@example.com" {
+		t.Errorf("unexpected environment output %q", first)
+	}
+	var got Payload
+	if err := json.Unmarshal([]byte(rest), &got); err != nil {
+		t.Fatalf("stdin is not the JSON payload: %v\n%s", err, rest)
+	}
+	if got.Key != "0123456789ab" || got.Event == nil || got.Event.ID != "a" || len(got.Events) != 2 ||
+		got.Event.Attendees[1].Email != "lead@example.com" || !got.Event.Start.Equal(payload.Event.Start) {
+		t.Errorf("unexpected payload %+v", got)
+	}
+
+	result, err := Run(context.Background(), "exit 3", payload, ExecOptions{}, &stdout, &stderr)
+	if err == nil || result.ExitCode != 3 {
+		t.Errorf("expected exit status 3 to be reported, got %d (%v)", result.ExitCode, err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	payload := NewPayload("0123456789ab", testTrigger(), time.Now())
+	got, err := Render(`notify-send "{{.Summary}}" "starts {{.Start | fmtTime}}" {{.JoinURL}} {{len .Events}}`, payload)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	want := `notify-send "Standup" "starts 10:00" 'https://meet.google.com/abc-defg-hij' '2'`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	at := time.Date(2025, 3, 17, 14, 0, 0, 0, time.UTC)
+	slot := NewPayload("k", calendar.StoredTrigger{Name: "free-slot", Summary: "Free slot (90 min)", At: at, GapMinutes: 90}, at)
+	got, err = Render(`echo "{{.Summary}} until {{fmtTime .End}}"`, slot)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if want := `echo "Free slot (90 min) until 15:30"`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	for _, cmd := range []string{
+		"notify-send 'Meeting'",
+		`notify-send "{{.Summary}}" "{{.Start | fmtTime}}"`,
+		`echo {{(index .Attendees 0).Email}} {{.Organizer.Email}} {{.GapMinutes}}`,
+	} {
+		if err := Check(cmd); err != nil {
+			t.Errorf("Check(%s): %v", cmd, err)
+		}
+	}
+	for _, cmd := range []string{`echo {{.Title}}`, `echo {{.Start | truncate 3}}`} {
+		if err := Check(cmd); err == nil {
+			t.Errorf("Check(%s): expected an error", cmd)
+		}
+	}
+}
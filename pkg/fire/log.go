@@ -0,0 +1,111 @@
+package fire
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/history"
+)
+
+// Log is a command log file that is rotated once it grows past MaxBytes,
+// keeping Backups old copies as path.1, path.2, ...
+type Log struct {
+	Path     string
+	MaxBytes int64
+	Backups  int
+}
+
+// Append records a run: a header naming the trigger and command, the
+// command's output and its exit status. The entry is written with a single
+// write so runs finishing at the same time do not interleave.
+func (l Log) Append(p Payload, cmd string, r Result, runErr error) error {
+	if err := os.MkdirAll(filepath.Dir(l.Path), 0750); err != nil {
+		return err
+	}
+	if err := l.rotate(); err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "=== %s %s %s %q: %s\n", r.Started.Format(time.RFC3339), p.Key, p.Trigger, p.Summary, cmd)
+	b.Write(r.Output)
+	if len(r.Output) > 0 && r.Output[len(r.Output)-1] != '\n' {
+		b.WriteByte('\n')
+	}
+	fmt.Fprintf(&b, "=== %s after %s\n", Status(r, runErr), r.Duration.Round(time.Millisecond))
+
+	f, err := os.OpenFile(l.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b.Bytes()); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rotate moves the log aside once it has reached MaxBytes. A MaxBytes of
+// zero never rotates.
+func (l Log) rotate() error {
+	info, err := os.Stat(l.Path)
+	if os.IsNotExist(err) || (err == nil && (l.MaxBytes <= 0 || info.Size() < l.MaxBytes)) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if l.Backups <= 0 {
+		return os.Remove(l.Path)
+	}
+	for i := l.Backups - 1; i >= 1; i-- {
+		err := os.Rename(l.backup(i), l.backup(i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(l.Path, l.backup(1))
+}
+
+func (l Log) backup(n int) string {
+	return l.Path + "." + strconv.Itoa(n)
+}
+
+// Status describes how a run ended, e.g. "exit 0" or "timed out".
+func Status(r Result, err error) string {
+	switch {
+	case r.TimedOut:
+		return "timed out"
+	case r.ExitCode >= 0:
+		return "exit " + strconv.Itoa(r.ExitCode)
+	case err != nil:
+		return "failed: " + err.Error()
+	default:
+		return "killed"
+	}
+}
+
+// HistoryEntry describes a run of cmd for the history file.
+func HistoryEntry(p Payload, cmd string, r Result, err error) history.Entry {
+	entry := history.Entry{
+		Key:         p.Key,
+		Trigger:     p.Trigger,
+		Summary:     p.Summary,
+		ScheduledAt: p.ScheduledAt,
+		FiredAt:     p.FiredAt,
+		Cmd:         cmd,
+		ExitCode:    r.ExitCode,
+		Status:      Status(r, err),
+		DurationMS:  r.Duration.Milliseconds(),
+		Output:      history.TrimOutput(r.Output),
+	}
+	for _, item := range p.Events {
+		entry.EventIDs = append(entry.EventIDs, item.ID)
+	}
+	return entry
+}
@@ -0,0 +1,95 @@
+package fire
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLog_Append(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "commands.log")
+	l := Log{Path: path, MaxBytes: 1 << 20, Backups: 2}
+	payload := NewPayload("0123456789ab", testTrigger(), time.Now())
+	started := time.Date(2025, 3, 15, 9, 55, 1, 0, time.UTC)
+
+	if err := l.Append(payload, "notify-send hi", Result{Started: started, Duration: 1500 * time.Millisecond, Output: []byte("sent")}, nil); err != nil {
+		t.Fatal(err)
+	}
+	failed := Result{Started: started, Duration: time.Second, ExitCode: 2, Output: []byte("no display\n")}
+	if err := l.Append(payload, "notify-send hi", failed, errors.New("exit status 2")); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `=== 2025-03-15T09:55:01Z 0123456789ab start "Standup + Sync": notify-send hi
+sent
+=== exit 0 after 1.5s
+=== 2025-03-15T09:55:01Z 0123456789ab start "Standup + Sync": notify-send hi
+no display
+=== exit 2 after 1s
+`
+	if string(data) != want {
+		t.Errorf("got\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestLog_Rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "commands.log")
+	l := Log{Path: path, MaxBytes: 10, Backups: 2}
+	payload := NewPayload("k", testTrigger(), time.Now())
+
+	for _, out := range []string{"first", "second", "third", "fourth"} {
+		if err := l.Append(payload, "cmd", Result{Output: []byte(out)}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for file, want := range map[string]string{path: "fourth", path + ".1": "third", path + ".2": "second"} {
+		data, err := os.ReadFile(file)
+		if err != nil || !strings.Contains(string(data), want) {
+			t.Errorf("%s: want %q, got %q (%v)", file, want, data, err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); err == nil {
+		t.Error("kept more backups than configured")
+	}
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		result Result
+		err    error
+		want   string
+	}{
+		{Result{ExitCode: 0}, nil, "exit 0"},
+		{Result{ExitCode: 1}, errors.New("exit status 1"), "exit 1"},
+		{Result{ExitCode: -1, TimedOut: true}, errors.New("timed out"), "timed out"},
+		{Result{ExitCode: -1}, errors.New(`exec: "sh": not found`), `failed: exec: "sh": not found`},
+		{Result{ExitCode: -1}, nil, "killed"},
+	}
+	for _, tt := range tests {
+		if got := Status(tt.result, tt.err); got != tt.want {
+			t.Errorf("Status(%+v) = %q, want %q", tt.result, got, tt.want)
+		}
+	}
+}
+
+func TestHistoryEntry(t *testing.T) {
+	fired := time.Date(2025, 3, 15, 4, 25, 3, 0, time.UTC)
+	payload := NewPayload("0123456789ab", testTrigger(), fired)
+	result := Result{Started: fired, Duration: 1500 * time.Millisecond, ExitCode: -1, TimedOut: true, Output: []byte("partial")}
+
+	entry := HistoryEntry(payload, "notify-send 'Standup'", result, errors.New("timed out after 1s"))
+	if entry.Key != "0123456789ab" || entry.Trigger != "start" || entry.Summary != "Standup + Sync" ||
+		strings.Join(entry.EventIDs, ",") != "a,b" || !entry.FiredAt.Equal(fired) || !entry.ScheduledAt.Equal(payload.ScheduledAt) {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if entry.Status != "timed out" || entry.ExitCode != -1 || entry.DurationMS != 1500 || entry.Output != "partial" || !entry.Failed() {
+		t.Errorf("unexpected outcome %+v", entry)
+	}
+}
@@ -0,0 +1,122 @@
+package fire
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/config"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// runMQTT publishes the payload as JSON to the action's topic.
+func runMQTT(ctx context.Context, a config.Action, p Payload) (Result, *config.Button, error) {
+	result := Result{Started: time.Now(), ExitCode: -1}
+	var out strings.Builder
+	finish := func(err error) (Result, *config.Button, error) {
+		result.Duration = time.Since(result.Started)
+		result.Output = []byte(out.String())
+		if err == nil {
+			result.ExitCode = 0
+		}
+		return result, nil, err
+	}
+
+	topic, err := RenderText(a.Topic, p)
+	if err != nil {
+		return finish(fmt.Errorf("rendering Topic: %w", err))
+	}
+	if topic == "" || strings.ContainsAny(topic, "+#\x00") {
+		return finish(fmt.Errorf("invalid topic %q", topic))
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return finish(err)
+	}
+	timeout, err := time.ParseDuration(a.Timeout)
+	if err != nil {
+		return finish(fmt.Errorf("invalid Timeout: %w", err))
+	}
+	opts, err := newMQTTOptions(a, timeout)
+	if err != nil {
+		return finish(err)
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	client := mqtt.NewClient(opts)
+	if err := waitToken(ctx, client.Connect(), timeout); err != nil {
+		return finish(fmt.Errorf("connecting to %s: %w", a.Broker, err))
+	}
+	defer client.Disconnect(250)
+
+	if err := waitToken(ctx, client.Publish(topic, byte(a.QoS), a.Retain, body), timeout); err != nil {
+		return finish(fmt.Errorf("publishing to %s: %w", topic, err))
+	}
+	fmt.Fprintf(&out, "published %d bytes to %s (QoS %d", len(body), topic, a.QoS)
+	if a.Retain {
+		out.WriteString(", retained")
+	}
+	out.WriteString(")\n")
+	return finish(nil)
+}
+
+// newMQTTOptions configures a client that connects once, without the
+// reconnects meant for long-running clients.
+func newMQTTOptions(a config.Action, timeout time.Duration) (*mqtt.ClientOptions, error) {
+	opts := mqtt.NewClientOptions().
+		AddBroker(a.Broker).
+		SetClientID(fmt.Sprintf("timeotter-%d", os.Getpid())).
+		SetUsername(a.Username).
+		SetPassword(a.Password).
+		SetProtocolVersion(4).
+		SetCleanSession(true).
+		SetAutoReconnect(false).
+		SetConnectRetry(false).
+		SetConnectTimeout(timeout).
+		SetWriteTimeout(timeout)
+
+	if a.CAFile == "" && a.CertFile == "" {
+		return opts, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if a.CAFile != "" {
+		ca, err := os.ReadFile(a.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates in %s", a.CAFile)
+		}
+	}
+	if a.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(a.CertFile, a.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return opts.SetTLSConfig(tlsConfig), nil
+}
+
+// waitToken waits for an MQTT operation to complete.
+func waitToken(ctx context.Context, token mqtt.Token, timeout time.Duration) error {
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("timed out after %s", timeout)
+		}
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,128 @@
+package fire
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/config"
+	"github.com/bupd/timeotter/pkg/testutil"
+)
+
+func mqttAction(broker string) config.Action {
+	return config.Action{
+		Name:    "office",
+		Type:    config.ActionMQTT,
+		Broker:  broker,
+		Topic:   "timeotter/{{.Calendar}}/{{.Trigger}}",
+		Timeout: "5s",
+	}
+}
+
+// waitMessages waits for the broker to have n messages; QoS 0 publishes
+// complete once written, before the broker has read them.
+func waitMessages(t *testing.T, broker *testutil.MQTTBroker, n int) []testutil.MQTTMessage {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(broker.Messages()) < n && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	messages := broker.Messages()
+	if len(messages) != n {
+		t.Fatalf("got %d messages, want %d", len(messages), n)
+	}
+	return messages
+}
+
+func TestRunAction_MQTT(t *testing.T) {
+	broker := testutil.StartMQTTBroker(t, nil)
+	payload := NewPayload("0123456789ab", testTrigger(), time.Now())
+
+	for qos := 0; qos <= 2; qos++ {
+		action := mqttAction("tcp://" + broker.Addr())
+		action.QoS = qos
+		action.Retain = qos == 1
+		result, next, err := RunAction(context.Background(), action, payload)
+		if err != nil || next != nil || result.ExitCode != 0 {
+			t.Fatalf("QoS %d: got %+v, %+v, %v", qos, next, result, err)
+		}
+		if !strings.Contains(string(result.Output), "to timeotter/work@example.com/start") {
+			t.Errorf("QoS %d: unexpected output %q", qos, result.Output)
+		}
+	}
+
+	messages := waitMessages(t, broker, 3)
+	sort.Slice(messages, func(i, j int) bool { return messages[i].QoS < messages[j].QoS })
+	for i, msg := range messages {
+		if msg.Topic != "timeotter/work@example.com/start" || msg.QoS != byte(i) || msg.Retain != (i == 1) ||
+			!strings.HasPrefix(msg.ClientID, "timeotter-") {
+			t.Errorf("unexpected message %+v", msg)
+		}
+		var got Payload
+		if err := json.Unmarshal(msg.Payload, &got); err != nil || got.Key != "0123456789ab" || got.Event.Summary != "Standup" {
+			t.Errorf("unexpected payload %s (%v)", msg.Payload, err)
+		}
+	}
+}
+
+func TestRunAction_MQTTAuth(t *testing.T) {
+	broker := testutil.StartMQTTBroker(t, nil)
+	broker.Username, broker.Password = "otter", "s3cret"
+	payload := NewPayload("k", testTrigger(), time.Now())
+
+	action := mqttAction("tcp://" + broker.Addr())
+	action.Username, action.Password = "otter", "wrong"
+	if _, _, err := RunAction(context.Background(), action, payload); err == nil || !strings.Contains(err.Error(), "connecting to") {
+		t.Errorf("expected a refused connection, got %v", err)
+	}
+
+	action.Password = "s3cret"
+	if _, _, err := RunAction(context.Background(), action, payload); err != nil {
+		t.Fatal(err)
+	}
+	waitMessages(t, broker, 1)
+}
+
+func TestRunAction_MQTTTLS(t *testing.T) {
+	serverTLS, caPEM := testutil.SelfSignedTLS(t)
+	broker := testutil.StartMQTTBroker(t, serverTLS)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, caPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	payload := NewPayload("k", testTrigger(), time.Now())
+
+	action := mqttAction("ssl://" + broker.Addr())
+	action.Timeout = "2s"
+	if _, _, err := RunAction(context.Background(), action, payload); err == nil {
+		t.Error("expected the self-signed certificate to be rejected without CAFile")
+	}
+
+	action.CAFile = caFile
+	if _, _, err := RunAction(context.Background(), action, payload); err != nil {
+		t.Fatal(err)
+	}
+	waitMessages(t, broker, 1)
+}
+
+func TestRunAction_MQTTErrors(t *testing.T) {
+	payload := NewPayload("k", testTrigger(), time.Now())
+
+	action := mqttAction("tcp://127.0.0.1:1")
+	if _, _, err := RunAction(context.Background(), action, payload); err == nil || !strings.Contains(err.Error(), "connecting to tcp://127.0.0.1:1") {
+		t.Errorf("got %v", err)
+	}
+
+	broker := testutil.StartMQTTBroker(t, nil)
+	action = mqttAction("tcp://" + broker.Addr())
+	action.Topic = "timeotter/+/{{.Trigger}}"
+	result, _, err := RunAction(context.Background(), action, payload)
+	if err == nil || !strings.Contains(err.Error(), `invalid topic "timeotter/+/start"`) || result.ExitCode != -1 {
+		t.Errorf("got %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,150 @@
+package fire
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/config"
+)
+
+// SignatureHeader carries the HMAC-SHA256 of a webhook body, keyed with
+// the action's Secret, as "sha256=<hex>".
+const SignatureHeader = "X-Timeotter-Signature-256"
+
+// maxRetryAfter caps how long a Retry-After header can make a webhook wait.
+const maxRetryAfter = time.Minute
+
+// Signature returns the SignatureHeader value of body for secret.
+func Signature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// runWebhook POSTs the payload as JSON to the action's URL, retrying
+// network errors, 429 and 5xx responses with exponential backoff.
+func runWebhook(ctx context.Context, a config.Action, p Payload) (Result, *config.Button, error) {
+	result := Result{Started: time.Now(), ExitCode: -1}
+	var out strings.Builder
+	finish := func(err error) (Result, *config.Button, error) {
+		result.Duration = time.Since(result.Started)
+		result.Output = []byte(out.String())
+		if err == nil {
+			result.ExitCode = 0
+		}
+		return result, nil, err
+	}
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		return finish(err)
+	}
+	timeout, err := time.ParseDuration(a.Timeout)
+	if err != nil {
+		return finish(fmt.Errorf("invalid Timeout: %w", err))
+	}
+	backoff, err := time.ParseDuration(a.Backoff)
+	if err != nil {
+		return finish(fmt.Errorf("invalid Backoff: %w", err))
+	}
+	retries := 0
+	if a.Retries != nil {
+		retries = *a.Retries
+	}
+
+	for attempt := 1; ; attempt++ {
+		wait, retry, err := postWebhook(ctx, a, body, timeout, &out)
+		if err == nil {
+			return finish(nil)
+		}
+		fmt.Fprintf(&out, "attempt %d: %v\n", attempt, err)
+		if !retry || attempt > retries {
+			if attempt > 1 {
+				err = fmt.Errorf("%w (after %d attempts)", err, attempt)
+			}
+			return finish(err)
+		}
+
+		wait = max(wait, backoff)
+		backoff *= 2
+		select {
+		case <-ctx.Done():
+			return finish(ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+}
+
+// postWebhook makes one request. On failure it reports whether the request
+// is worth retrying and how long the server asked to wait first.
+func postWebhook(parent context.Context, a config.Action, body []byte, timeout time.Duration, out io.Writer) (time.Duration, bool, error) {
+	ctx := parent
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "timeotter")
+	for name, value := range a.Headers {
+		req.Header.Set(name, value)
+	}
+	if a.Secret != "" {
+		req.Header.Set(SignatureHeader, Signature(a.Secret, body))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// Errors name the URL, which may hold a token; keep only the cause.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		if errors.Is(err, context.DeadlineExceeded) && parent.Err() == nil {
+			err = fmt.Errorf("timed out after %s", timeout)
+		}
+		return 0, parent.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		fmt.Fprintf(out, "%s\n", resp.Status)
+		return 0, false, nil
+	}
+	err = fmt.Errorf("%s", resp.Status)
+	if text := strings.TrimSpace(string(snippet)); text != "" {
+		err = fmt.Errorf("%s: %s", resp.Status, truncateLine(text, 200))
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	var wait time.Duration
+	if seconds, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil && seconds > 0 {
+		wait = min(time.Duration(seconds)*time.Second, maxRetryAfter)
+	}
+	return wait, retry, err
+}
+
+// truncateLine keeps the first line of s, cut to n bytes.
+func truncateLine(s string, n int) string {
+	s, _, _ = strings.Cut(s, "\n")
+	if len(s) > n {
+		s = s[:n] + "…"
+	}
+	return s
+}
@@ -0,0 +1,133 @@
+package fire
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/config"
+)
+
+func webhookAction(url string, retries int) config.Action {
+	return config.Action{
+		Name:    "lights",
+		Type:    config.ActionWebhook,
+		URL:     url,
+		Headers: map[string]string{"authorization": "Bearer token"},
+		Secret:  "s3cret",
+		Timeout: "2s",
+		Retries: &retries,
+		Backoff: "10ms",
+	}
+}
+
+func TestSignature(t *testing.T) {
+	got := Signature("key", []byte("The quick brown fox jumps over the lazy dog"))
+	if want := "sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestRunAction_Webhook(t *testing.T) {
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	payload := NewPayload("0123456789ab", testTrigger(), time.Now())
+	result, next, err := RunAction(context.Background(), webhookAction(server.URL+"/on-air", 3), payload)
+	if err != nil || next != nil || result.ExitCode != 0 || !strings.Contains(string(result.Output), "204 No Content") {
+		t.Fatalf("got %+v, %+v, %v", next, result, err)
+	}
+
+	if received.Method != http.MethodPost || received.URL.Path != "/on-air" ||
+		received.Header.Get("Content-Type") != "application/json" || received.Header.Get("Authorization") != "Bearer token" {
+		t.Errorf("unexpected request %s %s %v", received.Method, received.URL, received.Header)
+	}
+	if got := received.Header.Get(SignatureHeader); got != Signature("s3cret", body) {
+		t.Errorf("got signature %q, want %q", got, Signature("s3cret", body))
+	}
+	var got Payload
+	if err := json.Unmarshal(body, &got); err != nil || got.Key != "0123456789ab" || got.Event == nil || got.Event.Summary != "Standup" {
+		t.Errorf("unexpected body %s (%v)", body, err)
+	}
+}
+
+func TestRunAction_WebhookRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		retries  int
+		requests int32
+		wantErr  string
+	}{
+		{name: "recovers", statuses: []int{503, 502, 200}, retries: 3, requests: 3},
+		{name: "rate limited", statuses: []int{429, 200}, retries: 1, requests: 2},
+		{name: "gives up", statuses: []int{500, 500, 500, 500}, retries: 2, requests: 3, wantErr: "500 Internal Server Error: boom (after 3 attempts)"},
+		{name: "client error", statuses: []int{400, 200}, retries: 3, requests: 1, wantErr: "400 Bad Request: boom"},
+		{name: "no retries", statuses: []int{503, 200}, retries: 0, requests: 1, wantErr: "503 Service Unavailable: boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tt.statuses[requests.Add(1)-1]
+				w.WriteHeader(status)
+				if status >= 300 {
+					_, _ = io.WriteString(w, "boom\nmore detail")
+				}
+			}))
+			defer server.Close()
+
+			result, _, err := RunAction(context.Background(), webhookAction(server.URL, tt.retries), NewPayload("k", testTrigger(), time.Now()))
+			if got := requests.Load(); got != tt.requests {
+				t.Errorf("got %d requests, want %d", got, tt.requests)
+			}
+			if tt.wantErr == "" {
+				if err != nil || result.ExitCode != 0 || !strings.Contains(string(result.Output), "attempt 1: ") {
+					t.Errorf("got %+v, %v", result, err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr || result.ExitCode != -1 {
+				t.Errorf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunAction_WebhookTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	action := webhookAction(server.URL+"/?token=hidden", 0)
+	action.Timeout = "50ms"
+	_, _, err := RunAction(context.Background(), action, NewPayload("k", testTrigger(), time.Now()))
+	if err == nil || err.Error() != "timed out after 50ms" {
+		t.Errorf("got %v, want a timeout", err)
+	}
+
+	// Connection errors do not repeat the URL and its token.
+	action.URL = "http://127.0.0.1:1/?token=hidden"
+	_, _, err = RunAction(context.Background(), action, NewPayload("k", testTrigger(), time.Now()))
+	if err == nil || strings.Contains(err.Error(), "hidden") {
+		t.Errorf("got %v", err)
+	}
+}
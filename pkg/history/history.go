@@ -0,0 +1,244 @@
+// Package history records every command run by `timeotter fire` in a JSON
+// Lines file and reads it back for `timeotter history`.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// outputTail is how much of a run's output an entry keeps.
+const outputTail = 2 << 10
+
+// Entry is one fire attempt.
+type Entry struct {
+	Key         string    `json:"key"`
+	Trigger     string    `json:"trigger"`
+	Summary     string    `json:"summary"`
+	EventIDs    []string  `json:"eventIds,omitempty"`
+	ScheduledAt time.Time `json:"scheduledAt"`
+	FiredAt     time.Time `json:"firedAt"`
+	Cmd         string    `json:"cmd"`
+	// ExitCode is -1 when the command did not start, timed out or was
+	// killed; Status says which.
+	ExitCode   int    `json:"exitCode"`
+	Status     string `json:"status"`
+	DurationMS int64  `json:"durationMs"`
+	Output     string `json:"output,omitempty"`
+}
+
+// Failed reports whether the run did not exit successfully.
+func (e Entry) Failed() bool {
+	return e.ExitCode != 0
+}
+
+// Duration returns how long the command ran.
+func (e Entry) Duration() time.Duration {
+	return time.Duration(e.DurationMS) * time.Millisecond
+}
+
+// TrimOutput keeps the end of output that fits in an entry.
+func TrimOutput(output []byte) string {
+	if len(output) > outputTail {
+		output = output[len(output)-outputTail:]
+	}
+	return string(output)
+}
+
+// Append adds entry to the history file at path.
+func Append(path string, entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+	f, err := openLocked(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load reads the history file at path, oldest entry first. A missing file
+// is an empty history; lines that cannot be parsed are skipped.
+func Load(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parse(data), nil
+}
+
+func parse(data []byte) []Entry {
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for scanner.Scan() {
+		var entry Entry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Prune removes the entries fired before cutoff from the history file at
+// path. The pruned file replaces it atomically while the lock is held, so
+// runs appended meanwhile are kept.
+func Prune(path string, cutoff time.Time) (err error) {
+	f, err := openLocked(path, os.O_RDONLY)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// Closing releases the lock, once the pruned file is in place.
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	var kept bytes.Buffer
+	removed := false
+	for _, entry := range parse(data) {
+		if entry.FiredAt.Before(cutoff) {
+			removed = true
+			continue
+		}
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		kept.Write(append(line, '\n'))
+	}
+	if !removed {
+		return nil
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, kept.Bytes(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// openLocked opens path and takes an exclusive lock on it, released when
+// the file is closed. When Prune replaced the file while it waited for the
+// lock, it opens the new one.
+func openLocked(path string, flag int) (*os.File, error) {
+	for {
+		f, err := os.OpenFile(path, flag, 0600)
+		if err != nil {
+			return nil, err
+		}
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("locking %s: %w", path, err)
+		}
+		locked, err := f.Stat()
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		current, err := os.Stat(path)
+		if err == nil && os.SameFile(locked, current) {
+			return f, nil
+		}
+		_ = f.Close()
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+}
+
+// Filter selects history entries.
+type Filter struct {
+	// Since drops entries fired before it, unless zero.
+	Since      time.Time
+	FailedOnly bool
+}
+
+// Select returns the entries matching f.
+func (f Filter) Select(entries []Entry) []Entry {
+	var selected []Entry
+	for _, entry := range entries {
+		if entry.FiredAt.Before(f.Since) || (f.FailedOnly && !entry.Failed()) {
+			continue
+		}
+		selected = append(selected, entry)
+	}
+	return selected
+}
+
+// Write prints entries as a table, one row per run.
+func Write(w io.Writer, entries []Entry) {
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "No runs recorded.")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIRED\tDELAY\tTRIGGER\tEVENT\tSTATUS\tDURATION\tCOMMAND")
+	for _, e := range entries {
+		delay := e.FiredAt.Sub(e.ScheduledAt).Round(time.Second)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", e.FiredAt.Format("Mon 2006-01-02 15:04:05"), delay,
+			e.Trigger, e.Summary, e.Status, e.Duration().Round(time.Millisecond), e.Cmd)
+	}
+	_ = tw.Flush()
+}
+
+// WriteJSON prints entries as JSON Lines, the format of the history file.
+func WriteJSON(w io.Writer, entries []Entry) error {
+	enc := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseSince interprets the argument of `history --since`: a duration
+// before now such as "36h" or "7d", a local date such as "2025-03-01", or
+// an RFC 3339 time.
+func ParseSince(s string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q (want a duration like 24h or 7d, a date or an RFC 3339 time)", s)
+}
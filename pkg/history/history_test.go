@@ -0,0 +1,231 @@
+package history
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func entryAt(key string, fired time.Time, exitCode int) Entry {
+	status := "exit 0"
+	if exitCode != 0 {
+		status = "exit 1"
+	}
+	return Entry{
+		Key:         key,
+		Trigger:     "start",
+		Summary:     "Standup " + key,
+		ScheduledAt: fired.Add(-2 * time.Second),
+		FiredAt:     fired,
+		Cmd:         "notify-send hi",
+		ExitCode:    exitCode,
+		Status:      status,
+		DurationMS:  1250,
+	}
+}
+
+func TestAppendLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "history.jsonl")
+	if entries, err := Load(path); err != nil || len(entries) != 0 {
+		t.Fatalf("missing file: got %v, %v", entries, err)
+	}
+
+	now := time.Date(2025, 3, 15, 9, 55, 0, 0, time.UTC)
+	for i, key := range []string{"a", "b", "c"} {
+		if err := Append(path, entryAt(key, now.Add(time.Duration(i)*time.Minute), i%2)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A torn line from a crash must not hide the rest of the history.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("{\"key\": \"broken\n")
+	_ = f.Close()
+	if err := Append(path, entryAt("d", now.Add(time.Hour), 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, e := range entries {
+		keys = append(keys, e.Key)
+	}
+	if strings.Join(keys, ",") != "a,b,c,d" {
+		t.Errorf("got keys %v", keys)
+	}
+	if !entries[1].Failed() || entries[1].Duration() != 1250*time.Millisecond {
+		t.Errorf("unexpected entry %+v", entries[1])
+	}
+}
+
+func TestAppend_Concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	now := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := Append(path, entryAt("k", now, 0)); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if entries, _ := Load(path); len(entries) != 20 {
+		t.Errorf("got %d entries, want 20", len(entries))
+	}
+}
+
+func TestPrune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	now := time.Date(2025, 3, 15, 9, 0, 0, 0, time.UTC)
+	for i, key := range []string{"old", "older", "new"} {
+		fired := now.AddDate(0, 0, -40+i*15)
+		if key == "new" {
+			fired = now
+		}
+		if err := Append(path, entryAt(key, fired, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := Prune(path, now.AddDate(0, 0, -30)); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Key != "older" || entries[1].Key != "new" {
+		t.Errorf("unexpected entries after prune %+v", entries)
+	}
+	if err := Prune(filepath.Join(t.TempDir(), "missing.jsonl"), now); err != nil {
+		t.Errorf("pruning a missing file: %v", err)
+	}
+}
+
+// TestPrune_ConcurrentAppend checks that runs appended while the file is
+// pruned and replaced are not lost.
+func TestPrune_ConcurrentAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	now := time.Now()
+	for i := 0; i < 50; i++ {
+		if err := Append(path, entryAt("old", now.AddDate(0, 0, -40), 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := Append(path, entryAt("new", now, 0)); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := Prune(path, now.AddDate(0, 0, -30)); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := Prune(path, now.AddDate(0, 0, -30)); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _ := Load(path)
+	if len(entries) != 20 {
+		t.Errorf("got %d entries, want the 20 new ones", len(entries))
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	now := time.Date(2025, 3, 15, 9, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		entryAt("a", now.Add(-48*time.Hour), 1),
+		entryAt("b", now.Add(-2*time.Hour), 0),
+		entryAt("c", now.Add(-time.Hour), 1),
+	}
+	tests := []struct {
+		filter Filter
+		want   string
+	}{
+		{Filter{}, "a,b,c"},
+		{Filter{FailedOnly: true}, "a,c"},
+		{Filter{Since: now.Add(-24 * time.Hour)}, "b,c"},
+		{Filter{Since: now.Add(-24 * time.Hour), FailedOnly: true}, "c"},
+	}
+	for _, tt := range tests {
+		var keys []string
+		for _, e := range tt.filter.Select(entries) {
+			keys = append(keys, e.Key)
+		}
+		if got := strings.Join(keys, ","); got != tt.want {
+			t.Errorf("%+v: got %s, want %s", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2025, 3, 15, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"36h", now.Add(-36 * time.Hour)},
+		{"7d", now.AddDate(0, 0, -7)},
+		{"2025-03-01", time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"2025-03-14T18:30:00+05:30", time.Date(2025, 3, 14, 13, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseSince(tt.in, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseSince(%q) = %s, %v; want %s", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"yesterday", "-2h", "d"} {
+		if _, err := ParseSince(in, now); err == nil {
+			t.Errorf("ParseSince(%q): expected an error", in)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	Write(&buf, nil)
+	if buf.String() != "No runs recorded.\n" {
+		t.Errorf("unexpected empty output %q", buf.String())
+	}
+
+	buf.Reset()
+	Write(&buf, []Entry{entryAt("a", time.Date(2025, 3, 15, 9, 55, 2, 0, time.UTC), 1)})
+	for _, want := range []string{"FIRED", "Sat 2025-03-15 09:55:02", "2s", "Standup a", "exit 1", "1.25s", "notify-send hi"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("missing %q in\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := WriteJSON(&buf, []Entry{entryAt("a", time.Now(), 0), entryAt("b", time.Now(), 0)}); err != nil {
+		t.Fatal(err)
+	}
+	if entries := parse(buf.Bytes()); len(entries) != 2 || entries[1].Key != "b" {
+		t.Errorf("JSON output does not round-trip: %s", buf.String())
+	}
+}
@@ -0,0 +1,233 @@
+// Package notify shows desktop notifications through the
+// org.freedesktop.Notifications D-Bus service and waits for their buttons.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	serviceName = "org.freedesktop.Notifications"
+	objectPath  = dbus.ObjectPath("/org/freedesktop/Notifications")
+	iface       = "org.freedesktop.Notifications"
+)
+
+// Urgency is the urgency level of a notification.
+type Urgency byte
+
+// Urgency levels defined by the notification specification.
+const (
+	Low Urgency = iota
+	Normal
+	Critical
+)
+
+// ParseUrgency parses low, normal or critical; empty means normal.
+func ParseUrgency(s string) (Urgency, error) {
+	switch strings.ToLower(s) {
+	case "low":
+		return Low, nil
+	case "", "normal":
+		return Normal, nil
+	case "critical":
+		return Critical, nil
+	}
+	return Normal, fmt.Errorf("invalid urgency %q (want low, normal or critical)", s)
+}
+
+// Action is a button on a notification.
+type Action struct {
+	Key   string
+	Label string
+}
+
+// Notification is a notification to show.
+type Notification struct {
+	AppName string
+	// Icon is an icon name from the theme or a file path.
+	Icon    string
+	Summary string
+	Body    string
+	Urgency Urgency
+	// Timeout is how long the notification stays up: negative leaves it to
+	// the notification server and zero keeps it until it is dismissed.
+	Timeout time.Duration
+	Actions []Action
+}
+
+// Client is a connection to the notification service.
+type Client struct {
+	conn    *dbus.Conn
+	signals chan *dbus.Signal
+}
+
+// Dial connects to the session bus at address, or at the one
+// SessionBusAddress finds when address is empty.
+func Dial(address string) (*Client, error) {
+	if address == "" {
+		var err error
+		if address, err = SessionBusAddress(); err != nil {
+			return nil, err
+		}
+	}
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to the session bus at %s: %w", address, err)
+	}
+	err = conn.AddMatchSignal(dbus.WithMatchObjectPath(objectPath), dbus.WithMatchInterface(iface))
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	c := &Client{conn: conn, signals: make(chan *dbus.Signal, 16)}
+	conn.Signal(c.signals)
+	return c, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Notify shows n and returns its ID.
+func (c *Client) Notify(ctx context.Context, n Notification) (uint32, error) {
+	actions := make([]string, 0, 2*len(n.Actions))
+	for _, action := range n.Actions {
+		actions = append(actions, action.Key, action.Label)
+	}
+	hints := map[string]dbus.Variant{"urgency": dbus.MakeVariant(byte(n.Urgency))}
+	timeout := int32(-1)
+	if n.Timeout >= 0 {
+		timeout = int32(n.Timeout.Milliseconds())
+	}
+
+	var id uint32
+	call := c.conn.Object(serviceName, objectPath).CallWithContext(ctx, iface+".Notify", 0,
+		n.AppName, uint32(0), n.Icon, n.Summary, n.Body, actions, hints, timeout)
+	if err := call.Store(&id); err != nil {
+		return 0, fmt.Errorf("showing the notification: %w", err)
+	}
+	return id, nil
+}
+
+// Wait waits until notification id is acted on or closed. It returns the
+// key of the invoked action, or "" when the notification was closed
+// without one.
+func (c *Client) Wait(ctx context.Context, id uint32) (string, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case signal, ok := <-c.signals:
+			if !ok {
+				return "", errors.New("the session bus connection was closed")
+			}
+			if len(signal.Body) < 2 {
+				continue
+			}
+			if signalID, _ := signal.Body[0].(uint32); signalID != id {
+				continue
+			}
+			switch signal.Name {
+			case iface + ".ActionInvoked":
+				key, _ := signal.Body[1].(string)
+				return key, nil
+			case iface + ".NotificationClosed":
+				return "", nil
+			}
+		}
+	}
+}
+
+// CloseNotification removes notification id from the screen.
+func (c *Client) CloseNotification(ctx context.Context, id uint32) error {
+	return c.conn.Object(serviceName, objectPath).CallWithContext(ctx, iface+".CloseNotification", 0, id).Err
+}
+
+// SessionBusAddress finds the user's session bus. Cron runs jobs without
+// DBUS_SESSION_BUS_ADDRESS, so after the environment it tries the bus
+// socket in the user's runtime directory, then the environment of the
+// user's other processes, such as the desktop session.
+func SessionBusAddress() (string, error) {
+	if address := os.Getenv("DBUS_SESSION_BUS_ADDRESS"); address != "" {
+		return address, nil
+	}
+	uid := os.Getuid()
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = fmt.Sprintf("/run/user/%d", uid)
+	}
+	if socket := filepath.Join(runtimeDir, "bus"); isSocket(socket) {
+		return "unix:path=" + socket, nil
+	}
+	if address := addressFromProcesses("/proc", uid); address != "" {
+		return address, nil
+	}
+	return "", errors.New("no session bus found; is a desktop session running? (set the action's Bus to its address)")
+}
+
+// addressFromProcesses returns the session bus address in the environment
+// of the newest process of uid under procDir whose bus is still there.
+func addressFromProcesses(procDir string, uid int) string {
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return ""
+	}
+	var pids []int
+	for _, entry := range entries {
+		if pid, err := strconv.Atoi(entry.Name()); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(pids)))
+
+	for _, pid := range pids {
+		dir := filepath.Join(procDir, strconv.Itoa(pid))
+		info, err := os.Stat(dir)
+		if err != nil {
+			continue
+		}
+		if stat, ok := info.Sys().(*syscall.Stat_t); !ok || int(stat.Uid) != uid {
+			continue
+		}
+		environ, err := os.ReadFile(filepath.Join(dir, "environ"))
+		if err != nil {
+			continue
+		}
+		for _, kv := range bytes.Split(environ, []byte{0}) {
+			address, ok := strings.CutPrefix(string(kv), "DBUS_SESSION_BUS_ADDRESS=")
+			if ok && address != "" && reachable(address) {
+				return address
+			}
+		}
+	}
+	return ""
+}
+
+// reachable reports whether the socket of a unix:path address exists;
+// other kinds of address are assumed to be.
+func reachable(address string) bool {
+	for _, part := range strings.Split(strings.SplitN(address, ";", 2)[0], ",") {
+		if path, ok := strings.CutPrefix(part, "unix:path="); ok {
+			return isSocket(path)
+		}
+	}
+	return true
+}
+
+func isSocket(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode()&os.ModeSocket != 0
+}
@@ -0,0 +1,215 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bupd/timeotter/pkg/testutil"
+)
+
+func TestParseUrgency(t *testing.T) {
+	for in, want := range map[string]Urgency{"": Normal, "low": Low, "Normal": Normal, "CRITICAL": Critical} {
+		if got, err := ParseUrgency(in); err != nil || got != want {
+			t.Errorf("ParseUrgency(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseUrgency("urgent"); err == nil {
+		t.Error("expected an error for an unknown urgency")
+	}
+}
+
+func TestNotify(t *testing.T) {
+	address := testutil.StartSessionBus(t, "")
+	server := testutil.StartNotificationServer(t, address)
+
+	client, err := Dial(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	id, err := client.Notify(ctx, Notification{
+		AppName: "timeotter",
+		Icon:    "appointment-soon",
+		Summary: "Standup",
+		Body:    "09:30–09:45",
+		Urgency: Critical,
+		Timeout: 10 * time.Second,
+		Actions: []Action{{"0", "Join"}, {"1", "Snooze"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := server.Notifications()
+	if len(got) != 1 || got[0].ID != id {
+		t.Fatalf("unexpected notifications %+v (id %d)", got, id)
+	}
+	n := got[0]
+	if n.AppName != "timeotter" || n.Icon != "appointment-soon" || n.Summary != "Standup" || n.Body != "09:30–09:45" ||
+		n.Urgency != byte(Critical) || n.Timeout != 10000 || len(n.Actions) != 4 || n.Actions[3] != "Snooze" {
+		t.Errorf("unexpected notification %+v", n)
+	}
+
+	if _, err := client.Notify(ctx, Notification{Summary: "Default timeout", Timeout: -1}); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.Notifications(); got[1].Timeout != -1 || got[1].Urgency != byte(Low) {
+		t.Errorf("unexpected notification %+v", got[1])
+	}
+}
+
+func TestWait(t *testing.T) {
+	address := testutil.StartSessionBus(t, "")
+	server := testutil.StartNotificationServer(t, address)
+	server.OnNotify(func(s *testutil.NotificationServer, n testutil.Notification) {
+		// Another application's notification must not end the wait.
+		s.Invoke(n.ID+100, "0")
+		switch n.Summary {
+		case "click":
+			s.Invoke(n.ID, "1")
+		case "dismiss":
+			s.Close(n.ID, 2)
+		}
+	})
+
+	client, err := Dial(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	tests := []struct {
+		summary string
+		want    string
+		wantErr error
+	}{
+		{"click", "1", nil},
+		{"dismiss", "", nil},
+		{"ignore", "", context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.summary, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			if tt.wantErr != nil {
+				ctx, cancel = context.WithTimeout(context.Background(), 200*time.Millisecond)
+			}
+			defer cancel()
+
+			id, err := client.Notify(ctx, Notification{Summary: tt.summary, Actions: []Action{{"0", "A"}, {"1", "B"}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			key, err := client.Wait(ctx, id)
+			if key != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %q, %v; want %q, %v", key, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCloseNotification(t *testing.T) {
+	address := testutil.StartSessionBus(t, "")
+	server := testutil.StartNotificationServer(t, address)
+	client, err := Dial(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	id, err := client.Notify(ctx, Notification{Summary: "Standup"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.CloseNotification(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if closed := server.Closed(); len(closed) != 1 || closed[0] != id {
+		t.Errorf("got closed notifications %v, want [%d]", closed, id)
+	}
+}
+
+func TestDial_NoService(t *testing.T) {
+	client, err := Dial(testutil.StartSessionBus(t, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, err := client.Notify(context.Background(), Notification{Summary: "nobody listens"}); err == nil {
+		t.Error("expected an error without a notification server")
+	}
+}
+
+func TestSessionBusAddress(t *testing.T) {
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", "unix:path=/run/example/bus")
+	if got, err := SessionBusAddress(); err != nil || got != "unix:path=/run/example/bus" {
+		t.Errorf("got %q, %v; want the environment's address", got, err)
+	}
+
+	// As under cron: no address in the environment, but a bus socket in
+	// the runtime directory.
+	testutil.UnsetEnv(t, "DBUS_SESSION_BUS_ADDRESS")
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	testutil.StartSessionBus(t, filepath.Join(runtimeDir, "bus"))
+
+	address, err := SessionBusAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "unix:path=" + filepath.Join(runtimeDir, "bus"); address != want {
+		t.Errorf("got %q, want %q", address, want)
+	}
+	testutil.StartNotificationServer(t, address)
+	client, err := Dial("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, err := client.Notify(context.Background(), Notification{Summary: "from cron"}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAddressFromProcesses(t *testing.T) {
+	proc := t.TempDir()
+	socket := filepath.Join(t.TempDir(), "bus")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	environs := map[int]string{
+		100: "HOME=/home/me\x00DBUS_SESSION_BUS_ADDRESS=unix:path=" + socket + "\x00",
+		// The newest process points at a session that has ended.
+		200: "DBUS_SESSION_BUS_ADDRESS=unix:path=/nonexistent/bus\x00",
+		300: "HOME=/home/me\x00",
+	}
+	for pid, environ := range environs {
+		dir := filepath.Join(proc, strconv.Itoa(pid))
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "environ"), []byte(environ), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(proc, "self"), 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := addressFromProcesses(proc, os.Getuid()), "unix:path="+socket; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := addressFromProcesses(proc, os.Getuid()+1); got != "" {
+		t.Errorf("found %q in another user's processes", got)
+	}
+}
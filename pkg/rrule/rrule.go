@@ -0,0 +1,582 @@
+// Package rrule expands RFC 5545 recurrence rules into concrete occurrences.
+package rrule
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is the FREQ rule part.
+type Frequency int
+
+// Frequencies, from the finest to the coarsest.
+const (
+	Secondly Frequency = iota
+	Minutely
+	Hourly
+	Daily
+	Weekly
+	Monthly
+	Yearly
+)
+
+var frequencies = map[string]Frequency{
+	"SECONDLY": Secondly,
+	"MINUTELY": Minutely,
+	"HOURLY":   Hourly,
+	"DAILY":    Daily,
+	"WEEKLY":   Weekly,
+	"MONTHLY":  Monthly,
+	"YEARLY":   Yearly,
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// WeekdayNum is a BYDAY entry. N is zero for every such weekday ("MO"),
+// positive for the nth ("2MO") or negative for the nth last ("-1FR") one in
+// the month or year.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+// Rule is a parsed RRULE.
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	Count    int
+	// Until is the inclusive end of the recurrence; zero means none.
+	Until      time.Time
+	BySecond   []int
+	ByMinute   []int
+	ByHour     []int
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByYearDay  []int
+	ByWeekNo   []int
+	ByMonth    []int
+	BySetPos   []int
+	WeekStart  time.Weekday
+}
+
+// Parse parses an RRULE value such as "FREQ=WEEKLY;BYDAY=MO,WE". A leading
+// "RRULE:" is accepted. UNTIL values without a UTC designator are
+// interpreted in loc.
+func Parse(value string, loc *time.Location) (Rule, error) {
+	value = strings.TrimSpace(value)
+	if len(value) > 6 && strings.EqualFold(value[:6], "RRULE:") {
+		value = value[6:]
+	}
+
+	r := Rule{Interval: 1, WeekStart: time.Monday}
+	hasFreq := false
+	for _, part := range strings.Split(value, ";") {
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return Rule{}, fmt.Errorf("invalid rule part %q", part)
+		}
+		key = strings.ToUpper(key)
+		val = strings.ToUpper(val)
+
+		var err error
+		switch key {
+		case "FREQ":
+			if r.Freq, ok = frequencies[val]; !ok {
+				return Rule{}, fmt.Errorf("invalid FREQ %q", val)
+			}
+			hasFreq = true
+		case "INTERVAL":
+			if r.Interval, err = strconv.Atoi(val); err != nil || r.Interval < 1 {
+				return Rule{}, fmt.Errorf("invalid INTERVAL %q", val)
+			}
+		case "COUNT":
+			if r.Count, err = strconv.Atoi(val); err != nil || r.Count < 1 {
+				return Rule{}, fmt.Errorf("invalid COUNT %q", val)
+			}
+		case "UNTIL":
+			if r.Until, _, err = parseValue(val, loc); err != nil {
+				return Rule{}, fmt.Errorf("invalid UNTIL: %w", err)
+			}
+		case "BYSECOND":
+			r.BySecond, err = parseList(val, 0, 60, false)
+		case "BYMINUTE":
+			r.ByMinute, err = parseList(val, 0, 59, false)
+		case "BYHOUR":
+			r.ByHour, err = parseList(val, 0, 23, false)
+		case "BYDAY":
+			r.ByDay, err = parseWeekdays(val)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseList(val, 1, 31, true)
+		case "BYYEARDAY":
+			r.ByYearDay, err = parseList(val, 1, 366, true)
+		case "BYWEEKNO":
+			r.ByWeekNo, err = parseList(val, 1, 53, true)
+		case "BYMONTH":
+			r.ByMonth, err = parseList(val, 1, 12, false)
+		case "BYSETPOS":
+			r.BySetPos, err = parseList(val, 1, 366, true)
+		case "WKST":
+			if r.WeekStart, ok = weekdays[val]; !ok {
+				return Rule{}, fmt.Errorf("invalid WKST %q", val)
+			}
+		default:
+			return Rule{}, fmt.Errorf("unknown rule part %q", key)
+		}
+		if err != nil {
+			return Rule{}, fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+
+	if !hasFreq {
+		return Rule{}, fmt.Errorf("FREQ is required")
+	}
+	if err := r.validate(); err != nil {
+		return Rule{}, err
+	}
+	return r, nil
+}
+
+// validate applies the restrictions RFC 5545 places on combining rule parts.
+func (r Rule) validate() error {
+	if r.Count > 0 && !r.Until.IsZero() {
+		return fmt.Errorf("COUNT and UNTIL are mutually exclusive")
+	}
+	if len(r.ByWeekNo) > 0 && r.Freq != Yearly {
+		return fmt.Errorf("BYWEEKNO is only valid with FREQ=YEARLY")
+	}
+	if len(r.ByYearDay) > 0 && (r.Freq == Daily || r.Freq == Weekly || r.Freq == Monthly) {
+		return fmt.Errorf("BYYEARDAY is not valid with FREQ=DAILY, WEEKLY or MONTHLY")
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq == Weekly {
+		return fmt.Errorf("BYMONTHDAY is not valid with FREQ=WEEKLY")
+	}
+	for _, wd := range r.ByDay {
+		if wd.N != 0 && r.Freq != Monthly && r.Freq != Yearly {
+			return fmt.Errorf("numeric BYDAY values need FREQ=MONTHLY or YEARLY")
+		}
+	}
+	return nil
+}
+
+// parseList parses a comma separated list of integers in [min, max], or
+// [-max, -min] as well when negative is set.
+func parseList(value string, min, max int, negative bool) ([]int, error) {
+	var list []int
+	for _, field := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimPrefix(field, "+"))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", field)
+		}
+		abs := n
+		if n < 0 && negative {
+			abs = -n
+		}
+		if abs < min || abs > max {
+			return nil, fmt.Errorf("%d is out of range", n)
+		}
+		list = append(list, n)
+	}
+	return uniqueSorted(list), nil
+}
+
+// parseWeekdays parses a BYDAY list such as "MO,-1FR,2TU".
+func parseWeekdays(value string) ([]WeekdayNum, error) {
+	var list []WeekdayNum
+	for _, field := range strings.Split(value, ",") {
+		if len(field) < 2 {
+			return nil, fmt.Errorf("invalid weekday %q", field)
+		}
+		day, ok := weekdays[field[len(field)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid weekday %q", field)
+		}
+		wd := WeekdayNum{Day: day}
+		if prefix := field[:len(field)-2]; prefix != "" {
+			n, err := strconv.Atoi(strings.TrimPrefix(prefix, "+"))
+			if err != nil || n == 0 || n < -53 || n > 53 {
+				return nil, fmt.Errorf("invalid weekday %q", field)
+			}
+			wd.N = n
+		}
+		list = append(list, wd)
+	}
+	return list, nil
+}
+
+// parseValue parses a DATE or DATE-TIME value. Values without a UTC
+// designator are interpreted in loc. isDate reports a DATE value.
+func parseValue(value string, loc *time.Location) (t time.Time, isDate bool, err error) {
+	switch {
+	case strings.HasSuffix(value, "Z"):
+		t, err = time.Parse("20060102T150405Z", value)
+	case strings.Contains(value, "T"):
+		t, err = time.ParseInLocation("20060102T150405", value, loc)
+	default:
+		t, err = time.ParseInLocation("20060102", value, loc)
+		isDate = true
+	}
+	return t, isDate, err
+}
+
+// Between returns the occurrences of r starting at dtstart that fall in
+// [from, to), in dtstart's location. Occurrences are computed on the wall
+// clock of that location, so they keep their local time across DST
+// changes; a local time skipped by a DST change moves forward by the gap.
+func (r Rule) Between(dtstart, from, to time.Time) []time.Time {
+	if !to.After(from) {
+		return nil
+	}
+	loc := dtstart.Location()
+	start := wallClock(dtstart)
+	// A day of slack covers offset changes between the wall clock and to.
+	limit := wallClock(to.In(loc)).AddDate(0, 0, 1)
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	x := r.expand(start)
+
+	// COUNT needs every occurrence from the start; otherwise skip ahead to
+	// the periods around from.
+	k := 0
+	if r.Count == 0 {
+		k = periodsBetween(r.Freq, r.WeekStart, start, wallClock(from.In(loc)))/interval - 1
+		if k < 0 {
+			k = 0
+		}
+	}
+
+	var out []time.Time
+	count := 0
+	for ; ; k++ {
+		period := periodStart(r.Freq, r.WeekStart, start, k*interval)
+		if period.After(limit) {
+			return out
+		}
+		if !r.Until.IsZero() && inLocation(period, loc).After(r.Until) {
+			return out
+		}
+
+		for _, occurrence := range x.occurrences(period) {
+			if occurrence.Before(start) {
+				continue
+			}
+			t := inLocation(occurrence, loc)
+			if !r.Until.IsZero() && t.After(r.Until) {
+				return out
+			}
+			count++
+			if r.Count > 0 && count > r.Count {
+				return out
+			}
+			if t.Before(from) {
+				continue
+			}
+			if !t.Before(to) {
+				return out
+			}
+			out = append(out, t)
+		}
+	}
+}
+
+// expansion is a rule with the defaults RFC 5545 derives from DTSTART.
+type expansion struct {
+	Rule
+	byMonth    []int
+	byMonthDay []int
+	byDay      []WeekdayNum
+	byHour     []int
+	byMinute   []int
+	bySecond   []int
+}
+
+func (r Rule) expand(start time.Time) expansion {
+	x := expansion{
+		Rule:       r,
+		byMonth:    r.ByMonth,
+		byMonthDay: r.ByMonthDay,
+		byDay:      r.ByDay,
+		byHour:     r.ByHour,
+		byMinute:   r.ByMinute,
+		bySecond:   r.BySecond,
+	}
+
+	if len(r.ByWeekNo) == 0 && len(r.ByYearDay) == 0 && len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+		switch r.Freq {
+		case Yearly:
+			if len(x.byMonth) == 0 {
+				x.byMonth = []int{int(start.Month())}
+			}
+			x.byMonthDay = []int{start.Day()}
+		case Monthly:
+			x.byMonthDay = []int{start.Day()}
+		case Weekly:
+			x.byDay = []WeekdayNum{{Day: start.Weekday()}}
+		}
+	}
+	if len(x.byHour) == 0 && r.Freq > Hourly {
+		x.byHour = []int{start.Hour()}
+	}
+	if len(x.byMinute) == 0 && r.Freq > Minutely {
+		x.byMinute = []int{start.Minute()}
+	}
+	if len(x.bySecond) == 0 && r.Freq > Secondly {
+		x.bySecond = []int{start.Second()}
+	}
+	return x
+}
+
+// occurrences returns the sorted wall-clock occurrences within the period
+// starting at period.
+func (x expansion) occurrences(period time.Time) []time.Time {
+	first, days := x.dayRange(period)
+	hours := x.timeSet(Hourly, period.Hour(), x.byHour)
+	minutes := x.timeSet(Minutely, period.Minute(), x.byMinute)
+	seconds := x.timeSet(Secondly, period.Second(), x.bySecond)
+
+	var set []time.Time
+	for i := 0; i < days; i++ {
+		day := first.AddDate(0, 0, i)
+		if !x.matchDay(day) {
+			continue
+		}
+		for _, h := range hours {
+			for _, m := range minutes {
+				for _, s := range seconds {
+					set = append(set, time.Date(day.Year(), day.Month(), day.Day(), h, m, s, 0, time.UTC))
+				}
+			}
+		}
+	}
+
+	if len(x.BySetPos) > 0 {
+		set = selectPositions(set, x.BySetPos)
+	}
+	return set
+}
+
+// dayRange returns the first day and the number of days of the period.
+func (x expansion) dayRange(period time.Time) (time.Time, int) {
+	day := time.Date(period.Year(), period.Month(), period.Day(), 0, 0, 0, 0, time.UTC)
+	switch x.Freq {
+	case Yearly:
+		return day, daysInYear(day.Year())
+	case Monthly:
+		return day, daysInMonth(day)
+	case Weekly:
+		return day, 7
+	default:
+		return day, 1
+	}
+}
+
+// timeSet returns the values of one time field within a period. Fields
+// coarser than the frequency expand to list; the others are fixed by the
+// period and only kept when list allows them.
+func (x expansion) timeSet(field Frequency, value int, list []int) []int {
+	if x.Freq > field {
+		return list
+	}
+	if len(list) == 0 || contains(list, value) {
+		return []int{value}
+	}
+	return nil
+}
+
+// matchDay applies the day-level BYxxx rule parts to day.
+func (x expansion) matchDay(day time.Time) bool {
+	if len(x.byMonth) > 0 && !contains(x.byMonth, int(day.Month())) {
+		return false
+	}
+	if len(x.ByWeekNo) > 0 {
+		week, weeks := weekNumber(day, x.WeekStart)
+		if !containsSigned(x.ByWeekNo, week, weeks) {
+			return false
+		}
+	}
+	if len(x.ByYearDay) > 0 && !containsSigned(x.ByYearDay, day.YearDay(), daysInYear(day.Year())) {
+		return false
+	}
+	if len(x.byMonthDay) > 0 && !containsSigned(x.byMonthDay, day.Day(), daysInMonth(day)) {
+		return false
+	}
+	if len(x.byDay) > 0 && !x.matchWeekday(day) {
+		return false
+	}
+	return true
+}
+
+// matchWeekday reports whether day matches a BYDAY entry. Numbered entries
+// count within the year for YEARLY rules without BYMONTH, and within the
+// month otherwise.
+func (x expansion) matchWeekday(day time.Time) bool {
+	for _, wd := range x.byDay {
+		if wd.Day != day.Weekday() {
+			continue
+		}
+		if wd.N == 0 {
+			return true
+		}
+		index, total := day.Day(), daysInMonth(day)
+		if x.Freq == Yearly && len(x.ByMonth) == 0 {
+			index, total = day.YearDay(), daysInYear(day.Year())
+		}
+		nth := (index-1)/7 + 1
+		nthLast := -((total-index)/7 + 1)
+		if wd.N == nth || wd.N == nthLast {
+			return true
+		}
+	}
+	return false
+}
+
+// weekNumber returns the week of day and the number of weeks in its
+// week-numbering year. Weeks start on wkst and week 1 is the first week
+// with at least four days in the year.
+func weekNumber(day time.Time, wkst time.Weekday) (int, int) {
+	fourth := fourthDayOfWeek(day, wkst)
+	lastWeek := fourthDayOfWeek(time.Date(fourth.Year(), 12, 28, 0, 0, 0, 0, time.UTC), wkst)
+	return (fourth.YearDay()-1)/7 + 1, (lastWeek.YearDay()-1)/7 + 1
+}
+
+// fourthDayOfWeek returns the fourth day of the week containing day; the
+// week belongs to that day's year.
+func fourthDayOfWeek(day time.Time, wkst time.Weekday) time.Time {
+	offset := (int(day.Weekday()) - int(wkst) + 7) % 7
+	return day.AddDate(0, 0, 3-offset)
+}
+
+// selectPositions applies BYSETPOS to the sorted set.
+func selectPositions(set []time.Time, positions []int) []time.Time {
+	var selected []time.Time
+	seen := make(map[int]bool)
+	for _, pos := range positions {
+		i := pos - 1
+		if pos < 0 {
+			i = len(set) + pos
+		}
+		if i < 0 || i >= len(set) || seen[i] {
+			continue
+		}
+		seen[i] = true
+		selected = append(selected, set[i])
+	}
+	sort.Slice(selected, func(a, b int) bool { return selected[a].Before(selected[b]) })
+	return selected
+}
+
+// periodStart returns the wall-clock start of the period n frequency units
+// after the one containing start.
+func periodStart(freq Frequency, wkst time.Weekday, start time.Time, n int) time.Time {
+	y, m, d := start.Date()
+	switch freq {
+	case Yearly:
+		return time.Date(y+n, 1, 1, 0, 0, 0, 0, time.UTC)
+	case Monthly:
+		return time.Date(y, m+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+	case Weekly:
+		offset := (int(start.Weekday()) - int(wkst) + 7) % 7
+		return time.Date(y, m, d-offset+7*n, 0, 0, 0, 0, time.UTC)
+	case Daily:
+		return time.Date(y, m, d+n, 0, 0, 0, 0, time.UTC)
+	case Hourly:
+		return time.Date(y, m, d, start.Hour()+n, 0, 0, 0, time.UTC)
+	case Minutely:
+		return time.Date(y, m, d, start.Hour(), start.Minute()+n, 0, 0, time.UTC)
+	default:
+		return time.Date(y, m, d, start.Hour(), start.Minute(), start.Second()+n, 0, time.UTC)
+	}
+}
+
+// periodsBetween counts whole frequency units from the period containing
+// start to the one containing t.
+func periodsBetween(freq Frequency, wkst time.Weekday, start, t time.Time) int {
+	switch freq {
+	case Yearly:
+		return t.Year() - start.Year()
+	case Monthly:
+		return (t.Year()-start.Year())*12 + int(t.Month()) - int(start.Month())
+	case Weekly:
+		return int(periodStart(Weekly, wkst, t, 0).Sub(periodStart(Weekly, wkst, start, 0)).Hours()) / (24 * 7)
+	case Daily:
+		return int(periodStart(Daily, wkst, t, 0).Sub(periodStart(Daily, wkst, start, 0)).Hours()) / 24
+	case Hourly:
+		return int(t.Truncate(time.Hour).Sub(start.Truncate(time.Hour)).Hours())
+	case Minutely:
+		return int(t.Truncate(time.Minute).Sub(start.Truncate(time.Minute)).Minutes())
+	default:
+		return int(t.Sub(start).Seconds())
+	}
+}
+
+// wallClock returns t's local date and time as a UTC value, so that
+// calendar arithmetic is not affected by offset changes.
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
+
+// inLocation interprets a wall-clock value in loc.
+func inLocation(wall time.Time, loc *time.Location) time.Time {
+	t := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, loc)
+	if wallClock(t).Equal(wall) {
+		return t
+	}
+	// The wall clock time does not exist, e.g. it falls into a spring DST
+	// gap. RFC 5545 uses the offset from before the gap, moving it forward.
+	_, before := t.Add(-12 * time.Hour).Zone()
+	return wall.Add(-time.Duration(before) * time.Second).In(loc)
+}
+
+func daysInYear(year int) int {
+	return time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC).YearDay()
+}
+
+func daysInMonth(day time.Time) int {
+	return time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func contains(list []int, v int) bool {
+	for _, n := range list {
+		if n == v {
+			return true
+		}
+	}
+	return false
+}
+
+// containsSigned reports whether list holds v counted from the start, or
+// from the end of a range of total values.
+func containsSigned(list []int, v, total int) bool {
+	for _, n := range list {
+		if n == v || n == v-total-1 {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueSorted(list []int) []int {
+	sort.Ints(list)
+	out := list[:0]
+	for i, n := range list {
+		if i == 0 || n != list[i-1] {
+			out = append(out, n)
+		}
+	}
+	return out
+}
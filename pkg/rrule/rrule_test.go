@@ -0,0 +1,472 @@
+package rrule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func newYork(t *testing.T) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone America/New_York not available: %v", err)
+	}
+	return loc
+}
+
+func mustLocal(t *testing.T, value string, loc *time.Location) time.Time {
+	t.Helper()
+	v, err := time.ParseInLocation("20060102T150405", value, loc)
+	if err != nil {
+		t.Fatalf("bad test time %q: %v", value, err)
+	}
+	return v
+}
+
+func format(times []time.Time) []string {
+	out := make([]string, len(times))
+	for i, t := range times {
+		out[i] = t.Format("2006-01-02 15:04")
+	}
+	return out
+}
+
+// rfcExamples are the recurrence examples of RFC 5545 section 3.8.5.3, all
+// starting in America/New_York.
+var rfcExamples = []struct {
+	name    string
+	dtstart string
+	rule    string
+	// until bounds open-ended rules; defaults to 2010.
+	until string
+	want  []string
+	// wantCount checks only the number, first and last occurrence of long
+	// series.
+	wantCount int
+}{
+	{
+		name:    "daily for 10 occurrences",
+		dtstart: "19970902T090000",
+		rule:    "FREQ=DAILY;COUNT=10",
+		want: []string{
+			"1997-09-02 09:00", "1997-09-03 09:00", "1997-09-04 09:00", "1997-09-05 09:00", "1997-09-06 09:00",
+			"1997-09-07 09:00", "1997-09-08 09:00", "1997-09-09 09:00", "1997-09-10 09:00", "1997-09-11 09:00",
+		},
+	},
+	{
+		name:      "daily until December 24, 1997",
+		dtstart:   "19970902T090000",
+		rule:      "FREQ=DAILY;UNTIL=19971224T000000Z",
+		want:      []string{"1997-09-02 09:00", "1997-12-23 09:00"},
+		wantCount: 113,
+	},
+	{
+		name:    "every other day",
+		dtstart: "19970902T090000",
+		rule:    "FREQ=DAILY;INTERVAL=2",
+		until:   "19971001T000000",
+		want: []string{
+			"1997-09-02 09:00", "1997-09-04 09:00", "1997-09-06 09:00", "1997-09-08 09:00", "1997-09-10 09:00",
+			"1997-09-12 09:00", "1997-09-14 09:00", "1997-09-16 09:00", "1997-09-18 09:00", "1997-09-20 09:00",
+			"1997-09-22 09:00", "1997-09-24 09:00", "1997-09-26 09:00", "1997-09-28 09:00", "1997-09-30 09:00",
+		},
+	},
+	{
+		name:    "every 10 days, 5 occurrences",
+		dtstart: "19970902T090000",
+		rule:    "FREQ=DAILY;INTERVAL=10;COUNT=5",
+		want:    []string{"1997-09-02 09:00", "1997-09-12 09:00", "1997-09-22 09:00", "1997-10-02 09:00", "1997-10-12 09:00"},
+	},
+	{
+		name:      "every day in January, for 3 years",
+		dtstart:   "19980101T090000",
+		rule:      "FREQ=YEARLY;UNTIL=20000131T140000Z;BYMONTH=1;BYDAY=SU,MO,TU,WE,TH,FR,SA",
+		want:      []string{"1998-01-01 09:00", "2000-01-31 09:00"},
+		wantCount: 93,
+	},
+	{
+		name:    "weekly for 10 occurrences",
+		dtstart: "19970902T090000",
+		rule:    "FREQ=WEEKLY;COUNT=10",
+		want: []string{
+			"1997-09-02 09:00", "1997-09-09 09:00", "1997-09-16 09:00", "1997-09-23 09:00", "1997-09-30 09:00",
+			"1997-10-07 09:00", "1997-10-14 09:00", "1997-10-21 09:00", "1997-10-28 09:00", "1997-11-04 09:00",
+		},
+	},
+	{
+		name:    "every other week on Monday, Wednesday and Friday until December 24, 1997",
+		dtstart: "19970901T090000",
+		rule:    "FREQ=WEEKLY;INTERVAL=2;UNTIL=19971224T000000Z;WKST=SU;BYDAY=MO,WE,FR",
+		want: []string{
+			"1997-09-01 09:00", "1997-09-03 09:00", "1997-09-05 09:00", "1997-09-15 09:00", "1997-09-17 09:00",
+			"1997-09-19 09:00", "1997-09-29 09:00", "1997-10-01 09:00", "1997-10-03 09:00", "1997-10-13 09:00",
+			"1997-10-15 09:00", "1997-10-17 09:00", "1997-10-27 09:00", "1997-10-29 09:00", "1997-10-31 09:00",
+			"1997-11-10 09:00", "1997-11-12 09:00", "1997-11-14 09:00", "1997-11-24 09:00", "1997-11-26 09:00",
+			"1997-11-28 09:00", "1997-12-08 09:00", "1997-12-10 09:00", "1997-12-12 09:00", "1997-12-22 09:00",
+		},
+	},
+	{
+		name:    "every other week on Tuesday and Thursday, for 8 occurrences",
+		dtstart: "19970902T090000",
+		rule:    "FREQ=WEEKLY;INTERVAL=2;COUNT=8;WKST=SU;BYDAY=TU,TH",
+		want: []string{
+			"1997-09-02 09:00", "1997-09-04 09:00", "1997-09-16 09:00", "1997-09-18 09:00",
+			"1997-09-30 09:00", "1997-10-02 09:00", "1997-10-14 09:00", "1997-10-16 09:00",
+		},
+	},
+	{
+		name:    "monthly on the first Friday for 10 occurrences",
+		dtstart: "19970905T090000",
+		rule:    "FREQ=MONTHLY;COUNT=10;BYDAY=1FR",
+		want: []string{
+			"1997-09-05 09:00", "1997-10-03 09:00", "1997-11-07 09:00", "1997-12-05 09:00", "1998-01-02 09:00",
+			"1998-02-06 09:00", "1998-03-06 09:00", "1998-04-03 09:00", "1998-05-01 09:00", "1998-06-05 09:00",
+		},
+	},
+	{
+		name:    "every other month on the first and last Sunday for 10 occurrences",
+		dtstart: "19970907T090000",
+		rule:    "FREQ=MONTHLY;INTERVAL=2;COUNT=10;BYDAY=1SU,-1SU",
+		want: []string{
+			"1997-09-07 09:00", "1997-09-28 09:00", "1997-11-02 09:00", "1997-11-30 09:00", "1998-01-04 09:00",
+			"1998-01-25 09:00", "1998-03-01 09:00", "1998-03-29 09:00", "1998-05-03 09:00", "1998-05-31 09:00",
+		},
+	},
+	{
+		name:    "monthly on the second-to-last Monday for 6 months",
+		dtstart: "19970922T090000",
+		rule:    "FREQ=MONTHLY;COUNT=6;BYDAY=-2MO",
+		want: []string{
+			"1997-09-22 09:00", "1997-10-20 09:00", "1997-11-17 09:00",
+			"1997-12-22 09:00", "1998-01-19 09:00", "1998-02-16 09:00",
+		},
+	},
+	{
+		name:    "monthly on the third-to-last day",
+		dtstart: "19970928T090000",
+		rule:    "FREQ=MONTHLY;BYMONTHDAY=-3",
+		until:   "19980301T000000",
+		want: []string{
+			"1997-09-28 09:00", "1997-10-29 09:00", "1997-11-28 09:00",
+			"1997-12-29 09:00", "1998-01-29 09:00", "1998-02-26 09:00",
+		},
+	},
+	{
+		name:    "monthly on the 2nd and 15th for 10 occurrences",
+		dtstart: "19970902T090000",
+		rule:    "FREQ=MONTHLY;COUNT=10;BYMONTHDAY=2,15",
+		want: []string{
+			"1997-09-02 09:00", "1997-09-15 09:00", "1997-10-02 09:00", "1997-10-15 09:00", "1997-11-02 09:00",
+			"1997-11-15 09:00", "1997-12-02 09:00", "1997-12-15 09:00", "1998-01-02 09:00", "1998-01-15 09:00",
+		},
+	},
+	{
+		name:    "monthly on the first and last day for 10 occurrences",
+		dtstart: "19970930T090000",
+		rule:    "FREQ=MONTHLY;COUNT=10;BYMONTHDAY=1,-1",
+		want: []string{
+			"1997-09-30 09:00", "1997-10-01 09:00", "1997-10-31 09:00", "1997-11-01 09:00", "1997-11-30 09:00",
+			"1997-12-01 09:00", "1997-12-31 09:00", "1998-01-01 09:00", "1998-01-31 09:00", "1998-02-01 09:00",
+		},
+	},
+	{
+		name:    "every 18 months on the 10th thru 15th for 10 occurrences",
+		dtstart: "19970910T090000",
+		rule:    "FREQ=MONTHLY;INTERVAL=18;COUNT=10;BYMONTHDAY=10,11,12,13,14,15",
+		want: []string{
+			"1997-09-10 09:00", "1997-09-11 09:00", "1997-09-12 09:00", "1997-09-13 09:00", "1997-09-14 09:00",
+			"1997-09-15 09:00", "1999-03-10 09:00", "1999-03-11 09:00", "1999-03-12 09:00", "1999-03-13 09:00",
+		},
+	},
+	{
+		name:    "every Tuesday, every other month",
+		dtstart: "19970902T090000",
+		rule:    "FREQ=MONTHLY;INTERVAL=2;BYDAY=TU",
+		until:   "19980201T000000",
+		want: []string{
+			"1997-09-02 09:00", "1997-09-09 09:00", "1997-09-16 09:00", "1997-09-23 09:00", "1997-09-30 09:00",
+			"1997-11-04 09:00", "1997-11-11 09:00", "1997-11-18 09:00", "1997-11-25 09:00",
+			"1998-01-06 09:00", "1998-01-13 09:00", "1998-01-20 09:00", "1998-01-27 09:00",
+		},
+	},
+	{
+		name:    "yearly in June and July for 10 occurrences",
+		dtstart: "19970610T090000",
+		rule:    "FREQ=YEARLY;COUNT=10;BYMONTH=6,7",
+		want: []string{
+			"1997-06-10 09:00", "1997-07-10 09:00", "1998-06-10 09:00", "1998-07-10 09:00", "1999-06-10 09:00",
+			"1999-07-10 09:00", "2000-06-10 09:00", "2000-07-10 09:00", "2001-06-10 09:00", "2001-07-10 09:00",
+		},
+	},
+	{
+		name:    "every other year on January, February and March for 10 occurrences",
+		dtstart: "19970310T090000",
+		rule:    "FREQ=YEARLY;INTERVAL=2;COUNT=10;BYMONTH=1,2,3",
+		want: []string{
+			"1997-03-10 09:00", "1999-01-10 09:00", "1999-02-10 09:00", "1999-03-10 09:00", "2001-01-10 09:00",
+			"2001-02-10 09:00", "2001-03-10 09:00", "2003-01-10 09:00", "2003-02-10 09:00", "2003-03-10 09:00",
+		},
+	},
+	{
+		name:    "every third year on the 1st, 100th and 200th day for 10 occurrences",
+		dtstart: "19970101T090000",
+		rule:    "FREQ=YEARLY;INTERVAL=3;COUNT=10;BYYEARDAY=1,100,200",
+		want: []string{
+			"1997-01-01 09:00", "1997-04-10 09:00", "1997-07-19 09:00", "2000-01-01 09:00", "2000-04-09 09:00",
+			"2000-07-18 09:00", "2003-01-01 09:00", "2003-04-10 09:00", "2003-07-19 09:00", "2006-01-01 09:00",
+		},
+	},
+	{
+		name:    "every 20th Monday of the year",
+		dtstart: "19970519T090000",
+		rule:    "FREQ=YEARLY;BYDAY=20MO",
+		until:   "20000101T000000",
+		want:    []string{"1997-05-19 09:00", "1998-05-18 09:00", "1999-05-17 09:00"},
+	},
+	{
+		name:    "Monday of week number 20",
+		dtstart: "19970512T090000",
+		rule:    "FREQ=YEARLY;BYWEEKNO=20;BYDAY=MO",
+		until:   "20000101T000000",
+		want:    []string{"1997-05-12 09:00", "1998-05-11 09:00", "1999-05-17 09:00"},
+	},
+	{
+		name:    "every Thursday in March",
+		dtstart: "19970313T090000",
+		rule:    "FREQ=YEARLY;BYMONTH=3;BYDAY=TH",
+		until:   "20000101T000000",
+		want: []string{
+			"1997-03-13 09:00", "1997-03-20 09:00", "1997-03-27 09:00",
+			"1998-03-05 09:00", "1998-03-12 09:00", "1998-03-19 09:00", "1998-03-26 09:00",
+			"1999-03-04 09:00", "1999-03-11 09:00", "1999-03-18 09:00", "1999-03-25 09:00",
+		},
+	},
+	{
+		name:    "every Thursday, but only during June, July and August",
+		dtstart: "19970605T090000",
+		rule:    "FREQ=YEARLY;BYDAY=TH;BYMONTH=6,7,8",
+		until:   "19980101T000000",
+		want: []string{
+			"1997-06-05 09:00", "1997-06-12 09:00", "1997-06-19 09:00", "1997-06-26 09:00", "1997-07-03 09:00",
+			"1997-07-10 09:00", "1997-07-17 09:00", "1997-07-24 09:00", "1997-07-31 09:00", "1997-08-07 09:00",
+			"1997-08-14 09:00", "1997-08-21 09:00", "1997-08-28 09:00",
+		},
+	},
+	{
+		name:    "the first Saturday that follows the first Sunday of the month",
+		dtstart: "19970913T090000",
+		rule:    "FREQ=MONTHLY;BYDAY=SA;BYMONTHDAY=7,8,9,10,11,12,13",
+		until:   "19980701T000000",
+		want: []string{
+			"1997-09-13 09:00", "1997-10-11 09:00", "1997-11-08 09:00", "1997-12-13 09:00", "1998-01-10 09:00",
+			"1998-02-07 09:00", "1998-03-07 09:00", "1998-04-11 09:00", "1998-05-09 09:00", "1998-06-13 09:00",
+		},
+	},
+	{
+		name:    "US Presidential Election day",
+		dtstart: "19961105T090000",
+		rule:    "FREQ=YEARLY;INTERVAL=4;BYMONTH=11;BYDAY=TU;BYMONTHDAY=2,3,4,5,6,7,8",
+		until:   "20050101T000000",
+		want:    []string{"1996-11-05 09:00", "2000-11-07 09:00", "2004-11-02 09:00"},
+	},
+	{
+		name:    "third instance of Tuesday, Wednesday or Thursday for the next 3 months",
+		dtstart: "19970904T090000",
+		rule:    "FREQ=MONTHLY;COUNT=3;BYDAY=TU,WE,TH;BYSETPOS=3",
+		want:    []string{"1997-09-04 09:00", "1997-10-07 09:00", "1997-11-06 09:00"},
+	},
+	{
+		name:    "second-to-last weekday of the month",
+		dtstart: "19970929T090000",
+		rule:    "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-2",
+		until:   "19980401T000000",
+		want: []string{
+			"1997-09-29 09:00", "1997-10-30 09:00", "1997-11-27 09:00", "1997-12-30 09:00",
+			"1998-01-29 09:00", "1998-02-26 09:00", "1998-03-30 09:00",
+		},
+	},
+	{
+		// The RFC prints UNTIL=19970902T170000Z here, which ends the series
+		// at 13:00 local time; its own result list implies 21:00Z.
+		name:    "every 3 hours from 09:00 to 17:00 on a specific day",
+		dtstart: "19970902T090000",
+		rule:    "FREQ=HOURLY;INTERVAL=3;UNTIL=19970902T210000Z",
+		want:    []string{"1997-09-02 09:00", "1997-09-02 12:00", "1997-09-02 15:00"},
+	},
+	{
+		name:    "every 15 minutes for 6 occurrences",
+		dtstart: "19970902T090000",
+		rule:    "FREQ=MINUTELY;INTERVAL=15;COUNT=6",
+		want: []string{
+			"1997-09-02 09:00", "1997-09-02 09:15", "1997-09-02 09:30",
+			"1997-09-02 09:45", "1997-09-02 10:00", "1997-09-02 10:15",
+		},
+	},
+	{
+		name:    "every hour and a half for 4 occurrences",
+		dtstart: "19970902T090000",
+		rule:    "FREQ=MINUTELY;INTERVAL=90;COUNT=4",
+		want:    []string{"1997-09-02 09:00", "1997-09-02 10:30", "1997-09-02 12:00", "1997-09-02 13:30"},
+	},
+	{
+		name:      "every 20 minutes from 9:00 to 16:40 every day",
+		dtstart:   "19970902T090000",
+		rule:      "FREQ=DAILY;BYHOUR=9,10,11,12,13,14,15,16;BYMINUTE=0,20,40",
+		until:     "19970904T000000",
+		want:      []string{"1997-09-02 09:00", "1997-09-03 16:40"},
+		wantCount: 48,
+	},
+	{
+		name:      "every 20 minutes from 9:00 to 16:40 every day, minutely",
+		dtstart:   "19970902T090000",
+		rule:      "FREQ=MINUTELY;INTERVAL=20;BYHOUR=9,10,11,12,13,14,15,16",
+		until:     "19970904T000000",
+		want:      []string{"1997-09-02 09:00", "1997-09-03 16:40"},
+		wantCount: 48,
+	},
+	{
+		name:    "week start Monday",
+		dtstart: "19970805T090000",
+		rule:    "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=MO",
+		want:    []string{"1997-08-05 09:00", "1997-08-10 09:00", "1997-08-19 09:00", "1997-08-24 09:00"},
+	},
+	{
+		name:    "week start Sunday",
+		dtstart: "19970805T090000",
+		rule:    "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU",
+		want:    []string{"1997-08-05 09:00", "1997-08-17 09:00", "1997-08-19 09:00", "1997-08-31 09:00"},
+	},
+	{
+		name:    "invalid dates are ignored",
+		dtstart: "20070115T090000",
+		rule:    "FREQ=MONTHLY;BYMONTHDAY=15,30;COUNT=5",
+		want:    []string{"2007-01-15 09:00", "2007-01-30 09:00", "2007-02-15 09:00", "2007-03-15 09:00", "2007-03-30 09:00"},
+	},
+}
+
+func TestRule_RFC5545Examples(t *testing.T) {
+	loc := newYork(t)
+
+	for _, tt := range rfcExamples {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule, loc)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+			dtstart := mustLocal(t, tt.dtstart, loc)
+			until := "20100101T000000"
+			if tt.until != "" {
+				until = tt.until
+			}
+
+			got := format(rule.Between(dtstart, dtstart, mustLocal(t, until, loc)))
+			if tt.wantCount > 0 {
+				if len(got) != tt.wantCount || got[0] != tt.want[0] || got[len(got)-1] != tt.want[1] {
+					t.Errorf("got %d occurrences %v ... %v, want %d from %s to %s",
+						len(got), got[:min(len(got), 3)], got[max(len(got)-3, 0):], tt.wantCount, tt.want[0], tt.want[1])
+				}
+				return
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got  %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRule_BetweenWindow(t *testing.T) {
+	loc := newYork(t)
+	from := mustLocal(t, "19990301T000000", loc)
+	to := mustLocal(t, "19990601T000000", loc)
+
+	// A window later in the series must match the full expansion clipped
+	// to it, whichever periods were skipped to get there.
+	for _, tt := range rfcExamples {
+		if strings.Contains(tt.rule, "COUNT") || strings.Contains(tt.rule, "UNTIL") {
+			continue
+		}
+		rule, err := Parse(tt.rule, loc)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.rule, err)
+		}
+		dtstart := mustLocal(t, tt.dtstart, loc)
+
+		var want []time.Time
+		for _, occurrence := range rule.Between(dtstart, dtstart, to) {
+			if !occurrence.Before(from) {
+				want = append(want, occurrence)
+			}
+		}
+		got := rule.Between(dtstart, from, to)
+		if strings.Join(format(got), ",") != strings.Join(format(want), ",") {
+			t.Errorf("%s: window got %v, want %v", tt.name, format(got), format(want))
+		}
+	}
+}
+
+func TestRule_DST(t *testing.T) {
+	loc := newYork(t)
+
+	rule, err := Parse("FREQ=DAILY", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The local time stays at 09:00 while the offset changes.
+	got := rule.Between(mustLocal(t, "20251101T090000", loc), mustLocal(t, "20251101T000000", loc), mustLocal(t, "20251104T000000", loc))
+	want := []string{"2025-11-01T09:00:00-04:00", "2025-11-02T09:00:00-05:00", "2025-11-03T09:00:00-05:00"}
+	for i := range want {
+		if i >= len(got) || got[i].Format(time.RFC3339) != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+
+	// 02:30 does not exist on 2025-03-09; it moves forward by the gap.
+	got = rule.Between(mustLocal(t, "20250308T023000", loc), mustLocal(t, "20250308T000000", loc), mustLocal(t, "20250311T000000", loc))
+	want = []string{"2025-03-08T02:30:00-05:00", "2025-03-09T03:30:00-04:00", "2025-03-10T02:30:00-04:00"}
+	for i := range want {
+		if i >= len(got) || got[i].Format(time.RFC3339) != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+
+	// 01:30 happens twice on 2025-11-02; the first one is used.
+	got = rule.Between(mustLocal(t, "20251101T013000", loc), mustLocal(t, "20251101T000000", loc), mustLocal(t, "20251103T000000", loc))
+	if len(got) != 2 || got[1].Format(time.RFC3339) != "2025-11-02T01:30:00-04:00" {
+		t.Errorf("got %v, want the EDT 01:30 on 2025-11-02", got)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"COUNT=5",
+		"FREQ=FORTNIGHTLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20250101T000000Z",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ=DAILY;BYHOUR=24",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYWEEKNO=1",
+		"FREQ=MONTHLY;BYYEARDAY=1",
+		"FREQ=MONTHLY;BYDAY=XX",
+		"FREQ=DAILY;WKST=XX",
+		"FREQ=DAILY;COLOR=RED",
+		"FREQ",
+	} {
+		if _, err := Parse(value, time.UTC); err == nil {
+			t.Errorf("Parse(%q) should fail", value)
+		}
+	}
+
+	rule, err := Parse("RRULE:freq=monthly;byday=mo,-1fr;wkst=su", time.UTC)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rule.Freq != Monthly || rule.WeekStart != time.Sunday || len(rule.ByDay) != 2 {
+		t.Errorf("unexpected rule %+v", rule)
+	}
+}
@@ -0,0 +1,122 @@
+package rrule
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Set is a recurrence set: DTSTART and the occurrences of its rules and
+// RDATEs, minus its EXDATEs.
+type Set struct {
+	DTStart time.Time
+	RRules  []Rule
+	RDates  []time.Time
+	ExDates []time.Time
+}
+
+// ParseSet builds a recurrence set from RRULE, RDATE and EXDATE content
+// lines, as found in a VEVENT or in the recurrence field of the Google
+// Calendar API. Values without a TZID or UTC designator use dtstart's
+// location.
+func ParseSet(dtstart time.Time, lines []string) (Set, error) {
+	set := Set{DTStart: dtstart}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		head, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return Set{}, fmt.Errorf("invalid recurrence line %q", line)
+		}
+		params := strings.Split(head, ";")
+		name := strings.ToUpper(params[0])
+
+		loc := dtstart.Location()
+		for _, param := range params[1:] {
+			key, val, _ := strings.Cut(param, "=")
+			if strings.EqualFold(key, "TZID") {
+				tz, err := time.LoadLocation(strings.Trim(val, `"`))
+				if err != nil {
+					return Set{}, fmt.Errorf("%s: unknown TZID %q", name, val)
+				}
+				loc = tz
+			}
+		}
+
+		switch name {
+		case "RRULE":
+			rule, err := Parse(value, loc)
+			if err != nil {
+				return Set{}, fmt.Errorf("RRULE: %w", err)
+			}
+			set.RRules = append(set.RRules, rule)
+		case "RDATE", "EXDATE":
+			times, err := parseValues(value, loc)
+			if err != nil {
+				return Set{}, fmt.Errorf("%s: %w", name, err)
+			}
+			if name == "RDATE" {
+				set.RDates = append(set.RDates, times...)
+			} else {
+				set.ExDates = append(set.ExDates, times...)
+			}
+		default:
+			return Set{}, fmt.Errorf("unsupported recurrence line %q", name)
+		}
+	}
+	return set, nil
+}
+
+// parseValues parses a comma separated list of DATE, DATE-TIME or PERIOD
+// values; a period contributes its start.
+func parseValues(value string, loc *time.Location) ([]time.Time, error) {
+	var times []time.Time
+	for _, field := range strings.Split(value, ",") {
+		field, _, _ = strings.Cut(field, "/")
+		t, _, err := parseValue(strings.ToUpper(field), loc)
+		if err != nil {
+			return nil, err
+		}
+		times = append(times, t)
+	}
+	return times, nil
+}
+
+// Between returns the occurrences of the set in [from, to), sorted and in
+// DTSTART's location.
+func (s Set) Between(from, to time.Time) []time.Time {
+	loc := s.DTStart.Location()
+	candidates := s.RDates
+	if !s.DTStart.IsZero() {
+		candidates = append([]time.Time{s.DTStart}, candidates...)
+	}
+	for _, rule := range s.RRules {
+		candidates = append(candidates, rule.Between(s.DTStart, from, to)...)
+	}
+
+	var out []time.Time
+	for _, t := range candidates {
+		if t.Before(from) || !t.Before(to) || s.excluded(t) || containsTime(out, t) {
+			continue
+		}
+		out = append(out, t.In(loc))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+	return out
+}
+
+func (s Set) excluded(t time.Time) bool {
+	return containsTime(s.ExDates, t)
+}
+
+func containsTime(list []time.Time, t time.Time) bool {
+	for _, v := range list {
+		if v.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
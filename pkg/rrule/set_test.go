@@ -0,0 +1,53 @@
+package rrule
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSet(t *testing.T) {
+	loc := newYork(t)
+
+	// Every Friday the 13th, excluding DTSTART itself (RFC 5545).
+	dtstart := mustLocal(t, "19970902T090000", loc)
+	set, err := ParseSet(dtstart, []string{
+		"EXDATE;TZID=America/New_York:19970902T090000",
+		"RRULE:FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := format(set.Between(dtstart, mustLocal(t, "20010101T000000", loc)))
+	want := []string{"1998-02-13 09:00", "1998-03-13 09:00", "1998-11-13 09:00", "1999-08-13 09:00", "2000-10-13 09:00"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got  %v\nwant %v", got, want)
+	}
+
+	// RDATEs add occurrences, in UTC or as a period, and EXDATEs remove
+	// rule occurrences.
+	set, err = ParseSet(dtstart, []string{
+		"RRULE:FREQ=WEEKLY;COUNT=3",
+		"EXDATE:19970909T130000Z",
+		"RDATE:19970903T150000Z,19970904T090000/PT1H",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = format(set.Between(dtstart, mustLocal(t, "19971001T000000", loc)))
+	want = []string{"1997-09-02 09:00", "1997-09-03 11:00", "1997-09-04 09:00", "1997-09-16 09:00"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got  %v\nwant %v", got, want)
+	}
+
+	for _, lines := range [][]string{
+		{"EXRULE:FREQ=DAILY"},
+		{"RRULE FREQ=DAILY"},
+		{"RRULE:FREQ=NEVER"},
+		{"RDATE;TZID=Mars/Olympus:19970903T090000"},
+		{"EXDATE:yesterday"},
+	} {
+		if _, err := ParseSet(dtstart, lines); err == nil {
+			t.Errorf("ParseSet(%q) should fail", lines)
+		}
+	}
+}
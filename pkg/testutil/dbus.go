@@ -0,0 +1,173 @@
+package testutil
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// StartSessionBus starts a private dbus-daemon listening on socket, or on
+// a socket in a temporary directory when socket is empty, and returns its
+// address. The test is skipped when dbus-daemon is not installed.
+func StartSessionBus(t *testing.T, socket string) string {
+	t.Helper()
+
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon is not installed")
+	}
+	if socket == "" {
+		socket = filepath.Join(t.TempDir(), "bus")
+	}
+
+	cmd := exec.Command(daemon, "--session", "--nofork", "--nopidfile", "--print-address", "--address=unix:path="+socket)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("dbus-daemon: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("starting dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	address := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(stdout).ReadString('\n')
+		address <- strings.TrimSpace(line)
+	}()
+	select {
+	case addr := <-address:
+		if addr == "" {
+			t.Fatal("dbus-daemon did not print its address")
+		}
+		return addr
+	case <-time.After(10 * time.Second):
+		t.Fatal("dbus-daemon did not start")
+	}
+	return ""
+}
+
+// Notification is a notification received by a NotificationServer.
+type Notification struct {
+	ID      uint32
+	AppName string
+	Icon    string
+	Summary string
+	Body    string
+	Actions []string
+	Urgency byte
+	Timeout int32
+}
+
+// NotificationServer implements org.freedesktop.Notifications on a test
+// bus, recording the notifications it is sent.
+type NotificationServer struct {
+	conn *dbus.Conn
+
+	mu            sync.Mutex
+	notifications []Notification
+	closed        []uint32
+	onNotify      func(s *NotificationServer, n Notification)
+}
+
+// StartNotificationServer claims the notification service on the bus at
+// address.
+func StartNotificationServer(t *testing.T, address string) *NotificationServer {
+	t.Helper()
+
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatalf("connecting to %s: %v", address, err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	s := &NotificationServer{conn: conn}
+	if err := conn.Export(s, "/org/freedesktop/Notifications", "org.freedesktop.Notifications"); err != nil {
+		t.Fatalf("exporting the notification server: %v", err)
+	}
+	reply, err := conn.RequestName("org.freedesktop.Notifications", dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("claiming org.freedesktop.Notifications: %v %v", reply, err)
+	}
+	return s
+}
+
+// OnNotify sets a function called with every notification after it is
+// recorded, e.g. to invoke one of its actions.
+func (s *NotificationServer) OnNotify(f func(s *NotificationServer, n Notification)) {
+	s.mu.Lock()
+	s.onNotify = f
+	s.mu.Unlock()
+}
+
+// Notify implements the Notify method of the specification.
+func (s *NotificationServer) Notify(appName string, _ uint32, icon, summary, body string, actions []string,
+	hints map[string]dbus.Variant, timeout int32) (uint32, *dbus.Error) {
+	n := Notification{AppName: appName, Icon: icon, Summary: summary, Body: body, Actions: actions, Timeout: timeout}
+	if urgency, ok := hints["urgency"].Value().(byte); ok {
+		n.Urgency = urgency
+	}
+	s.mu.Lock()
+	n.ID = uint32(len(s.notifications) + 1)
+	s.notifications = append(s.notifications, n)
+	onNotify := s.onNotify
+	s.mu.Unlock()
+
+	if onNotify != nil {
+		go onNotify(s, n)
+	}
+	return n.ID, nil
+}
+
+// CloseNotification implements the CloseNotification method, reporting
+// the notification as closed by a call.
+func (s *NotificationServer) CloseNotification(id uint32) *dbus.Error {
+	s.mu.Lock()
+	s.closed = append(s.closed, id)
+	s.mu.Unlock()
+	s.Close(id, 3)
+	return nil
+}
+
+// Invoke emits ActionInvoked for notification id.
+func (s *NotificationServer) Invoke(id uint32, key string) {
+	_ = s.conn.Emit("/org/freedesktop/Notifications", "org.freedesktop.Notifications.ActionInvoked", id, key)
+}
+
+// Close emits NotificationClosed for notification id with reason.
+func (s *NotificationServer) Close(id, reason uint32) {
+	_ = s.conn.Emit("/org/freedesktop/Notifications", "org.freedesktop.Notifications.NotificationClosed", id, reason)
+}
+
+// Notifications returns the notifications received so far.
+func (s *NotificationServer) Notifications() []Notification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Notification(nil), s.notifications...)
+}
+
+// Closed returns the IDs of the notifications closed by CloseNotification.
+func (s *NotificationServer) Closed() []uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]uint32(nil), s.closed...)
+}
+
+// UnsetEnv unsets key for the duration of the test.
+func UnsetEnv(t *testing.T, key string) {
+	t.Helper()
+	if value, ok := os.LookupEnv(key); ok {
+		t.Setenv(key, value)
+		_ = os.Unsetenv(key)
+	}
+}
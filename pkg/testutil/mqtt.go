@@ -0,0 +1,288 @@
+package testutil
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// MQTTMessage is a message published to an MQTTBroker.
+type MQTTMessage struct {
+	ClientID string
+	Topic    string
+	Payload  []byte
+	QoS      byte
+	Retain   bool
+}
+
+// MQTTBroker is a minimal MQTT 3.1.1 broker for tests. It accepts
+// connections and publishes at every QoS level and records the messages;
+// it does not deliver them to subscribers.
+type MQTTBroker struct {
+	// Username and Password, when set, are required from clients.
+	Username string
+	Password string
+
+	listener net.Listener
+	mu       sync.Mutex
+	messages []MQTTMessage
+}
+
+// StartMQTTBroker starts a broker on a local port, serving TLS when
+// tlsConfig is not nil.
+func StartMQTTBroker(t *testing.T, tlsConfig *tls.Config) *MQTTBroker {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("starting the MQTT broker: %v", err)
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	b := &MQTTBroker{listener: listener}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+// Addr returns the host:port the broker listens on.
+func (b *MQTTBroker) Addr() string {
+	return b.listener.Addr().String()
+}
+
+// Messages returns the messages published so far.
+func (b *MQTTBroker) Messages() []MQTTMessage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]MQTTMessage(nil), b.messages...)
+}
+
+// MQTT control packet types.
+const (
+	mqttConnect    = 1
+	mqttConnack    = 2
+	mqttPublish    = 3
+	mqttPuback     = 4
+	mqttPubrec     = 5
+	mqttPubrel     = 6
+	mqttPubcomp    = 7
+	mqttSubscribe  = 8
+	mqttSuback     = 9
+	mqttPingreq    = 12
+	mqttPingresp   = 13
+	mqttDisconnect = 14
+)
+
+func (b *MQTTBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	clientID := ""
+	pending := make(map[uint16]MQTTMessage)
+
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(30 * time.Second))
+		header, body, err := readMQTTPacket(r)
+		if err != nil {
+			return
+		}
+		switch header >> 4 {
+		case mqttConnect:
+			var code byte
+			if clientID, code, err = b.connect(body); err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte{mqttConnack << 4, 2, 0, code})
+			if code != 0 {
+				return
+			}
+		case mqttPublish:
+			msg := MQTTMessage{ClientID: clientID, QoS: (header >> 1) & 3, Retain: header&1 == 1}
+			topic, rest, err := readMQTTString(body)
+			if err != nil {
+				return
+			}
+			msg.Topic = topic
+			var id uint16
+			if msg.QoS > 0 {
+				if len(rest) < 2 {
+					return
+				}
+				id, rest = binary.BigEndian.Uint16(rest), rest[2:]
+			}
+			msg.Payload = append([]byte(nil), rest...)
+			switch msg.QoS {
+			case 0:
+				b.record(msg)
+			case 1:
+				b.record(msg)
+				_, _ = conn.Write(mqttAck(mqttPuback<<4, id))
+			case 2:
+				pending[id] = msg
+				_, _ = conn.Write(mqttAck(mqttPubrec<<4, id))
+			}
+		case mqttPubrel:
+			if len(body) < 2 {
+				return
+			}
+			id := binary.BigEndian.Uint16(body)
+			if msg, ok := pending[id]; ok {
+				b.record(msg)
+				delete(pending, id)
+			}
+			_, _ = conn.Write(mqttAck(mqttPubcomp<<4, id))
+		case mqttSubscribe:
+			if len(body) < 2 {
+				return
+			}
+			// Grant QoS 0 to the single topic filter clients send here.
+			_, _ = conn.Write([]byte{mqttSuback << 4, 3, body[0], body[1], 0})
+		case mqttPingreq:
+			_, _ = conn.Write([]byte{mqttPingresp << 4, 0})
+		case mqttDisconnect:
+			return
+		}
+	}
+}
+
+// connect parses a CONNECT packet and returns the client ID and the
+// CONNACK return code.
+func (b *MQTTBroker) connect(body []byte) (string, byte, error) {
+	protocol, rest, err := readMQTTString(body)
+	if err != nil || len(rest) < 4 {
+		return "", 0, errors.New("malformed CONNECT")
+	}
+	if protocol != "MQTT" || rest[0] != 4 {
+		return "", 1, nil // unacceptable protocol version
+	}
+	flags := rest[1]
+	rest = rest[4:]
+
+	clientID, rest, err := readMQTTString(rest)
+	if err != nil {
+		return "", 0, err
+	}
+	if flags&0x04 != 0 {
+		if _, rest, err = readMQTTString(rest); err != nil {
+			return "", 0, err
+		}
+		if _, rest, err = readMQTTString(rest); err != nil {
+			return "", 0, err
+		}
+	}
+	var username, password string
+	if flags&0x80 != 0 {
+		if username, rest, err = readMQTTString(rest); err != nil {
+			return "", 0, err
+		}
+	}
+	if flags&0x40 != 0 {
+		if password, _, err = readMQTTString(rest); err != nil {
+			return "", 0, err
+		}
+	}
+	if (b.Username != "" || b.Password != "") && (username != b.Username || password != b.Password) {
+		return clientID, 4, nil // bad user name or password
+	}
+	return clientID, 0, nil
+}
+
+func (b *MQTTBroker) record(msg MQTTMessage) {
+	b.mu.Lock()
+	b.messages = append(b.messages, msg)
+	b.mu.Unlock()
+}
+
+func readMQTTPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		digit, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(digit&0x7f) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+		multiplier *= 128
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
+
+func readMQTTString(b []byte) (string, []byte, error) {
+	if len(b) < 2 {
+		return "", nil, errors.New("short string")
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", nil, errors.New("short string")
+	}
+	return string(b[2 : 2+n]), b[2+n:], nil
+}
+
+func mqttAck(header byte, id uint16) []byte {
+	return []byte{header, 2, byte(id >> 8), byte(id)}
+}
+
+// SelfSignedTLS returns a server TLS configuration for 127.0.0.1 and
+// localhost, and the PEM of its certificate for clients to trust.
+func SelfSignedTLS(t *testing.T) (*tls.Config, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "timeotter test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:              []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12},
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}